@@ -0,0 +1,207 @@
+package loadfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/appconfigdata"
+)
+
+// reAppConfigFilename matches appconfig://application/environment/profile,
+// each a name or an ID
+var reAppConfigFilename = regexp.MustCompile(`^appconfig:\/\/([^\/]+)\/([^\/]+)\/([^\/]+)$`)
+
+// AppConfigLoader fetches a configuration profile from AWS AppConfig, named
+// as appconfig://application/environment/profile, using the AppConfig Data
+// API and the default AWS credentials, as S3Loader does. The format is taken
+// from the profile's content type, e.g. application/json, or can be given by
+// a Loader's AliasExtension.
+//
+// A configuration session is started for each profile on first use and its
+// token kept. AppConfig says how long to wait before polling again, and until
+// then the content last fetched is returned without a request, so the profile
+// can be loaded as often as is convenient, e.g. by Watch, without being
+// throttled.
+type AppConfigLoader struct {
+	// Credentials replaces the default credentials, as for S3Loader
+	Credentials CredentialSource
+
+	// RoleARN, when set and Credentials isn't, is assumed with
+	// AssumeRoleCredentials
+	RoleARN string
+
+	// ExternalID is passed to AssumeRole when RoleARN is set
+	ExternalID string
+
+	// MinimumPollInterval is the RequiredMinimumPollIntervalInSeconds of new
+	// sessions, AppConfig's default of 60 seconds when zero. AppConfig
+	// doesn't accept less than 15 seconds.
+	MinimumPollInterval time.Duration
+
+	mu       sync.Mutex
+	client   *appconfigdata.AppConfigData
+	sessions map[string]*appConfigSession
+}
+
+// appConfigSession is the state of the configuration session of a profile
+type appConfigSession struct {
+	mu          sync.Mutex
+	token       string
+	nextPoll    time.Time
+	content     []byte
+	contentType string
+}
+
+func (al *AppConfigLoader) getClient() (*appconfigdata.AppConfigData, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.client != nil {
+		return al.client, nil
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	source := al.Credentials
+	if source == nil && al.RoleARN != "" {
+		source = AssumeRoleCredentials{RoleARN: al.RoleARN, ExternalID: al.ExternalID}
+	}
+	configs := []*aws.Config{}
+	if source != nil {
+		config, err := source.AWSConfig(sess)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	al.client = appconfigdata.New(sess, configs...)
+	return al.client, nil
+}
+
+func (al *AppConfigLoader) getSession(filename string) *appConfigSession {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.sessions == nil {
+		al.sessions = map[string]*appConfigSession{}
+	}
+	sess, ok := al.sessions[filename]
+	if !ok {
+		sess = &appConfigSession{}
+		al.sessions[filename] = sess
+	}
+	return sess
+}
+
+func (al *AppConfigLoader) GetReader(filename string) (io.Reader, error) {
+	return al.GetReaderContext(context.Background(), filename)
+}
+
+// GetReaderContext is GetReader, with ctx covering the requests
+func (al *AppConfigLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	parts := reAppConfigFilename.FindStringSubmatch(filename)
+	if len(parts) != 4 {
+		return nil, errors.New("Impossible bad match passed to AppConfigLoader")
+	}
+
+	client, err := al.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	sess := al.getSession(filename)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.content == nil || !time.Now().Before(sess.nextPoll) {
+		if err := al.poll(ctx, client, sess, parts[1], parts[2], parts[3]); err != nil {
+			return nil, err
+		}
+	}
+	return formatReader{
+		Reader: bytes.NewReader(sess.content),
+		ext:    appConfigExtension(sess.contentType),
+	}, nil
+}
+
+// poll gets the latest configuration of the session, starting one if it has
+// no token. AppConfig returns no content when it is unchanged since the last
+// poll, leaving what the session already has.
+func (al *AppConfigLoader) poll(ctx context.Context, client *appconfigdata.AppConfigData, sess *appConfigSession, application, environment, profile string) error {
+	restarted := false
+	for {
+		if sess.token == "" {
+			input := &appconfigdata.StartConfigurationSessionInput{
+				ApplicationIdentifier:          aws.String(application),
+				EnvironmentIdentifier:          aws.String(environment),
+				ConfigurationProfileIdentifier: aws.String(profile),
+			}
+			if al.MinimumPollInterval > 0 {
+				input.RequiredMinimumPollIntervalInSeconds = aws.Int64(int64(al.MinimumPollInterval / time.Second))
+			}
+			out, err := client.StartConfigurationSessionWithContext(ctx, input)
+			if err != nil {
+				return appConfigError(err)
+			}
+			sess.token = aws.StringValue(out.InitialConfigurationToken)
+			// A new session returns the whole configuration
+			sess.content = nil
+			restarted = true
+		}
+
+		out, err := client.GetLatestConfigurationWithContext(ctx, &appconfigdata.GetLatestConfigurationInput{
+			ConfigurationToken: aws.String(sess.token),
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == appconfigdata.ErrCodeBadRequestException && !restarted {
+			// Tokens expire after 24 hours, or when a poll was missed
+			sess.token = ""
+			continue
+		}
+		if err != nil {
+			return appConfigError(err)
+		}
+
+		sess.token = aws.StringValue(out.NextPollConfigurationToken)
+		sess.nextPoll = time.Now().Add(time.Duration(aws.Int64Value(out.NextPollIntervalInSeconds)) * time.Second)
+		if len(out.Configuration) > 0 || sess.content == nil {
+			sess.content = out.Configuration
+			if sess.content == nil {
+				sess.content = []byte{}
+			}
+			sess.contentType = aws.StringValue(out.ContentType)
+		}
+		return nil
+	}
+}
+
+// appConfigExtension returns the extension of the format of contentType, or ""
+func appConfigExtension(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	format, err := ParseFormat(contentType)
+	if err != nil {
+		return ""
+	}
+	return string(format)
+}
+
+func appConfigError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == appconfigdata.ErrCodeResourceNotFoundException {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
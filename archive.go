@@ -0,0 +1,86 @@
+package loadfile
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrMemberNotFound is returned for an archive!member filename when the
+// archive has no such member
+var ErrMemberNotFound = errors.New("Archive member not found")
+
+// splitMember splits a !member off filename when the part before it is a tar
+// archive, otherwise '!' is part of the name.
+//
+// A filename of the form archive!member, e.g.
+// https://host/bundle.tar.gz!config.yaml, loads a single file from a tar
+// archive. The archive is fetched by any TypeLoader and decompressed by its
+// extension (.tar, .tar.gz, .tgz, .tar.bz2 or .tar.zst), then the member is
+// decompressed and decoded by its own extension. A missing member returns
+// ErrMemberNotFound, a corrupt archive the gzip or tar error, e.g.
+// gzip.ErrHeader or tar.ErrHeader, and a member which doesn't parse its
+// decoder's error.
+func splitMember(filename string) (string, string) {
+	idx := strings.LastIndex(filename, "!")
+	if idx < 0 || idx == len(filename)-1 || !isTarName(filename[:idx]) {
+		return filename, ""
+	}
+	return filename[:idx], filename[idx+1:]
+}
+
+func isTarName(filename string) bool {
+	ext := fileExtension(filename)
+	if _, ok := codecs[ext]; ok {
+		ext = fileExtension(trimExtension(filename))
+	}
+	return ext == "tar" || ext == "tgz"
+}
+
+// extractMember returns the content of member from the tar archive read from
+// reader. Closing it releases any decompressor, but doesn't close reader.
+func (l *Loader) extractMember(archiveName, member string, reader io.Reader) (io.ReadCloser, error) {
+	if fileExtension(archiveName) == "tgz" {
+		archiveName = trimExtension(archiveName) + ".tar.gz"
+	}
+	_, decompressed, err := l.decompressRaw(archiveName, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	want := strings.TrimPrefix(path.Clean("/"+member), "/")
+	archive := tar.NewReader(decompressed)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			decompressed.Close()
+			return nil, fmt.Errorf("%w: %s", ErrMemberNotFound, member)
+		}
+		if err != nil {
+			decompressed.Close()
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if strings.TrimPrefix(path.Clean("/"+header.Name), "/") == want {
+			return &readCloser{Reader: archive, Closer: decompressed}, nil
+		}
+	}
+}
+
+// multiCloser closes each Closer in order, returning the first error
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var first error
+	for _, c := range mc {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,117 @@
+package loadfile
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tarGzipped returns a gzipped tar archive of members, given as pairs of
+// name and content
+func tarGzipped(t *testing.T, members ...string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for i := 0; i+1 < len(members); i += 2 {
+		name, content := members[i], members[i+1]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return gzipped(t, buf.String())
+}
+
+func TestLoadArchiveMemberOverHTTP(t *testing.T) {
+	bundle := tarGzipped(t,
+		"config.yaml", "name: app\n",
+		"env/prod.json", `{"name": "prod"}`,
+		"settings.yaml.gz", string(gzipped(t, "name: compressed\n")),
+		"bad.yaml", "name: [\n",
+	)
+	files := map[string][]byte{
+		"/bundle.tar.gz":  bundle,
+		"/bundle.tgz":     bundle,
+		"/corrupt.tar.gz": []byte("this is not a gzip file"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name     string
+		filename string
+		want     string
+		wantErr  error
+	}{{
+		name:     "member",
+		filename: "/bundle.tar.gz!config.yaml",
+		want:     "app",
+	}, {
+		name:     "nested member",
+		filename: "/bundle.tar.gz!env/prod.json",
+		want:     "prod",
+	}, {
+		name:     "tgz",
+		filename: "/bundle.tgz!./config.yaml",
+		want:     "app",
+	}, {
+		name:     "compressed member",
+		filename: "/bundle.tar.gz!settings.yaml.gz",
+		want:     "compressed",
+	}, {
+		name:     "missing member",
+		filename: "/bundle.tar.gz!other.yaml",
+		wantErr:  ErrMemberNotFound,
+	}, {
+		name:     "bad gzip",
+		filename: "/corrupt.tar.gz!config.yaml",
+		wantErr:  gzip.ErrHeader,
+	}, {
+		name:     "missing archive",
+		filename: "/missing.tar.gz!config.yaml",
+		wantErr:  ErrNotFound,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			into := struct{ Name string }{}
+			err := NewLoader().Load(srv.URL+tc.filename, &into)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+
+	t.Run("bad member", func(t *testing.T) {
+		err := NewLoader().Load(srv.URL+"/bundle.tar.gz!bad.yaml", &struct{ Name string }{})
+		if err == nil {
+			t.Fatal("got no error")
+		}
+		for _, other := range []error{ErrMemberNotFound, gzip.ErrHeader, tar.ErrHeader} {
+			if errors.Is(err, other) {
+				t.Errorf("got %v, a parse error, as %v", err, other)
+			}
+		}
+	})
+}
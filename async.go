@@ -0,0 +1,59 @@
+package loadfile
+
+import (
+	"context"
+)
+
+// LoadHandle is a load started by LoadAsync
+type LoadHandle struct {
+	done   chan struct{}
+	err    error
+	cancel context.CancelFunc
+}
+
+// LoadAsync starts loading filename into into in its own goroutine, as Load
+// does, returning straight away, so that several remote files can be fetched
+// at once and each waited for only when it is needed. into mustn't be used
+// until Wait has returned.
+func (l *Loader) LoadAsync(filename string, into interface{}) *LoadHandle {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &LoadHandle{done: make(chan struct{}), cancel: cancel}
+	loader := *l
+	loader.ctx = ctx
+	loader.tracer = l.tracer.fork()
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		h.err = loader.Load(filename, into)
+	}()
+	return h
+}
+
+// LoadAsync starts loading a file in its own goroutine, using the default
+// loader
+func LoadAsync(filename string, into interface{}) *LoadHandle {
+	return DefaultLoader.LoadAsync(filename, into)
+}
+
+// Wait blocks until the load has finished, returning its error
+func (h *LoadHandle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Done is closed when the load has finished, for use in a select
+func (h *LoadHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Cancel stops the load as soon as it can. Fetches by a TypeLoader
+// implementing ContextLoader, such as the S3 and HTTP loaders, have their
+// context cancelled, which stops reading their bodies too, and nothing more
+// is fetched. A read already under way from any other TypeLoader, such as a
+// local file, carries on, as does decoding what was read, so the load can
+// still succeed. Wait still has to be called to know the load is no longer
+// using into, and returns an error matching context.Canceled when Cancel
+// stopped the load.
+func (h *LoadHandle) Cancel() {
+	h.cancel()
+}
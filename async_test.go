@@ -0,0 +1,51 @@
+package loadfile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// blockingLoader blocks each fetch until its context is done
+type blockingLoader struct {
+	started chan struct{}
+}
+
+func (bl blockingLoader) GetReader(filename string) (io.Reader, error) {
+	return strings.NewReader("{}"), nil
+}
+
+func (bl blockingLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	close(bl.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestLoadAsync(t *testing.T) {
+	filename := writeTestFile(t, "app.yaml", "name: app\n")
+	into := struct{ Name string }{}
+	if err := NewLoader().LoadAsync(filename, &into).Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if into.Name != "app" {
+		t.Errorf("Name is %q", into.Name)
+	}
+}
+
+func TestLoadAsyncCancel(t *testing.T) {
+	l := NewLoader()
+	bl := blockingLoader{started: make(chan struct{})}
+	if err := l.Register(regexp.MustCompile(`^block:\/\/`), bl); err != nil {
+		t.Fatal(err)
+	}
+	into := map[string]interface{}{}
+	h := l.LoadAsync("block://app.json", &into)
+	<-bl.started
+	h.Cancel()
+	if err := h.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
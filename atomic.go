@@ -0,0 +1,20 @@
+package loadfile
+
+import "sync/atomic"
+
+// LoadAtomic loads filename into a new T and stores it in ptr, so that readers
+// using ptr.Load() see either the previous config or the complete new one,
+// never one partly decoded. On error ptr is left unchanged. It is a function
+// rather than a Loader method, as methods can't have type parameters; a nil
+// Loader uses the DefaultLoader.
+func LoadAtomic[T any](l *Loader, filename string, ptr *atomic.Pointer[T]) error {
+	if l == nil {
+		l = DefaultLoader
+	}
+	into := new(T)
+	if err := l.Load(filename, into); err != nil {
+		return err
+	}
+	ptr.Store(into)
+	return nil
+}
@@ -0,0 +1,138 @@
+package loadfile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by BreakerLoader while its circuit is open
+var ErrCircuitOpen = errors.New("Circuit open, not attempting load")
+
+// BreakerState is the state of a BreakerLoader's circuit
+type BreakerState int
+
+const (
+	// BreakerClosed passes every call through to the inner loader
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every call with ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerLoader wraps another TypeLoader, and after Threshold consecutive
+// failures fails fast with ErrCircuitOpen rather than calling it. Once Cooldown
+// has passed a single probe call is let through: success closes the circuit
+// again, failure re-opens it for another Cooldown. A file not being found is
+// not a failure.
+type BreakerLoader struct {
+	Inner TypeLoader
+
+	// Threshold is the number of consecutive failures which opens the
+	// circuit, 5 if zero
+	Threshold int
+
+	// Cooldown is how long the circuit stays open before probing, 30 seconds
+	// if zero
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (bl *BreakerLoader) threshold() int {
+	if bl.Threshold <= 0 {
+		return 5
+	}
+	return bl.Threshold
+}
+
+func (bl *BreakerLoader) cooldown() time.Duration {
+	if bl.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return bl.Cooldown
+}
+
+// State returns the current state of the circuit, e.g. for metrics
+func (bl *BreakerLoader) State() BreakerState {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	if bl.failures < bl.threshold() {
+		return BreakerClosed
+	}
+	if bl.probing || time.Since(bl.openedAt) >= bl.cooldown() {
+		return BreakerHalfOpen
+	}
+	return BreakerOpen
+}
+
+func (bl *BreakerLoader) allow() error {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	if bl.failures < bl.threshold() {
+		return nil
+	}
+	if bl.probing || time.Since(bl.openedAt) < bl.cooldown() {
+		return ErrCircuitOpen
+	}
+	bl.probing = true
+	return nil
+}
+
+// record counts err as a failure. Not found is the inner loader answering,
+// e.g. for the missing candidates of LoadFirst, so it counts as success.
+func (bl *BreakerLoader) record(err error) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.probing = false
+	if err == nil || isNotFound(err) {
+		bl.failures = 0
+		return
+	}
+	bl.failures++
+	if bl.failures >= bl.threshold() {
+		bl.openedAt = time.Now()
+	}
+}
+
+func (bl *BreakerLoader) GetReader(filename string) (io.Reader, error) {
+	if err := bl.allow(); err != nil {
+		return nil, err
+	}
+	r, err := bl.Inner.GetReader(filename)
+	bl.record(err)
+	return r, err
+}
+
+// GetReaderContext is GetReader, passing ctx to Inner when it is a
+// ContextLoader
+func (bl *BreakerLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	contextLoader, ok := bl.Inner.(ContextLoader)
+	if !ok {
+		return bl.GetReader(filename)
+	}
+	if err := bl.allow(); err != nil {
+		return nil, err
+	}
+	r, err := contextLoader.GetReaderContext(ctx, filename)
+	bl.record(err)
+	return r, err
+}
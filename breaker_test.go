@@ -0,0 +1,48 @@
+package loadfile
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestBreakerNotFound(t *testing.T) {
+	breaker := &BreakerLoader{Inner: memLoader{"mem://app.json": `{"Port": 8080}`}, Threshold: 1}
+	l := NewLoader()
+	if err := l.Register(regexp.MustCompile(`^mem:\/\/`), breaker); err != nil {
+		t.Fatal(err)
+	}
+	got := struct{ Port int }{}
+	for i := 0; i < 2; i++ {
+		if err := l.LoadOptional("mem://missing.json", &got); err != nil {
+			t.Fatal(err)
+		}
+		found, err := l.LoadFirst(&got, "mem://local.json", "mem://app.json")
+		if err != nil || found != "mem://app.json" || got.Port != 8080 {
+			t.Fatalf("got %q, %+v, %v, want mem://app.json", found, got, err)
+		}
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Errorf("circuit is %s after missing files, want closed", state)
+	}
+}
+
+func TestBreakerContext(t *testing.T) {
+	breaker := &BreakerLoader{Inner: &flakyLoader{hang: true}, Threshold: 1, Cooldown: time.Minute}
+	l := NewLoader(WithDefaultTimeout(20 * time.Millisecond))
+	if err := l.Register(regexp.MustCompile(`^flaky:\/\/`), breaker); err != nil {
+		t.Fatal(err)
+	}
+	got := struct{ Port int }{}
+	start := time.Now()
+	if err := l.Load("flaky://config.json", &got); err == nil {
+		t.Fatal("loaded from a hanging loader")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s, want the default timeout to reach Inner", elapsed)
+	}
+	if err := l.Load("flaky://config.json", &got); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got error %v, want ErrCircuitOpen after the timeout", err)
+	}
+}
@@ -0,0 +1,106 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBudgetExceeded is returned by the multi-file APIs when a Loader created
+// WithTotalTimeout or WithTotalReadLimit runs out of time or bytes
+var ErrBudgetExceeded = errors.New("Load budget exceeded")
+
+// WithTotalTimeout limits each call to LoadLayered, LoadLayeredWithSources,
+// LoadDir, LoadPrefix, LoadManifest, LoadWithExtraFromEnv and LoadForHost to d
+// overall, however many files it loads. No file is fetched once d has passed,
+// and fetches by a ContextLoader are cancelled at the deadline. Any per-file
+// WithDefaultTimeout still applies, whichever ends first.
+func WithTotalTimeout(d time.Duration) Option {
+	return func(l *Loader) {
+		l.totalTimeout = d
+	}
+}
+
+// WithTotalReadLimit limits each call to the multi-file APIs, as
+// WithTotalTimeout, to reading n bytes overall, as fetched, before any
+// decompression. Any per-file WithReadAllLimit still applies.
+func WithTotalReadLimit(n int64) Option {
+	return func(l *Loader) {
+		l.totalReadLimit = n
+	}
+}
+
+// budget is the time and bytes left to one multi-file call
+type budget struct {
+	deadline  time.Time
+	limit     int64
+	remaining int64
+}
+
+// withBudget returns a copy of the Loader with a new budget for one
+// multi-file call, or the Loader itself when it has no total limits or is
+// already within a call
+func (l *Loader) withBudget() *Loader {
+	if l.budget != nil || (l.totalTimeout <= 0 && l.totalReadLimit <= 0) {
+		return l
+	}
+	budgeted := *l
+	budgeted.budget = &budget{limit: l.totalReadLimit, remaining: l.totalReadLimit}
+	if l.totalTimeout > 0 {
+		budgeted.budget.deadline = time.Now().Add(l.totalTimeout)
+	}
+	return &budgeted
+}
+
+// check returns ErrBudgetExceeded once the deadline has passed
+func (b *budget) check() error {
+	if !b.deadline.IsZero() && !time.Now().Before(b.deadline) {
+		return fmt.Errorf("%w: total timeout reached", ErrBudgetExceeded)
+	}
+	return nil
+}
+
+// budgetReader counts bytes read against the budget. It keeps the Close and
+// FormatExtension of the reader it wraps.
+type budgetReader struct {
+	r      io.Reader
+	budget *budget
+}
+
+func (br *budgetReader) Read(p []byte) (int, error) {
+	if br.budget.limit <= 0 {
+		return br.r.Read(p)
+	}
+	remaining := atomic.LoadInt64(&br.budget.remaining)
+	if remaining <= 0 {
+		// As limitedReader, check for one more byte before failing
+		var probe [1]byte
+		n, err := br.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: read more than %d bytes in total", ErrBudgetExceeded, br.budget.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := br.r.Read(p)
+	atomic.AddInt64(&br.budget.remaining, -int64(n))
+	return n, err
+}
+
+func (br *budgetReader) Close() error {
+	if closer, ok := br.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (br *budgetReader) FormatExtension() string {
+	if fr, ok := br.r.(FormatReader); ok {
+		return fr.FormatExtension()
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// SaveCanonical saves from to the local file filename as Save does, but in a
+// canonical form, so that the same value always gives the same bytes, e.g. for
+// comparing with golden files in tests. Every mapping's keys are sorted,
+// struct fields included, indentation is two spaces, and the file ends with
+// a newline. JSON isn't HTML escaped, and YAML is written in block style with
+// strings quoted only where they need to be, including those like yes or 0123
+// which YAML 1.1, and so Load, would read as another type.
+// WithPreserveComments doesn't apply, as the file is replaced rather than
+// edited.
+func (l *Loader) SaveCanonical(filename string, from interface{}) error {
+	filename, writer, err := l.saveTarget(filename)
+	if err != nil {
+		return err
+	}
+	b, err := l.MarshalCanonical(fileExtension(filename), from)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return writer.WriteFile(filename, b)
+}
+
+// SaveCanonical saves from to filename in a canonical form, using the
+// DefaultLoader
+func SaveCanonical(filename string, from interface{}) error {
+	return DefaultLoader.SaveCanonical(filename, from)
+}
+
+// MarshalCanonical encodes from in the canonical form SaveCanonical writes,
+// as JSON or YAML by the extension ext, e.g. "yaml"
+func (l *Loader) MarshalCanonical(ext string, from interface{}) ([]byte, error) {
+	switch l.docFormat(ext) {
+	case docJSON:
+		return canonicalJSON(from)
+	case docYAML:
+		return canonicalYAML(from)
+	}
+	return nil, fmt.Errorf("%w: saving %s files", ErrUnsupported, ext)
+}
+
+// canonicalJSON encodes from through a generic document, as encoding/json
+// sorts the keys of maps but not the fields of structs
+func canonicalJSON(from interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(from)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	// Numbers are written as they were encoded
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func canonicalYAML(from interface{}) ([]byte, error) {
+	node := &yamlv3.Node{}
+	if err := node.Encode(from); err != nil {
+		return nil, err
+	}
+	canonicalNode(node)
+	buf := &bytes.Buffer{}
+	enc := yamlv3.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalNode sorts the keys of node's mappings, expands aliases and clears
+// styles and comments, leaving the encoder to choose how to write each value
+// but for strings YAML 1.1 would read as something else
+func canonicalNode(node *yamlv3.Node) {
+	if node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		*node = *node.Alias
+	}
+	node.Style = 0
+	if node.Kind == yamlv3.ScalarNode && node.ShortTag() == "!!str" && !plainYAML11String(node.Value) {
+		node.Style = yamlv3.DoubleQuotedStyle
+	}
+	node.Anchor = ""
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, child := range node.Content {
+		canonicalNode(child)
+	}
+	if node.Kind != yamlv3.MappingNode {
+		return
+	}
+
+	pairs := make([][2]*yamlv3.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yamlv3.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, b := pairs[i][0], pairs[j][0]
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return a.ShortTag() < b.ShortTag()
+	})
+	node.Content = node.Content[:0]
+	for _, pair := range pairs {
+		node.Content = append(node.Content, pair[0], pair[1])
+	}
+}
+
+// plainYAML11String returns true if yaml.v2, which follows YAML 1.1, reads
+// value written plain back as the same string. yaml.v3 follows YAML 1.2, so
+// would write yes, on or 0123 plain, which yaml.v2 reads as a bool or an int.
+func plainYAML11String(value string) bool {
+	if value == "" || strings.ContainsAny(value, "\n") {
+		// Left to yaml.v3, which quotes or writes blocks for these
+		return true
+	}
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(value), &decoded); err != nil {
+		return false
+	}
+	s, ok := decoded.(string)
+	return ok && s == value
+}
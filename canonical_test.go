@@ -0,0 +1,52 @@
+package loadfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveCanonical(t *testing.T) {
+	from := map[string]interface{}{
+		"zone":    "eu",
+		"answer":  "yes",
+		"enabled": true,
+		"switch":  "off",
+		"mode":    "0644",
+		"version": "1.10",
+		"count":   3,
+		"note":    "key: value",
+		"hosts":   []interface{}{"b", "a"},
+		"limits":  map[string]interface{}{"memory": "512Mi", "cpu": "no"},
+	}
+	for _, ext := range []string{"json", "yaml"} {
+		t.Run(ext, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "config."+ext)
+			if err := SaveCanonical(filename, from); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", "canonical."+ext))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+
+			loaded := map[string]interface{}{}
+			if err := Load(filename, &loaded); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"answer", "switch", "mode", "version"} {
+				if !reflect.DeepEqual(loaded[key], from[key]) {
+					t.Errorf("%s: got %#v, want %#v", key, loaded[key], from[key])
+				}
+			}
+		})
+	}
+}
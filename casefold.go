@@ -0,0 +1,165 @@
+package loadfile
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// WithCaseInsensitiveYAML binds YAML keys to struct fields ignoring case, as
+// encoding/json does, so that Timeout in a YAML file sets the same field
+// that it would in a JSON file. A key which differs from a field's name only
+// in case is renamed to it before decoding. When several keys of one mapping
+// bind to the same field, e.g. timeout and Timeout, the last of them in the
+// file wins, as it does in JSON, and the others are ignored. Maps, and keys
+// which match no field, are left as they are.
+func WithCaseInsensitiveYAML() Option {
+	return func(l *Loader) {
+		l.caseInsensitiveYAML = true
+	}
+}
+
+// caseFoldingDecoder wraps a YAML decoder to rename keys to the fields of t
+// they match ignoring case
+func caseFoldingDecoder(decoder DecoderFunc, t reflect.Type) DecoderFunc {
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		folded, err := foldKeys(content, t)
+		if err != nil {
+			return err
+		}
+		return decoder(bytes.NewReader(folded), into)
+	}
+}
+
+// foldKeys returns YAML content with its keys renamed to the fields of t they
+// match ignoring case, or as it is when none need renaming
+func foldKeys(content []byte, t reflect.Type) ([]byte, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &doc); err != nil || !foldYAMLKeys(&doc, t, map[*yamlv3.Node]bool{}) {
+		// Leave syntax errors to the decoder to report
+		return content, nil
+	}
+	return yamlv3.Marshal(&doc)
+}
+
+// foldYAMLKeys renames the keys of node which match a field of t ignoring
+// case, returning true if it changed anything
+func foldYAMLKeys(node *yamlv3.Node, t reflect.Type, seen map[*yamlv3.Node]bool) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || seen[node] {
+		return false
+	}
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		changed := false
+		for _, child := range node.Content {
+			changed = foldYAMLKeys(child, t, seen) || changed
+		}
+		return changed
+	case yamlv3.AliasNode:
+		seen[node] = true
+		return foldYAMLKeys(node.Alias, t, seen)
+	case yamlv3.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return false
+		}
+		changed := false
+		for _, child := range node.Content {
+			changed = foldYAMLKeys(child, t.Elem(), seen) || changed
+		}
+		return changed
+	case yamlv3.MappingNode:
+	default:
+		return false
+	}
+
+	changed := false
+	if t.Kind() == reflect.Map {
+		for i := 1; i < len(node.Content); i += 2 {
+			changed = foldYAMLKeys(node.Content[i], t.Elem(), seen) || changed
+		}
+		return changed
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	names := yamlFieldNames(t)
+	// The index of the last key bound to each field
+	last := map[string]int{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if name, ok := names[strings.ToLower(node.Content[i].Value)]; ok && node.Content[i].Kind == yamlv3.ScalarNode {
+			last[name] = i
+		}
+	}
+	content := make([]*yamlv3.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name, ok := names[strings.ToLower(key.Value)]
+		if ok && key.Kind == yamlv3.ScalarNode {
+			if last[name] != i {
+				changed = true
+				continue
+			}
+			if key.Value != name {
+				key.Value = name
+				changed = true
+			}
+			if index, ok := fieldForKey(t, docYAML, name); ok {
+				changed = foldYAMLKeys(value, t.FieldByIndex(index).Type, seen) || changed
+			}
+		}
+		content = append(content, key, value)
+	}
+	node.Content = content
+	return changed
+}
+
+// yamlFieldNames returns the key each field of t is decoded from in YAML, by
+// the key lower cased, including the fields of structs decoded inline after
+// t's own
+func yamlFieldNames(t reflect.Type) map[string]string {
+	names := map[string]string{}
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		inline := []reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("yaml")
+			name := strings.Split(tag, ",")[0]
+			if name == "-" {
+				continue
+			}
+			if f.Type.Kind() == reflect.Struct && (isUntaggedEmbedded(f) || strings.Contains(tag, ",inline")) {
+				inline = append(inline, f.Type)
+				if !isUntaggedEmbedded(f) {
+					continue
+				}
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			if _, ok := names[strings.ToLower(name)]; !ok {
+				names[strings.ToLower(name)] = name
+			}
+		}
+		for _, t := range inline {
+			collect(t)
+		}
+	}
+	collect(t)
+	return names
+}
@@ -0,0 +1,63 @@
+package loadfile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCaseInsensitiveYAML(t *testing.T) {
+	type database struct {
+		Host string
+	}
+	type config struct {
+		Timeout  int
+		Version  string
+		Database database
+		Labels   map[string]string
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		options []Option
+		want    config
+		wantErr error
+	}{{
+		name:    "folded",
+		content: "Timeout: 5\nVERSION: 1.10\nDatabase:\n  HOST: db\nLabels:\n  Tier: web\n",
+		want:    config{Timeout: 5, Version: "1.10", Database: database{Host: "db"}, Labels: map[string]string{"Tier": "web"}},
+	}, {
+		name:    "last key wins",
+		content: "timeout: 1\nTimeout: 2\n",
+		want:    config{Timeout: 2},
+	}, {
+		name:    "strict accepts folded keys",
+		content: "Timeout: 5\nDatabase: {Host: db}\n",
+		options: []Option{WithStrict()},
+		want:    config{Timeout: 5, Database: database{Host: "db"}},
+	}, {
+		name:    "strict rejects unknown keys",
+		content: "Timeout: 5\nOther: 1\n",
+		options: []Option{WithStrict()},
+		wantErr: ErrUnknownKey,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := config{}
+			loader := NewLoader(append(tc.options, WithCaseInsensitiveYAML())...)
+			err := loader.Load(writeTestFile(t, "config.yaml", tc.content), &got)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package loadfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// WithCharset transcodes JSON, XML and YAML files from enc to UTF-8 before
+// decoding, e.g. charmap.Windows1252 from golang.org/x/text/encoding/charmap.
+// A byte order mark at the start of the file overrides enc. The encoding an
+// XML file declares is taken to be the one it was transcoded from.
+//
+// Without it, files starting with a UTF-8, UTF-16LE or UTF-16BE byte order mark
+// are still transcoded, and the mark removed, so UTF-16 files exported by
+// Windows tools decode as expected.
+func WithCharset(enc encoding.Encoding) Option {
+	return func(l *Loader) {
+		l.charset = enc
+	}
+}
+
+var byteOrderMarks = [][]byte{
+	{0xef, 0xbb, 0xbf},
+	{0xff, 0xfe},
+	{0xfe, 0xff},
+}
+
+// transcode converts a text format's content to UTF-8, when the Loader has a
+// charset or the content starts with a byte order mark
+func (l *Loader) transcode(ext string, reader io.Reader) io.Reader {
+	switch l.docFormat(ext) {
+	case docJSON, docXML, docYAML:
+	default:
+		return reader
+	}
+
+	fallback := transform.Transformer(transform.Nop)
+	if l.charset != nil {
+		fallback = l.charset.NewDecoder()
+	} else if cr, ok := reader.(*contentReader); ok {
+		if !hasByteOrderMark(cr.content) {
+			return reader
+		}
+	} else {
+		buffered := bufio.NewReader(reader)
+		start, _ := buffered.Peek(3)
+		reader = buffered
+		if !hasByteOrderMark(start) {
+			return reader
+		}
+	}
+	transcoded := io.Reader(transform.NewReader(reader, unicode.BOMOverride(fallback)))
+	if l.docFormat(ext) == docXML {
+		transcoded = declareUTF8(transcoded)
+	}
+	return transcoded
+}
+
+func hasByteOrderMark(start []byte) bool {
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(start, bom) {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlEncoding matches the encoding declared by an XML document's prolog
+var xmlEncoding = regexp.MustCompile(`^<\?xml\s[^?>]*?encoding\s*=\s*("[^"]*"|'[^']*')`)
+
+// maxXMLDeclaration is as far into a document its declaration is looked for
+const maxXMLDeclaration = 512
+
+// declareUTF8 rewrites the encoding a transcoded XML document declares to
+// UTF-8, which it now is, so whatever it was declared as is accepted
+func declareUTF8(reader io.Reader) io.Reader {
+	buffered := bufio.NewReaderSize(reader, maxXMLDeclaration)
+	start, _ := buffered.Peek(maxXMLDeclaration)
+	match := xmlEncoding.FindSubmatchIndex(start)
+	if match == nil {
+		return buffered
+	}
+	prolog := append(append([]byte{}, start[:match[2]]...), `"UTF-8"`...)
+	if _, err := buffered.Discard(match[3]); err != nil {
+		return buffered
+	}
+	return io.MultiReader(bytes.NewReader(prolog), buffered)
+}
+
+// xmlCharsetReader refuses the encodings XML documents declare other than
+// UTF-8. A document is only declared as UTF-8 once transcoded, by WithCharset
+// or its byte order mark.
+func xmlCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	return nil, fmt.Errorf("xml: encoding %q declared but not supported without WithCharset", label)
+}
@@ -0,0 +1,82 @@
+package loadfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestXMLCharsets(t *testing.T) {
+	utf16 := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	for _, tc := range []struct {
+		name    string
+		charset encoding.Encoding
+		encode  encoding.Encoding
+		content string
+		want    string
+		wantErr string
+	}{{
+		name:    "declared latin-1 with charset",
+		charset: charmap.ISO8859_1,
+		encode:  charmap.ISO8859_1,
+		content: `<?xml version="1.0" encoding="ISO-8859-1"?><config><name>café</name></config>`,
+		want:    "café",
+	}, {
+		name:    "declared utf-16 with byte order mark",
+		encode:  utf16,
+		content: `<?xml version="1.0" encoding="UTF-16"?><config><name>café</name></config>`,
+		want:    "café",
+	}, {
+		name:    "byte order mark overriding the declaration",
+		encode:  utf16,
+		content: `<?xml version='1.0' encoding='windows-1252'?><config><name>café</name></config>`,
+		want:    "café",
+	}, {
+		name:    "undeclared with charset",
+		charset: charmap.ISO8859_1,
+		encode:  charmap.ISO8859_1,
+		content: `<config><name>café</name></config>`,
+		want:    "café",
+	}, {
+		name:    "declared latin-1 without charset",
+		encode:  charmap.ISO8859_1,
+		content: `<?xml version="1.0" encoding="ISO-8859-1"?><config><name>cafe</name></config>`,
+		wantErr: "without WithCharset",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := tc.encode.NewEncoder().String(tc.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filename := filepath.Join(t.TempDir(), "config.xml")
+			if err := os.WriteFile(filename, []byte(encoded), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var opts []Option
+			if tc.charset != nil {
+				opts = append(opts, WithCharset(tc.charset))
+			}
+			got := struct {
+				Name string `xml:"name"`
+			}{}
+			err = NewLoader(opts...).Load(filename, &got)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tc.want {
+				t.Errorf("got %q, want %q", got.Name, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package loadfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// closeCountingLoader serves files from memory as io.ReadClosers, counting
+// those opened and not yet closed
+type closeCountingLoader struct {
+	files map[string][]byte
+
+	mu     sync.Mutex
+	opened int
+	open   int
+}
+
+type countedBody struct {
+	io.Reader
+	loader *closeCountingLoader
+	once   sync.Once
+}
+
+func (cb *countedBody) Close() error {
+	cb.once.Do(func() {
+		cb.loader.mu.Lock()
+		defer cb.loader.mu.Unlock()
+		cb.loader.open--
+	})
+	return nil
+}
+
+func (cl *closeCountingLoader) GetReader(filename string) (io.Reader, error) {
+	content, ok := cl.files[filename]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.opened++
+	cl.open++
+	return &countedBody{Reader: bytes.NewReader(content), loader: cl}, nil
+}
+
+func TestClosePropagation(t *testing.T) {
+	files := map[string][]byte{
+		"mem://app.yaml":          []byte("name: app\n"),
+		"mem://override.yaml":     []byte("name: override\n"),
+		"mem://app.yaml.gz":       gzipped(t, "name: app\n"),
+		"mem://bundle.tar.gz":     tarGzipped(t, "app.yaml", "name: app\n"),
+		"mem://bad.yaml":          []byte("name: [\n"),
+		"mem://bad.yaml.gz":       []byte("not gzip"),
+		"mem://large.yaml":        []byte("name: " + string(bytes.Repeat([]byte("x"), 64)) + "\n"),
+		"mem://empty.yaml":        {},
+		"mem://documents.yaml":    []byte("name: a\n---\nname: b\n"),
+		"mem://cfg/includes.yaml": []byte("name: !include name.yaml\n"),
+		"mem://cfg/name.yaml":     []byte("app\n"),
+	}
+	type config struct{ Name string }
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		load    func(l *Loader) error
+		wantErr bool
+	}{{
+		name: "load",
+		load: func(l *Loader) error { return l.Load("mem://app.yaml", &config{}) },
+	}, {
+		name: "gzip",
+		load: func(l *Loader) error { return l.Load("mem://app.yaml.gz", &config{}) },
+	}, {
+		name:    "bad gzip",
+		load:    func(l *Loader) error { return l.Load("mem://bad.yaml.gz", &config{}) },
+		wantErr: true,
+	}, {
+		name: "archive member",
+		load: func(l *Loader) error { return l.Load("mem://bundle.tar.gz!app.yaml", &config{}) },
+	}, {
+		name:    "missing archive member",
+		load:    func(l *Loader) error { return l.Load("mem://bundle.tar.gz!other.yaml", &config{}) },
+		wantErr: true,
+	}, {
+		name:    "decode error",
+		load:    func(l *Loader) error { return l.Load("mem://bad.yaml", &config{}) },
+		wantErr: true,
+	}, {
+		name:    "read limit",
+		opts:    []Option{WithReadAllLimit(16)},
+		load:    func(l *Loader) error { return l.Load("mem://large.yaml", &config{}) },
+		wantErr: true,
+	}, {
+		name: "empty",
+		load: func(l *Loader) error { return l.Load("mem://empty.yaml", &config{}) },
+	}, {
+		name: "layered",
+		load: func(l *Loader) error {
+			return l.LoadLayered(&config{}, "mem://app.yaml", "mem://override.yaml")
+		},
+	}, {
+		name: "documents",
+		load: func(l *Loader) error { return l.LoadDocuments("mem://documents.yaml", &[]config{}) },
+	}, {
+		name: "includes",
+		opts: []Option{WithIncludes()},
+		load: func(l *Loader) error { return l.Load("mem://cfg/includes.yaml", &config{}) },
+	}, {
+		name: "keys",
+		load: func(l *Loader) error {
+			_, err := l.LoadKeys("mem://app.yaml", &config{})
+			return err
+		},
+	}, {
+		name: "capture",
+		load: func(l *Loader) error {
+			_, err := l.LoadAndCapture("mem://app.yaml", &config{})
+			return err
+		},
+	}, {
+		name: "warnings",
+		load: func(l *Loader) error {
+			_, err := l.LoadWithWarnings("mem://bad.yaml", &config{})
+			return err
+		},
+		wantErr: true,
+	}, {
+		name: "as format",
+		load: func(l *Loader) error { return l.LoadAs("mem://app.yaml", FormatYAML, &config{}) },
+	}, {
+		name: "optional",
+		load: func(l *Loader) error { return l.LoadOptional("mem://app.yaml", &config{}) },
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := &closeCountingLoader{files: files}
+			l := NewLoader(tc.opts...)
+			if err := l.Register(regexp.MustCompile(`^mem:\/\/`), cl); err != nil {
+				t.Fatal(err)
+			}
+			err := tc.load(l)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if cl.opened == 0 {
+				t.Fatal("nothing was opened")
+			}
+			if cl.open != 0 {
+				t.Errorf("%d of %d readers were not closed", cl.open, cl.opened)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Command loadfile loads a file from any supported scheme and format into a
+// generic document and prints it, which is handy for debugging and for
+// converting between formats:
+//
+//	loadfile -to yaml s3://bucket/config.json
+//
+// XML can't be decoded into a generic document so isn't supported as input.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/daemonl/loadfile"
+	"gopkg.in/yaml.v2"
+)
+
+func main() {
+	to := flag.String("to", "json", "output format, json or yaml")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-to json|yaml] filename\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *to); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(filename, to string) error {
+	var doc interface{}
+	if err := loadfile.Load(filename, &doc); err != nil {
+		return err
+	}
+	doc = stringKeys(doc)
+
+	switch to {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case "yaml", "yml":
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return fmt.Errorf("Unknown output format %q", to)
+}
+
+// stringKeys converts the map[interface{}]interface{} values YAML decodes to
+// into map[string]interface{}, which can be encoded as JSON
+func stringKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			m[fmt.Sprint(k)] = stringKeys(child)
+		}
+		return m
+	case map[string]interface{}:
+		for k, child := range val {
+			val[k] = stringKeys(child)
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = stringKeys(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,114 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// WithStringCoercion parses strings in JSON, YAML and .env files which are
+// decoded into bool, integer, float or time.Duration fields, as strconv and
+// time.ParseDuration do, e.g. "8080" into an int and "30s" into a duration,
+// for files generated from sources where every value is a string, such as
+// environment variables. A string which doesn't parse is an error naming the
+// key and the value. Values which aren't strings decode as usual, as do
+// types with their own Unmarshal methods.
+//
+// A .env file is coerced as the YAML document of its keys, so its quoted
+// values are parsed as well as its unquoted ones.
+func WithStringCoercion() Option {
+	return func(l *Loader) {
+		l.stringCoercion = true
+	}
+}
+
+// stringCoercionHook is the typeHook for WithStringCoercion
+type stringCoercionHook struct{}
+
+func (stringCoercionHook) matches(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return !decodesItself(t, docJSON) && !decodesItself(t, docYAML)
+	}
+	return false
+}
+
+func (stringCoercionHook) decode(format string, t reflect.Type, node interface{}) (reflect.Value, error) {
+	s, ok := node.(string)
+	if !ok {
+		// Decoded as the format would have without the hook
+		content, err := encodeDocument(format, node)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value := reflect.New(t)
+		if format == docYAML {
+			err = yaml.Unmarshal(content, value.Interface())
+		} else {
+			err = json.Unmarshal(content, value.Interface())
+		}
+		return value.Elem(), err
+	}
+
+	value := reflect.New(t).Elem()
+	trimmed := strings.TrimSpace(s)
+	var err error
+	switch kind := t.Kind(); {
+	case t == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(trimmed); err == nil {
+			value.SetInt(int64(d))
+		}
+	case kind == reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(trimmed); err == nil {
+			value.SetBool(b)
+		}
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(trimmed, 10, t.Bits()); err == nil {
+			value.SetInt(i)
+		}
+	case kind >= reflect.Uint && kind <= reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(trimmed, 10, t.Bits()); err == nil {
+			value.SetUint(u)
+		}
+	default:
+		var f float64
+		if f, err = strconv.ParseFloat(trimmed, t.Bits()); err == nil {
+			value.SetFloat(f)
+		}
+	}
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("Cannot parse %q as %s", s, t)
+	}
+	return value, nil
+}
+
+// dotenvYAMLDecoder wraps a YAML decoder to take a .env file, decoded by
+// dotenv into the generic document of its keys, so that the wrappers of a
+// YAML decoder apply to it
+func dotenvYAMLDecoder(dotenv, decoder DecoderFunc) DecoderFunc {
+	return func(r io.Reader, into interface{}) error {
+		doc := map[string]interface{}{}
+		if err := dotenv(r, &doc); err != nil {
+			return err
+		}
+		content, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		return decoder(bytes.NewReader(content), into)
+	}
+}
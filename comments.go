@@ -0,0 +1,62 @@
+package loadfile
+
+import "fmt"
+
+// WithJSONComments allows // line and /* block */ comments in JSON files,
+// which are otherwise a syntax error. Nothing else beyond strict JSON is
+// accepted, e.g. trailing commas are still rejected.
+func WithJSONComments() Option {
+	return func(l *Loader) {
+		l.jsonComments = true
+	}
+}
+
+// stripJSONComments replaces comments outside of strings with spaces, keeping
+// newlines so that syntax error offsets still point at the right line
+func stripJSONComments(content []byte) ([]byte, error) {
+	out := make([]byte, len(content))
+	copy(out, content)
+	inString := false
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			continue
+		}
+		if c != '/' || i+1 >= len(out) {
+			continue
+		}
+		switch out[i+1] {
+		case '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case '*':
+			start := i
+			out[i], out[i+1] = ' ', ' '
+			for i += 2; ; i++ {
+				if i+1 >= len(out) {
+					return nil, fmt.Errorf("Unterminated comment at offset %d", start)
+				}
+				if out[i] == '*' && out[i+1] == '/' {
+					out[i], out[i+1] = ' ', ' '
+					i++
+					break
+				}
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+		}
+	}
+	return out, nil
+}
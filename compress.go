@@ -0,0 +1,103 @@
+package loadfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// codec wraps a compressed stream with a decompressing reader. If the reader
+// it returns is an io.Closer it is closed once decoding is done, the
+// compressed stream itself is closed separately.
+type codec func(r io.Reader) (io.Reader, error)
+
+// codecs maps compressed file extensions to their codec. The extension is
+// removed before the format is detected, so config.yaml.gz is decoded as
+// YAML.
+var codecs = map[string]codec{
+	"gz": func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	"bz2": func(r io.Reader) (io.Reader, error) {
+		return bzip2.NewReader(r), nil
+	},
+	"zst": func(r io.Reader) (io.Reader, error) {
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	},
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// WithGzipDetection decompresses gzip content whatever the file is called, by
+// checking for the gzip magic bytes. The format is still taken from the
+// filename, so a gzipped config.json is decoded as JSON. Files ending in .gz
+// are always decompressed, this is for compressed files which aren't named
+// that way.
+func WithGzipDetection() Option {
+	return func(l *Loader) {
+		l.detectGzip = true
+	}
+}
+
+// readCloser pairs a reader with the Close of something it wraps
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// asReadCloser returns r with a no-op Close unless it already has one
+func asReadCloser(r io.Reader) io.ReadCloser {
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc
+	}
+	return ioutil.NopCloser(r)
+}
+
+// decompress wraps reader to decompress files with a codec's extension,
+// returning the filename with that extension removed so that the format can be
+// found from it. Closing the returned reader releases the decompressor but
+// does not close reader. Any read limit applies to the decompressed stream.
+func (l *Loader) decompress(filename string, reader io.Reader) (string, io.ReadCloser, error) {
+	formatName, decompressed, err := l.decompressRaw(filename, reader)
+	if err != nil {
+		return "", nil, err
+	}
+	return formatName, l.limit(decompressed), nil
+}
+
+func (l *Loader) decompressRaw(filename string, reader io.Reader) (string, io.ReadCloser, error) {
+	if wrap, ok := codecs[fileExtension(filename)]; ok {
+		decompressed, err := wrap(reader)
+		if err != nil {
+			return "", nil, err
+		}
+		return trimExtension(filename), asReadCloser(decompressed), nil
+	}
+
+	if l.detectGzip {
+		buffered := bufio.NewReader(reader)
+		magic, _ := buffered.Peek(len(gzipMagic))
+		if bytes.Equal(magic, gzipMagic) {
+			gz, err := gzip.NewReader(buffered)
+			if err != nil {
+				return "", nil, err
+			}
+			return filename, gz, nil
+		}
+		return filename, ioutil.NopCloser(buffered), nil
+	}
+
+	if cr, ok := reader.(*contentReader); ok {
+		return filename, cr, nil
+	}
+	return filename, ioutil.NopCloser(reader), nil
+}
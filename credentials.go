@@ -0,0 +1,94 @@
+package loadfile
+
+import (
+	"errors"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// CredentialSource supplies the credentials the clients of S3Loader and
+// AppConfigLoader use, as an aws.Config applied over the default session. The
+// session is built from the shared config, so a source can use it to call STS
+// with the default credentials.
+type CredentialSource interface {
+	AWSConfig(sess *session.Session) (*aws.Config, error)
+}
+
+// StaticCredentials is a CredentialSource with fixed keys
+type StaticCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func (sc StaticCredentials) AWSConfig(sess *session.Session) (*aws.Config, error) {
+	creds := credentials.NewStaticCredentials(sc.AccessKeyID, sc.SecretAccessKey, sc.SessionToken)
+	return &aws.Config{Credentials: creds}, nil
+}
+
+// EnvCredentials is a CredentialSource reading only AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, ignoring shared config and
+// instance roles
+type EnvCredentials struct{}
+
+func (EnvCredentials) AWSConfig(sess *session.Session) (*aws.Config, error) {
+	return &aws.Config{Credentials: credentials.NewEnvCredentials()}, nil
+}
+
+// AssumeRoleCredentials is a CredentialSource which assumes RoleARN through
+// STS using the default credentials, e.g. to reach a bucket in another account
+type AssumeRoleCredentials struct {
+	RoleARN string
+
+	// ExternalID is passed to AssumeRole when set
+	ExternalID string
+}
+
+func (ar AssumeRoleCredentials) AWSConfig(sess *session.Session) (*aws.Config, error) {
+	creds := stscreds.NewCredentials(sess, ar.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if ar.ExternalID != "" {
+			p.ExternalID = aws.String(ar.ExternalID)
+		}
+	})
+	return &aws.Config{Credentials: creds}, nil
+}
+
+// WebIdentityCredentials is a CredentialSource which exchanges a web identity
+// token for RoleARN's credentials, e.g. the service account token EKS
+// workload identity mounts. Empty fields are read from the variables EKS
+// (IRSA) sets, AWS_ROLE_ARN, AWS_WEB_IDENTITY_TOKEN_FILE and
+// AWS_ROLE_SESSION_NAME.
+type WebIdentityCredentials struct {
+	RoleARN string
+
+	// TokenFile is the path of the token, re-read whenever the credentials
+	// are refreshed
+	TokenFile string
+
+	// SessionName identifies the session in CloudTrail, loadfile when empty
+	SessionName string
+}
+
+func (wi WebIdentityCredentials) AWSConfig(sess *session.Session) (*aws.Config, error) {
+	roleARN := firstNonEmpty(wi.RoleARN, os.Getenv("AWS_ROLE_ARN"))
+	tokenFile := firstNonEmpty(wi.TokenFile, os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if roleARN == "" || tokenFile == "" {
+		return nil, errors.New("Web identity credentials need a role ARN and token file, from the fields or AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	sessionName := firstNonEmpty(wi.SessionName, os.Getenv("AWS_ROLE_SESSION_NAME"), "loadfile")
+	creds := stscreds.NewWebIdentityCredentials(sess, roleARN, sessionName, tokenFile)
+	return &aws.Config{Credentials: creds}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
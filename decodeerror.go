@@ -0,0 +1,122 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// DecodeError is returned when a file can't be decoded, with the position of
+// the problem when the decoder reports one. Line and Column start at 1, and
+// are 0 when not known: JSON and TOML errors give both, YAML and XML only the
+// line. A registered DecoderFunc can report a position by returning an error
+// with a Position() (line, column int) method. The position is in the content
+// as decoded, after includes, templates and the like are applied.
+type DecodeError struct {
+	Filename string
+	Line     int
+	Column   int
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	filename := redactURL(e.Filename)
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", filename, e.Line, e.Column, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", filename, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", filename, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+var reYAMLErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// newDecodeError finds the position of err, with read being as much of the
+// content as the decoder had read
+func newDecodeError(filename string, read []byte, err error) *DecodeError {
+	de := &DecodeError{Filename: filename, Err: err}
+
+	// Each key of a MultiError was decoded from a document of its own, so
+	// positions in them aren't positions in the file
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		return de
+	}
+
+	var jsonSyntax *json.SyntaxError
+	var jsonType *json.UnmarshalTypeError
+	var xmlSyntax *xml.SyntaxError
+	var positioned interface{ Position() (int, int) }
+	var yamlType *yaml.TypeError
+	var yamlV3Type *yamlv3.TypeError
+	switch {
+	case errors.As(err, &jsonSyntax):
+		de.Line, de.Column = offsetPosition(read, jsonSyntax.Offset)
+	case errors.As(err, &jsonType):
+		de.Line, de.Column = offsetPosition(read, jsonType.Offset)
+	case errors.As(err, &xmlSyntax):
+		de.Line = xmlSyntax.Line
+	case errors.As(err, &positioned):
+		// toml.DecodeError, among others
+		de.Line, de.Column = positioned.Position()
+	case errors.As(err, &yamlType) && len(yamlType.Errors) > 0:
+		de.Line = yamlErrorLine(yamlType.Errors[0])
+	case errors.As(err, &yamlV3Type) && len(yamlV3Type.Errors) > 0:
+		de.Line = yamlErrorLine(yamlV3Type.Errors[0])
+	default:
+		// yaml syntax errors are plain errors, "yaml: line 3: ..."
+		if strings.HasPrefix(err.Error(), "yaml:") {
+			de.Line = yamlErrorLine(err.Error())
+		}
+	}
+	return de
+}
+
+func yamlErrorLine(msg string) int {
+	match := reYAMLErrorLine.FindStringSubmatch(msg)
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
+
+// offsetPosition converts a byte offset, as the JSON decoder reports it, to a
+// line and column. The decoder gives the offset after the byte at fault, so
+// this is the position of the byte before the offset.
+func offsetPosition(content []byte, offset int64) (int, int) {
+	if offset <= 0 || offset > int64(len(content)) {
+		return 0, 0
+	}
+	before := content[:offset-1]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// recordingReader keeps everything read through it, to find the position of a
+// decode error
+type recordingReader struct {
+	r    io.Reader
+	read bytes.Buffer
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.read.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,57 @@
+package loadfile
+
+import (
+	"io"
+	"time"
+)
+
+// DefaultFormatter is implemented by a TypeLoader which knows the format of
+// what it fetches when nothing else does, e.g. a backend which never gives
+// useful type hints. DefaultFormat is used when neither the reader, as a
+// FormatReader, nor the filename's extension name a format. It returns "" to
+// leave the format to the usual fallback, JSON.
+type DefaultFormatter interface {
+	DefaultFormat() Format
+}
+
+// defaultExtension returns ext, or the DefaultFormat of filename's TypeLoader
+// when ext isn't the extension of a format
+func (l *Loader) defaultExtension(filename, ext string) string {
+	if l.isFormat(ext) {
+		return ext
+	}
+	rg, err := l.getReaderGetter(filename)
+	if err != nil {
+		return ext
+	}
+	if df, ok := rg.(DefaultFormatter); ok {
+		if format := df.DefaultFormat(); format != "" {
+			return string(format)
+		}
+	}
+	return ext
+}
+
+// LoadAs is Load, decoding the file as format whatever its extension, or its
+// TypeLoader, says it is, e.g. for an endpoint which mislabels its content.
+// Every extension is ignored, so a compressed file is only decompressed when
+// the Loader was created WithGzipDetection.
+func (l *Loader) LoadAs(filename string, format Format, into interface{}) (err error) {
+	start := time.Now()
+	defer func() {
+		l.stats.recordLoad(time.Since(start), err)
+	}()
+	reader, err := l.GetReader(filename)
+	if err != nil {
+		return err
+	}
+	if readCloser, ok := reader.(io.Closer); ok {
+		defer readCloser.Close()
+	}
+	return l.decode(filename, formatReader{Reader: reader, ext: string(format)}, into)
+}
+
+// LoadAs loads a file as format, using the default loader
+func LoadAs(filename string, format Format, into interface{}) error {
+	return DefaultLoader.LoadAs(filename, format, into)
+}
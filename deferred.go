@@ -0,0 +1,67 @@
+package loadfile
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// LoadDeferred returns each top level section of a JSON or YAML object
+// unparsed, keyed by its top level key, so that each can be decoded later by
+// whatever owns it, e.g. a plugin whose schema isn't known yet. Sections are
+// in the file's own format: JSON sections are the original bytes, as with
+// json.RawMessage, YAML sections are the section's own nodes re-encoded, so
+// scalars such as 1.10 or yes and comments are as written, with aliases
+// and << merges replaced by what they refer to. As JSON is also YAML, yaml.Unmarshal
+// decodes a section from either.
+func (l *Loader) LoadDeferred(filename string) (map[string][]byte, error) {
+	sections := map[string][]byte{}
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch l.docFormat(ext) {
+	case docJSON:
+		raw := map[string]json.RawMessage{}
+		if err := json.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		for key, section := range raw {
+			sections[key] = section
+		}
+	case docYAML:
+		nodes := map[string]yamlv3.Node{}
+		if err := yamlv3.Unmarshal(content, &nodes); err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		for key, node := range nodes {
+			// the node is encoded as written, but aliases of anchors in other
+			// sections are expanded as they won't be there, and so are merges
+			node := node
+			expanded, err := expandedCopy(&node)
+			var section []byte
+			if err == nil {
+				inlineNestedMerges(expanded)
+				section, err = yamlv3.Marshal(expanded)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", redactURL(filename), key, err)
+			}
+			sections[key] = section
+		}
+	default:
+		return nil, fmt.Errorf("%s: format %q can't be split into sections", redactURL(filename), ext)
+	}
+	return sections, nil
+}
+
+// LoadDeferred returns the unparsed top level sections of a file, using the
+// default loader
+func LoadDeferred(filename string) (map[string][]byte, error) {
+	return DefaultLoader.LoadDeferred(filename)
+}
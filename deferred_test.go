@@ -0,0 +1,77 @@
+package loadfile
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLoadDeferredSections(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "json as written",
+			file:    "app.json",
+			content: `{"a": {"v": 1.10,  "big": 18446744073709551616}, "b": "x<y"}`,
+			want:    map[string]string{"a": `{"v": 1.10,  "big": 18446744073709551616}`, "b": `"x<y"`},
+		},
+		{
+			name:    "yaml scalars",
+			file:    "app.yaml",
+			content: "a:\n  v: 1.10\n  zip: 01234\n  ok: yes\n  big: 18446744073709551616\nb: '1.10'\n",
+			want:    map[string]string{"a": "v: 1.10\nzip: 01234\nok: yes\nbig: 18446744073709551616\n", "b": "'1.10'\n"},
+		},
+		{
+			name:    "yaml comments",
+			file:    "app.yaml",
+			content: "a:\n  v: 1 # kept\n",
+			want:    map[string]string{"a": "v: 1 # kept\n"},
+		},
+		{
+			name:    "yaml aliases",
+			file:    "app.yaml",
+			content: "a: &shared\n  v: 1.10\nb:\n  <<: *shared\n  w: 2\n",
+			want:    map[string]string{"a": "v: 1.10\n", "b": "w: 2\nv: 1.10\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			sections, err := NewLoader().LoadDeferred(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sections) != len(tc.want) {
+				t.Errorf("got %d sections, want %d", len(sections), len(tc.want))
+			}
+			for key, want := range tc.want {
+				if got := string(sections[key]); got != want {
+					t.Errorf("section %s is %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDeferredRawMessage(t *testing.T) {
+	content := `{"a": {"v": 1.10}, "b": [1e3,  "<"]}`
+	filename := writeTestFile(t, "app.json", content)
+	sections, err := NewLoader().LoadDeferred(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != len(raw) {
+		t.Errorf("got %d sections, want %d", len(sections), len(raw))
+	}
+	for key, want := range raw {
+		if got := string(sections[key]); got != string(want) {
+			t.Errorf("section %s is %q, want %q as json.RawMessage has it", key, got, want)
+		}
+	}
+}
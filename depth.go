@@ -0,0 +1,115 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ErrTooDeep is returned by a Loader created WithMaxDepth for a document
+// nested more deeply than allowed
+var ErrTooDeep = errors.New("Document is nested too deeply")
+
+// WithMaxDepth rejects JSON and YAML documents with objects and arrays nested
+// more than n levels deep, returning ErrTooDeep before anything is decoded, to
+// protect against adversarial files. A flat object is 1 level.
+func WithMaxDepth(n int) Option {
+	return func(l *Loader) {
+		l.maxDepth = n
+	}
+}
+
+// checkDepth returns ErrTooDeep if content nests more than max levels
+func checkDepth(format string, content []byte, max int) error {
+	switch format {
+	case docJSON:
+		return checkJSONDepth(content, max)
+	case docYAML:
+		dec := yamlv3.NewDecoder(bytes.NewReader(content))
+		for {
+			node := &yamlv3.Node{}
+			err := dec.Decode(node)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if nodeDepth(node, map[*yamlv3.Node]int{}) > max {
+				return fmt.Errorf("%w: more than %d levels", ErrTooDeep, max)
+			}
+		}
+	}
+	return nil
+}
+
+// checkJSONDepth counts nesting with the tokenizer, without building values
+func checkJSONDepth(content []byte, max int) error {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			continue
+		}
+		switch delim {
+		case '{', '[':
+			depth++
+			if depth > max {
+				return fmt.Errorf("%w: more than %d levels", ErrTooDeep, max)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+}
+
+// nodeDepth returns how many levels of mappings and sequences node nests,
+// with aliases expanded as the decoder would expand them. The depth of each
+// anchored node is kept in depths, so a node aliased many times is only
+// walked once.
+func nodeDepth(node *yamlv3.Node, depths map[*yamlv3.Node]int) int {
+	if node.Kind == yamlv3.AliasNode {
+		if node.Alias == nil {
+			return 0
+		}
+		return nodeDepth(node.Alias, depths)
+	}
+	if depth, ok := depths[node]; ok {
+		return depth
+	}
+	if node.Anchor != "" {
+		// An alias within the node it refers to is left to the decoder
+		// to reject
+		depths[node] = 0
+	}
+	deepest := 0
+	for i, child := range node.Content {
+		depth := nodeDepth(child, depths)
+		if node.Kind == yamlv3.MappingNode && i%2 == 1 && isMergeKey(node.Content[i-1]) && depth > 0 {
+			// Merged keys are the mapping's own, not a level within it
+			depth--
+		}
+		if depth > deepest {
+			deepest = depth
+		}
+	}
+	if node.Kind == yamlv3.MappingNode || node.Kind == yamlv3.SequenceNode {
+		deepest++
+	}
+	if node.Anchor != "" {
+		depths[node] = deepest
+	}
+	return deepest
+}
@@ -0,0 +1,79 @@
+package loadfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckDepth(t *testing.T) {
+	// Each level aliases the one before it twice, so expanded it is as deep
+	// as the number of levels, and wide enough to need memoising
+	aliased := "a0: &a0 [x]\n"
+	for i := 1; i < 6; i++ {
+		aliased += strings.Replace("aN: &aN [*aM, *aM]\n", "N", string(rune('0'+i)), 2)
+		aliased = strings.Replace(aliased, "M", string(rune('0'+i-1)), 2)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		format  string
+		content string
+		max     int
+		wantErr bool
+	}{{
+		name:    "flat json",
+		format:  docJSON,
+		content: `{"a": 1}`,
+		max:     1,
+	}, {
+		name:    "deep json",
+		format:  docJSON,
+		content: `{"a": {"b": [1]}}`,
+		max:     2,
+		wantErr: true,
+	}, {
+		name:    "yaml within the limit",
+		format:  docYAML,
+		content: "a:\n  b: 1\n",
+		max:     2,
+	}, {
+		name:    "deep yaml",
+		format:  docYAML,
+		content: "a:\n  b:\n    c: 1\n",
+		max:     2,
+		wantErr: true,
+	}, {
+		name:    "depth built from aliases",
+		format:  docYAML,
+		content: aliased,
+		max:     3,
+		wantErr: true,
+	}, {
+		name:    "aliases within the limit",
+		format:  docYAML,
+		content: aliased,
+		max:     7,
+	}, {
+		name:    "merged keys are one level",
+		format:  docYAML,
+		content: "base: &b {x: 1}\nitem:\n  <<: *b\n  y: 2\n",
+		max:     2,
+	}, {
+		name:    "later documents",
+		format:  docYAML,
+		content: "a: 1\n---\na: {b: {c: 1}}\n",
+		max:     2,
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkDepth(tc.format, []byte(tc.content), tc.max)
+			if tc.wantErr != errors.Is(err, ErrTooDeep) {
+				t.Fatalf("got %v, want ErrTooDeep %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package loadfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind is what happened to a value between two documents
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is one difference found by Diff. Path is the dotted path of keys to
+// the value, with list elements by their position, e.g. servers.0.port, or by
+// their key when the list is keyed, e.g. servers[name=web].port. Old is nil
+// for an added value and New for a removed one. Values are as in an
+// OrderedMap.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, diffValue(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, diffValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Path, diffValue(c.Old), diffValue(c.New))
+}
+
+// Changes is the list of differences between two documents
+type Changes []Change
+
+// String returns one line per change, + for added, - for removed and ~ for
+// changed, with values as JSON
+func (c Changes) String() string {
+	lines := make([]string, len(c))
+	for i, change := range c {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffKeys are the keys which identify the elements of a list of objects,
+// so that they are matched by key rather than position, in order of
+// preference
+var diffKeys = []string{"name", "id", "key"}
+
+// Diff loads two JSON or YAML files with LoadOrdered and returns what changed
+// from a to b, in the order of a's keys, with keys only in b after them.
+// Nested objects are compared key by key. Lists are compared element by
+// element: by key when every element of both is an object with a distinct
+// name, id or key, otherwise by position. Numbers are equal by value, so 1 in
+// a JSON file is the same as 1.0 in YAML.
+func (l *Loader) Diff(a, b string) (Changes, error) {
+	docA, err := l.LoadOrdered(a)
+	if err != nil {
+		return nil, err
+	}
+	docB, err := l.LoadOrdered(b)
+	if err != nil {
+		return nil, err
+	}
+	changes := Changes{}
+	diffDocuments(&changes, "", docA, docB)
+	return changes, nil
+}
+
+// Diff returns the changes between two files, using the default loader
+func Diff(a, b string) (Changes, error) {
+	return DefaultLoader.Diff(a, b)
+}
+
+func diffDocuments(changes *Changes, path string, a, b interface{}) {
+	switch aVal := a.(type) {
+	case *OrderedMap:
+		if bVal, ok := b.(*OrderedMap); ok {
+			diffMaps(changes, path, aVal, bVal)
+			return
+		}
+	case []interface{}:
+		if bVal, ok := b.([]interface{}); ok {
+			diffLists(changes, path, aVal, bVal)
+			return
+		}
+	}
+	if !equalLeaves(a, b) {
+		*changes = append(*changes, Change{Kind: ChangeChanged, Path: path, Old: a, New: b})
+	}
+}
+
+func diffMaps(changes *Changes, path string, a, b *OrderedMap) {
+	for _, key := range a.Keys {
+		bValue, ok := b.Get(key)
+		if !ok {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: joinPath(path, key), Old: a.Values[key]})
+			continue
+		}
+		diffDocuments(changes, joinPath(path, key), a.Values[key], bValue)
+	}
+	for _, key := range b.Keys {
+		if _, ok := a.Get(key); !ok {
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: joinPath(path, key), New: b.Values[key]})
+		}
+	}
+}
+
+func diffLists(changes *Changes, path string, a, b []interface{}) {
+	if key := listKey(a, b); key != "" {
+		bByKey := map[string]interface{}{}
+		for _, element := range b {
+			bByKey[elementKey(element, key)] = element
+		}
+		aByKey := map[string]bool{}
+		for _, element := range a {
+			id := elementKey(element, key)
+			aByKey[id] = true
+			elementPath := fmt.Sprintf("%s[%s=%s]", path, key, id)
+			if bElement, ok := bByKey[id]; ok {
+				diffDocuments(changes, elementPath, element, bElement)
+			} else {
+				*changes = append(*changes, Change{Kind: ChangeRemoved, Path: elementPath, Old: element})
+			}
+		}
+		for _, element := range b {
+			if id := elementKey(element, key); !aByKey[id] {
+				*changes = append(*changes, Change{Kind: ChangeAdded, Path: fmt.Sprintf("%s[%s=%s]", path, key, id), New: element})
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		elementPath := joinPath(path, fmt.Sprint(i))
+		switch {
+		case i >= len(b):
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: elementPath, Old: a[i]})
+		case i >= len(a):
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: elementPath, New: b[i]})
+		default:
+			diffDocuments(changes, elementPath, a[i], b[i])
+		}
+	}
+}
+
+// listKey returns the key which identifies every element of both lists, or ""
+func listKey(a, b []interface{}) string {
+	if len(a) == 0 && len(b) == 0 {
+		return ""
+	}
+	for _, key := range diffKeys {
+		if isKeyedBy(a, key) && isKeyedBy(b, key) {
+			return key
+		}
+	}
+	return ""
+}
+
+// isKeyedBy returns true if every element of list is an object with a
+// distinct scalar value for key
+func isKeyedBy(list []interface{}, key string) bool {
+	seen := map[string]bool{}
+	for _, element := range list {
+		m, ok := element.(*OrderedMap)
+		if !ok {
+			return false
+		}
+		value, ok := m.Get(key)
+		if !ok {
+			return false
+		}
+		switch value.(type) {
+		case *OrderedMap, []interface{}, nil:
+			return false
+		}
+		id := fmt.Sprint(value)
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+func elementKey(element interface{}, key string) string {
+	value, _ := element.(*OrderedMap).Get(key)
+	return fmt.Sprint(value)
+}
+
+// equalLeaves compares values which aren't both objects or both lists
+func equalLeaves(a, b interface{}) bool {
+	if aNum, ok := documentNumber(a); ok {
+		bNum, ok := documentNumber(b)
+		return ok && aNum == bNum
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// diffValue formats a value as JSON
+func diffValue(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,197 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	docJSON = "json"
+	docYAML = "yaml"
+	docXML  = "xml"
+	docTOML = "toml"
+)
+
+// docFormat returns which of the built in formats handles the extension, or ""
+// for formats added with RegisterFormat
+func (l *Loader) docFormat(ext string) string {
+	switch ext = l.aliasedExtension(ext); ext {
+	case "yml", "yaml":
+		return docYAML
+	case "xml":
+		return docXML
+	case "json":
+		return docJSON
+	case "toml":
+		return docTOML
+	}
+	if l.isFormat(ext) {
+		return ""
+	}
+	return docJSON
+}
+
+// parseDocument parses JSON or YAML into generic values, where objects are
+// map[string]interface{} and arrays []interface{}, whichever format they came
+// from
+func parseDocument(format string, b []byte) (interface{}, error) {
+	var doc interface{}
+	switch format {
+	case docJSON:
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+	case docYAML:
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+	case docTOML:
+		if err := toml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Format %q can't be decoded generically", format)
+	}
+	return normalizeDocument(doc), nil
+}
+
+// decodeJSONNumbers parses JSON into generic values as parseDocument does,
+// but keeps numbers as json.Number, so that re-encoding writes them as they
+// were rather than as an int64 or float64
+func decodeJSONNumbers(b []byte) (interface{}, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// normalizeDocument converts YAML's map[interface{}]interface{} into
+// map[string]interface{}, and JSON numbers into int64 or float64, so that
+// documents from either format can be mixed and re-encoded into either
+func normalizeDocument(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			m[fmt.Sprint(k)] = normalizeDocument(child)
+		}
+		return m
+	case map[string]interface{}:
+		for k, child := range val {
+			val[k] = normalizeDocument(child)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = normalizeDocument(child)
+		}
+		return val
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	}
+	return v
+}
+
+// documentPaths returns the dotted path of every leaf value in a normalized
+// document. Slices are leaves.
+func documentPaths(doc interface{}, prefix string) []string {
+	m, ok := doc.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		if prefix == "" {
+			return nil
+		}
+		return []string{prefix}
+	}
+	paths := []string{}
+	for key, child := range m {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		paths = append(paths, documentPaths(child, path)...)
+	}
+	return paths
+}
+
+// encodeDocument is the inverse of parseDocument
+func encodeDocument(format string, doc interface{}) ([]byte, error) {
+	switch format {
+	case docJSON:
+		return json.Marshal(doc)
+	case docYAML:
+		return yaml.Marshal(doc)
+	}
+	return nil, fmt.Errorf("Format %q can't be encoded generically", format)
+}
+
+// deepMerge merges src into dst, values in src winning. Nested maps are merged
+// recursively, anything else in src replaces the value in dst.
+func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[key] = deepMerge(dstMap, srcMap)
+			continue
+		}
+		dst[key] = srcValue
+	}
+	return dst
+}
+
+// readPrepared fetches filename and runs prepare on it, returning the
+// extension of its format and the content ready to decode. An empty file
+// returns ErrEmpty.
+func (l *Loader) readPrepared(filename string) (string, []byte, error) {
+	r, err := l.GetReadCloser(filename)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+	ext, prepared, err := l.prepare(filename, r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer prepared.Close()
+	content, err := ioutil.ReadAll(prepared)
+	if err != nil {
+		return "", nil, err
+	}
+	return ext, content, nil
+}
+
+// readAll fetches the whole content of filename through the loader
+func (l *Loader) readAll(filename string) ([]byte, error) {
+	r, err := l.GetReadCloser(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return l.readFetched(r)
+}
+
+// readFetched reads the whole of r, as fetched, within the read limit
+func (l *Loader) readFetched(r io.Reader) ([]byte, error) {
+	if l.readLimit > 0 {
+		r = &limitedReader{r: r, n: l.readLimit}
+	}
+	return ioutil.ReadAll(r)
+}
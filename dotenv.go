@@ -0,0 +1,170 @@
+package loadfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var reDotenvLine = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+// decodeDotenv decodes a .env file of KEY=value lines. Blank lines and lines
+// starting with # are skipped, as is an export before the key. A value may be
+// single quoted, taken literally, or double quoted, where \n, \t, \" and \\
+// are escapes. An unquoted value ends at a # after whitespace.
+//
+// ${KEY} and ${KEY:-default} in double quoted and unquoted values are
+// replaced as the file is read, in order, by the value of KEY from an earlier
+// line, otherwise the environment variable KEY, otherwise default or "". A
+// reference to a key which is only set on a later line is an error, rather
+// than quietly using the environment, so that
+//
+//	BASE_URL=https://api.example.com
+//	API_URL=${BASE_URL}/v1
+//
+// gives API_URL https://api.example.com/v1, and the lines the other way round
+// fail.
+//
+// Keys decode into fields as YAML mapping keys do, so a field usually needs a
+// tag such as yaml:"API_URL". Unquoted values are typed as YAML scalars, so
+// PORT=5432 can be decoded into an int, and quoted values are strings.
+func decodeDotenv(r io.Reader, into interface{}) error {
+	lines, err := readDotenvLines(r)
+	if err != nil {
+		return err
+	}
+
+	// The line each key is first set on
+	first := map[string]int{}
+	for _, line := range lines {
+		if _, ok := first[line.key]; !ok {
+			first[line.key] = line.number
+		}
+	}
+	values := map[string]string{}
+	mapping := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	for _, line := range lines {
+		value := line.value
+		if line.quote != '\'' {
+			var refErr error
+			value = reEnvPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+				match := reEnvPlaceholder.FindStringSubmatch(placeholder)
+				name := match[1]
+				if resolved, ok := values[name]; ok {
+					if resolved != "" || !strings.Contains(placeholder, ":-") {
+						return resolved
+					}
+				} else if number := first[name]; number > line.number && refErr == nil {
+					refErr = fmt.Errorf("Line %d: ${%s} refers to a key which is only set on line %d", line.number, name, number)
+				} else if env := os.Getenv(name); env != "" {
+					return env
+				}
+				return match[2]
+			})
+			if refErr != nil {
+				return refErr
+			}
+		}
+		values[line.key] = value
+
+		// A key set again replaces the earlier value
+		for i := 0; i < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value == line.key {
+				mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+				break
+			}
+		}
+		valueNode := &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: value}
+		if line.quote != 0 {
+			valueNode.Style = yamlv3.DoubleQuotedStyle
+		}
+		mapping.Content = append(mapping.Content,
+			&yamlv3.Node{Kind: yamlv3.ScalarNode, Value: line.key},
+			valueNode,
+		)
+	}
+	if len(mapping.Content) == 0 {
+		return nil
+	}
+	return mapping.Decode(into)
+}
+
+// dotenvLine is a KEY=value line, with the value unquoted and unescaped
+type dotenvLine struct {
+	number int
+	key    string
+	value  string
+	// quote is the quote the value was in, or 0
+	quote byte
+}
+
+func readDotenvLines(r io.Reader) ([]dotenvLine, error) {
+	lines := []dotenvLine{}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		match := reDotenvLine.FindStringSubmatch(text)
+		if match == nil {
+			return nil, fmt.Errorf("Line %d: expected KEY=value", number)
+		}
+		line := dotenvLine{number: number, key: match[1]}
+		value, err := unquoteDotenv(match[2], &line.quote)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %w", number, err)
+		}
+		line.value = value
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// unquoteDotenv returns the value of raw, setting quote to the quote it was
+// in, if any
+func unquoteDotenv(raw string, quote *byte) (string, error) {
+	if raw == "" || (raw[0] != '"' && raw[0] != '\'') {
+		if idx := strings.Index(raw, " #"); idx >= 0 {
+			raw = raw[:idx]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+	*quote = raw[0]
+	value := strings.Builder{}
+	for i := 1; i < len(raw); i++ {
+		c := raw[i]
+		if c == *quote {
+			if rest := strings.TrimSpace(raw[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", fmt.Errorf("Unexpected %q after the closing quote", rest)
+			}
+			return value.String(), nil
+		}
+		if c == '\\' && *quote == '"' && i+1 < len(raw) {
+			i++
+			switch raw[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			case '"', '\\':
+				c = raw[i]
+			default:
+				value.WriteByte('\\')
+				c = raw[i]
+			}
+		}
+		value.WriteByte(c)
+	}
+	return "", fmt.Errorf("Missing closing %c", *quote)
+}
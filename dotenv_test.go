@@ -0,0 +1,92 @@
+package loadfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDotenvReferences(t *testing.T) {
+	t.Setenv("LOADFILE_TEST_HOST", "env.example.com")
+	t.Setenv("BASE_URL", "https://env.example.com")
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{{
+		name:    "chained",
+		content: "BASE_URL=https://api.example.com\nAPI_URL=${BASE_URL}/v1\nDOCS_URL=${API_URL}/docs\n",
+		want: map[string]string{
+			"BASE_URL": "https://api.example.com",
+			"API_URL":  "https://api.example.com/v1",
+			"DOCS_URL": "https://api.example.com/v1/docs",
+		},
+	}, {
+		name:    "forward reference",
+		content: "API_URL=${BASE_URL}/v1\nBASE_URL=https://api.example.com\n",
+		wantErr: "Line 1: ${BASE_URL} refers to a key which is only set on line 2",
+	}, {
+		name:    "environment",
+		content: "HOST=${LOADFILE_TEST_HOST}\n",
+		want:    map[string]string{"HOST": "env.example.com"},
+	}, {
+		name:    "earlier line before the environment",
+		content: "LOADFILE_TEST_HOST=file.example.com\nHOST=${LOADFILE_TEST_HOST}\n",
+		want:    map[string]string{"LOADFILE_TEST_HOST": "file.example.com", "HOST": "file.example.com"},
+	}, {
+		name:    "default",
+		content: "HOST=${LOADFILE_TEST_MISSING:-localhost}\n",
+		want:    map[string]string{"HOST": "localhost"},
+	}, {
+		name:    "default for an empty earlier line",
+		content: "EMPTY=\nHOST=${EMPTY:-localhost}\n",
+		want:    map[string]string{"EMPTY": "", "HOST": "localhost"},
+	}, {
+		name:    "empty earlier line",
+		content: "EMPTY=\nHOST=${EMPTY}\n",
+		want:    map[string]string{"EMPTY": "", "HOST": ""},
+	}, {
+		name:    "unset",
+		content: "HOST=${LOADFILE_TEST_MISSING}\n",
+		want:    map[string]string{"HOST": ""},
+	}, {
+		name:    "set again",
+		content: "PATH_LIST=/bin\nPATH_LIST=${PATH_LIST}:/usr/bin\n",
+		want:    map[string]string{"PATH_LIST": "/bin:/usr/bin"},
+	}, {
+		name:    "double quoted",
+		content: "BASE=api\nURL=\"https://${BASE}.example.com\"\n",
+		want:    map[string]string{"BASE": "api", "URL": "https://api.example.com"},
+	}, {
+		name:    "single quoted",
+		content: "BASE=api\nURL='${BASE}'\n",
+		want:    map[string]string{"BASE": "api", "URL": "${BASE}"},
+	}, {
+		name:    "single quoted forward reference",
+		content: "URL='${BASE}'\nBASE=api\n",
+		want:    map[string]string{"BASE": "api", "URL": "${BASE}"},
+	}, {
+		name:    "comments and export",
+		content: "# comment\n\nexport BASE=api # trailing\nURL=${BASE}#not a comment\n",
+		want:    map[string]string{"BASE": "api", "URL": "api#not a comment"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, "app.env", tc.content)
+			got := map[string]string{}
+			err := NewLoader().Load(filename, &got)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want an error with %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
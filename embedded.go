@@ -0,0 +1,259 @@
+package loadfile
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// embeddingTypes caches whether a type has untagged embedded structs
+var embeddingTypes sync.Map
+
+// hasUntaggedEmbedded returns true if t, or any type it contains, embeds a
+// struct without a yaml tag
+func hasUntaggedEmbedded(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if cached, ok := embeddingTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	found := findUntaggedEmbedded(t, map[reflect.Type]bool{})
+	embeddingTypes.Store(t, found)
+	return found
+}
+
+func findUntaggedEmbedded(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findUntaggedEmbedded(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if isUntaggedEmbedded(f) {
+				return true
+			}
+			if f.PkgPath == "" && findUntaggedEmbedded(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isUntaggedEmbedded returns true for an embedded struct of an exported type
+// without a yaml tag, as yaml can't decode into unexported embedded types
+func isUntaggedEmbedded(f reflect.StructField) bool {
+	return f.Anonymous && f.PkgPath == "" && f.Type.Kind() == reflect.Struct && f.Tag.Get("yaml") == ""
+}
+
+// embeddedDecoder wraps a YAML decoder to decode untagged embedded structs as
+// encoding/json does.
+//
+// The YAML decoders only inline embedded structs tagged `yaml:",inline"`,
+// decoding an untagged embedded CommonConfig from a commonconfig key instead,
+// where encoding/json decodes its fields from the enclosing object. So that an
+// embedded struct is decoded the same way from either format, YAML documents
+// for a target with untagged embedded structs have the embedded fields' keys
+// moved under the key the YAML decoder expects. A commonconfig key in the file
+// still works, with any of its fields also given at the top level winning.
+// Types with their own UnmarshalYAML or UnmarshalJSON, including through an
+// embedded struct, are given their mapping as it is in the file. Only keys are
+// moved, so values are decoded as they are written.
+func embeddedDecoder(decoder DecoderFunc) DecoderFunc {
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		doc := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(content, doc); err != nil || len(doc.Content) != 1 {
+			// let the decoder report the syntax error
+			return decoder(bytes.NewReader(content), into)
+		}
+		if !flattenEmbedded(reflect.TypeOf(into), doc.Content[0], map[*yamlv3.Node]bool{}) {
+			return decoder(bytes.NewReader(content), into)
+		}
+		content, err = yamlv3.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		return decoder(bytes.NewReader(content), into)
+	}
+}
+
+var yamlv3UnmarshalerType = reflect.TypeOf((*yamlv3.Unmarshaler)(nil)).Elem()
+
+// unmarshalsItself returns true if t has an UnmarshalYAML or UnmarshalJSON
+// method, which is given the mapping as it is
+func unmarshalsItself(t reflect.Type) bool {
+	ptr := reflect.PtrTo(t)
+	return ptr.Implements(yamlUnmarshalerType) || ptr.Implements(yamlv3UnmarshalerType) || ptr.Implements(jsonUnmarshalerType)
+}
+
+// flattenEmbedded moves keys of node which belong to untagged embedded
+// structs of t under the embedded field's own key, returning true if it moved
+// any
+func flattenEmbedded(t reflect.Type, node *yamlv3.Node, seen map[*yamlv3.Node]bool) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yamlv3.AliasNode {
+		if seen[node] || node.Alias == nil {
+			return false
+		}
+		seen[node] = true
+		return flattenEmbedded(t, node.Alias, seen)
+	}
+	changed := false
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if node.Kind == yamlv3.SequenceNode {
+			for _, item := range node.Content {
+				changed = flattenEmbedded(t.Elem(), item, seen) || changed
+			}
+		}
+		return changed
+	case reflect.Map:
+		if node.Kind == yamlv3.MappingNode {
+			for i := 1; i < len(node.Content); i += 2 {
+				changed = flattenEmbedded(t.Elem(), node.Content[i], seen) || changed
+			}
+		}
+		return changed
+	case reflect.Struct:
+	default:
+		return false
+	}
+
+	if node.Kind != yamlv3.MappingNode || hasInlineMap(t) || unmarshalsItself(t) {
+		return false
+	}
+	if hasMergeKey(node) {
+		// Merged keys may need moving too, so merge them in first
+		inlineMerges(node)
+		changed = true
+	}
+	kept := make([]*yamlv3.Node, 0, len(node.Content))
+	moved := map[int]*yamlv3.Node{}
+	order := []int{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		index, ok := fieldForKey(t, docYAML, key.Value)
+		if !ok || key.Kind != yamlv3.ScalarNode {
+			kept = append(kept, key, value)
+			continue
+		}
+		field := t.Field(index[0])
+		if len(index) > 1 && isUntaggedEmbedded(field) && !unmarshalsItself(field.Type) {
+			if moved[index[0]] == nil {
+				moved[index[0]] = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+				order = append(order, index[0])
+			}
+			moved[index[0]].Content = append(moved[index[0]].Content, key, value)
+			continue
+		}
+		changed = flattenEmbedded(t.FieldByIndex(index).Type, value, seen) || changed
+		kept = append(kept, key, value)
+	}
+	node.Content = kept
+	for _, i := range order {
+		field := t.Field(i)
+		flattenEmbedded(field.Type, moved[i], seen)
+		name := strings.ToLower(field.Name)
+		j := mappingIndex(node, name)
+		if j >= 0 && node.Content[j].Kind == yamlv3.AliasNode && node.Content[j].Alias != nil {
+			// Merge into a copy, leaving the anchored mapping as it is
+			node.Content[j] = copyNode(node.Content[j].Alias)
+			node.Content[j].Anchor = ""
+		}
+		switch {
+		case j >= 0 && node.Content[j].Kind == yamlv3.MappingNode:
+			mergeNodes(node.Content[j], moved[i])
+		case j >= 0:
+			node.Content[j] = moved[i]
+		default:
+			node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: name}, moved[i])
+		}
+		changed = true
+	}
+	return changed
+}
+
+// hasMergeKey returns true if a mapping has a << key
+func hasMergeKey(node *yamlv3.Node) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if isMergeKey(node.Content[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineMerges replaces the << keys of a mapping with the keys they merge,
+// as the decoders would merge them: the mapping's own keys win, then those of
+// earlier merged mappings
+func inlineMerges(node *yamlv3.Node) {
+	own := []*yamlv3.Node{}
+	merges := []*yamlv3.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if isMergeKey(node.Content[i]) {
+			merges = append(merges, mergedNodes(node.Content[i+1])...)
+			continue
+		}
+		own = append(own, node.Content[i], node.Content[i+1])
+	}
+	node.Content = own
+	for _, merged := range merges {
+		merged = copyNode(merged)
+		inlineMerges(merged)
+		for i := 0; i+1 < len(merged.Content); i += 2 {
+			if mappingIndex(node, merged.Content[i].Value) < 0 {
+				node.Content = append(node.Content, merged.Content[i], merged.Content[i+1])
+			}
+		}
+	}
+}
+
+// mappingIndex returns the index of the value of key in a mapping's Content,
+// or -1
+func mappingIndex(node *yamlv3.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Kind == yamlv3.ScalarNode && node.Content[i].Value == key {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// mergeNodes merges the mapping src into dst as deepMerge does, values in src
+// winning
+func mergeNodes(dst, src *yamlv3.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value != key.Value {
+				continue
+			}
+			if existing := dst.Content[j+1]; existing.Kind == yamlv3.MappingNode && value.Kind == yamlv3.MappingNode {
+				mergeNodes(existing, value)
+			} else {
+				dst.Content[j+1] = value
+			}
+			replaced = true
+		}
+		if !replaced {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package loadfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+type EmbeddedCommon struct {
+	Version string
+	Flag    string
+	Code    string
+}
+
+// EmbeddedCustom decodes itself from the whole mapping
+type EmbeddedCustom struct {
+	Keys int
+}
+
+func (c *EmbeddedCustom) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	m := map[string]interface{}{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	c.Keys = len(m)
+	return nil
+}
+
+func TestEmbeddedDecoder(t *testing.T) {
+	type config struct {
+		EmbeddedCommon
+		Name string
+	}
+	type custom struct {
+		EmbeddedCustom
+		Name string
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		into    interface{}
+		want    interface{}
+	}{{
+		name:    "values as written",
+		content: "name: web\nversion: 1.10\nflag: yes\ncode: 01234\n",
+		into:    &config{},
+		want:    &config{Name: "web", EmbeddedCommon: EmbeddedCommon{Version: "1.10", Flag: "yes", Code: "01234"}},
+	}, {
+		name:    "top level wins over the embedded key",
+		content: "embeddedcommon:\n  version: '1'\n  flag: on\nversion: '2'\n",
+		into:    &config{},
+		want:    &config{EmbeddedCommon: EmbeddedCommon{Version: "2", Flag: "on"}},
+	}, {
+		name:    "merged keys",
+		content: "base: &b\n  version: 1.10\n  name: base\nitems:\n- <<: *b\n  name: web\n",
+		into:    &struct{ Items []config }{},
+		want:    &struct{ Items []config }{Items: []config{{Name: "web", EmbeddedCommon: EmbeddedCommon{Version: "1.10"}}}},
+	}, {
+		name:    "own unmarshaler gets the mapping as it is",
+		content: "name: web\na: 1\nb: 2\n",
+		into:    &custom{},
+		want:    &custom{EmbeddedCustom: EmbeddedCustom{Keys: 3}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := NewLoader().Load(writeTestFile(t, "config.yaml", tc.content), tc.into); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.into, tc.want) {
+				t.Errorf("got %+v, want %+v", tc.into, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reEnvFilename = regexp.MustCompile(`^env:\/\/(.+)$`)
+
+// EnvLoader reads a whole file from a single environment variable, named like
+// env://APP_CONFIG. The format is taken from a suffix on the variable name,
+// e.g. env://APP_CONFIG_YAML, otherwise from Format. A missing or empty
+// variable returns ErrNotFound.
+//
+// A file too large for one variable can be split across numbered variables,
+// named like env://APP_CONFIG_*, which are APP_CONFIG_0, APP_CONFIG_1 and so
+// on joined in order and base64 decoded. A gap in the numbering, or two
+// variables with the same number such as APP_CONFIG_1 and APP_CONFIG_01, is
+// an error, rather than a silently truncated file. A format suffix goes
+// before the *, e.g. env://APP_CONFIG_YAML_*, and may be any format the
+// Loader decodes.
+type EnvLoader struct {
+	// Format is the extension of the format used when the variable name has
+	// no format suffix. JSON when empty.
+	Format string
+}
+
+func envName(filename string) (string, error) {
+	parts := reEnvFilename.FindStringSubmatch(filename)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("Impossible bad match passed to EnvLoader")
+	}
+	return parts[1], nil
+}
+
+func (el *EnvLoader) GetReader(filename string) (io.Reader, error) {
+	name, err := envName(filename)
+	if err != nil {
+		return nil, err
+	}
+	if prefix := strings.TrimSuffix(name, "*"); prefix != name {
+		return splitEnv(prefix)
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("%w: environment variable %s is not set", ErrNotFound, name)
+	}
+	return strings.NewReader(value), nil
+}
+
+// splitEnv joins and decodes the variables named prefix followed by 0, 1 and
+// so on
+func splitEnv(prefix string) (io.Reader, error) {
+	parts := map[int]string{}
+	last := -1
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		number, err := strconv.ParseUint(strings.TrimPrefix(name, prefix), 10, 16)
+		if !strings.HasPrefix(name, prefix) || err != nil || value == "" {
+			continue
+		}
+		index := int(number)
+		if _, ok := parts[index]; ok {
+			// e.g. APP_CONFIG_1 and APP_CONFIG_01, either could be meant
+			return nil, fmt.Errorf("Environment variables %s*: more than one is numbered %d", prefix, index)
+		}
+		parts[index] = value
+		if index > last {
+			last = index
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%w: environment variable %s0 is not set", ErrNotFound, prefix)
+	}
+	joined := strings.Builder{}
+	for i := 0; i <= last; i++ {
+		part, ok := parts[i]
+		if !ok {
+			return nil, fmt.Errorf("Environment variable %s%d is not set, but %s%d is", prefix, i, prefix, last)
+		}
+		joined.WriteString(strings.TrimSpace(part))
+	}
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(joined.String(), "="))
+	if err != nil {
+		return nil, fmt.Errorf("Environment variables %s*: %w", prefix, err)
+	}
+	return bytes.NewReader(decoded), nil
+}
+
+func (el *EnvLoader) formatHint(filename string, isFormat func(ext string) bool) string {
+	name, err := envName(filename)
+	name = strings.TrimSuffix(name, "_*")
+	if err != nil || strings.Contains(name, ".") {
+		// A real extension is used as is
+		return ""
+	}
+	if idx := strings.LastIndex(name, "_"); idx >= 0 {
+		if suffix := strings.ToLower(name[idx+1:]); isFormat(suffix) {
+			return suffix
+		}
+	}
+	return el.Format
+}
@@ -0,0 +1,86 @@
+package loadfile
+
+import (
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEnvLoader(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("name: split\n"))
+	for _, tc := range []struct {
+		name     string
+		env      map[string]string
+		filename string
+		want     string
+		wantErr  string
+	}{{
+		name:     "format suffix",
+		env:      map[string]string{"TEST_CONFIG_YAML": "name: app\n"},
+		filename: "env://TEST_CONFIG_YAML",
+		want:     "app",
+	}, {
+		name:     "loader format suffix",
+		env:      map[string]string{"TEST_CONFIG_LINES": "name=lines"},
+		filename: "env://TEST_CONFIG_LINES",
+		want:     "lines",
+	}, {
+		name: "split",
+		env: map[string]string{
+			"TEST_SPLIT_YAML_0": encoded[:6],
+			"TEST_SPLIT_YAML_1": encoded[6:],
+		},
+		filename: "env://TEST_SPLIT_YAML_*",
+		want:     "split",
+	}, {
+		name: "gap",
+		env: map[string]string{
+			"TEST_GAP_YAML_0": encoded[:6],
+			"TEST_GAP_YAML_2": encoded[6:],
+		},
+		filename: "env://TEST_GAP_YAML_*",
+		wantErr:  "TEST_GAP_YAML_1 is not set",
+	}, {
+		name: "collision",
+		env: map[string]string{
+			"TEST_COLLIDE_YAML_0":  encoded[:6],
+			"TEST_COLLIDE_YAML_1":  encoded[6:],
+			"TEST_COLLIDE_YAML_01": encoded[6:],
+		},
+		filename: "env://TEST_COLLIDE_YAML_*",
+		wantErr:  "more than one is numbered 1",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			l := NewLoader()
+			if err := l.RegisterFormat("lines", func(r io.Reader, into interface{}) error {
+				content, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				_, value, _ := strings.Cut(string(content), "=")
+				into.(*struct{ Name string }).Name = value
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			into := struct{ Name string }{}
+			err := l.Load(tc.filename, &into)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+}
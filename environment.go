@@ -0,0 +1,179 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var reEnvironmentKey = regexp.MustCompile(`^(.+)@([A-Za-z0-9_-]+(?:,[A-Za-z0-9_-]+)*)$`)
+
+// WithEnvironment selects environment specific values in JSON and YAML files.
+// A key tagged with environments, written key@env or key@env1,env2, applies
+// only when name is one of them, and is dropped otherwise:
+//
+//	port: 8080
+//	port@prod: 443
+//	database:
+//	  host: localhost
+//	database@prod,staging:
+//	  host: db.internal
+//
+// A selected key replaces the untagged key of the same name, or, when both are
+// objects, is merged into it as LoadLayered merges files, so that only the
+// fields which differ need tagging. Environment names are letters, digits, _
+// and -, so a key such as user@example.com, with a '.', is left alone, but
+// admin@localhost is a tag. Tags are selected at every depth, after includes
+// and references are resolved. Untagged keys are used in every environment.
+func WithEnvironment(name string) Option {
+	return func(l *Loader) {
+		l.environment = name
+	}
+}
+
+// selectEnvironment returns content with tagged keys selected for the
+// Loader's environment, in the same format as it was given. YAML is selected
+// on yaml.v3 nodes, and JSON with its numbers as written, so the values kept
+// are as they were, and content without tags is unchanged.
+func (l *Loader) selectEnvironment(ext string, content []byte) ([]byte, error) {
+	switch l.docFormat(ext) {
+	case docJSON:
+		var doc interface{}
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		doc, tagged := selectEnvironment(doc, l.environment)
+		if !tagged {
+			return content, nil
+		}
+		return json.Marshal(doc)
+	case docYAML:
+		root := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(content, root); err != nil {
+			return nil, err
+		}
+		if !selectEnvironmentNode(root, l.environment) {
+			return content, nil
+		}
+		return yamlv3.Marshal(root)
+	}
+	return content, nil
+}
+
+// selectEnvironment selects the tagged keys of a generic document, returning
+// whether it had any
+func selectEnvironment(v interface{}, environment string) (interface{}, bool) {
+	tagged := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		selected := map[string]interface{}{}
+		overrides := map[string]interface{}{}
+		for _, key := range sortedKeys(val) {
+			child, childTagged := selectEnvironment(val[key], environment)
+			tagged = tagged || childTagged
+			match := reEnvironmentKey.FindStringSubmatch(key)
+			if match == nil {
+				selected[key] = child
+				continue
+			}
+			tagged = true
+			if hasEnvironment(match[2], environment) {
+				overrides[key] = child
+			}
+		}
+		for _, key := range sortedKeys(overrides) {
+			base := reEnvironmentKey.FindStringSubmatch(key)[1]
+			override, overrideIsMap := overrides[key].(map[string]interface{})
+			existing, existingIsMap := selected[base].(map[string]interface{})
+			if overrideIsMap && existingIsMap {
+				selected[base] = deepMerge(existing, override)
+				continue
+			}
+			selected[base] = overrides[key]
+		}
+		return selected, tagged
+	case []interface{}:
+		for i, child := range val {
+			selected, childTagged := selectEnvironment(child, environment)
+			val[i], tagged = selected, tagged || childTagged
+		}
+	}
+	return v, tagged
+}
+
+// selectEnvironmentNode selects the tagged keys of a YAML node in place,
+// returning whether it had any. Aliases aren't followed, as the nodes they
+// refer to are selected where they are anchored.
+func selectEnvironmentNode(node *yamlv3.Node, environment string) bool {
+	tagged := false
+	for _, child := range node.Content {
+		tagged = selectEnvironmentNode(child, environment) || tagged
+	}
+	if node.Kind != yamlv3.MappingNode {
+		return tagged
+	}
+
+	kept := make([]*yamlv3.Node, 0, len(node.Content))
+	overrides := map[string]*yamlv3.Node{}
+	keys := []string{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		var match []string
+		if key.Kind == yamlv3.ScalarNode {
+			match = reEnvironmentKey.FindStringSubmatch(key.Value)
+		}
+		if match == nil {
+			kept = append(kept, key, value)
+			continue
+		}
+		tagged = true
+		if hasEnvironment(match[2], environment) {
+			overrides[key.Value] = value
+			keys = append(keys, key.Value)
+		}
+	}
+	if !tagged {
+		return false
+	}
+	node.Content = kept
+	sort.Strings(keys)
+	for _, key := range keys {
+		base := reEnvironmentKey.FindStringSubmatch(key)[1]
+		override := overrides[key]
+		if override.Kind == yamlv3.AliasNode && override.Alias != nil && override.Alias.Kind == yamlv3.MappingNode {
+			override = copyNode(override.Alias)
+			override.Anchor = ""
+		}
+		i := mappingIndex(node, base)
+		if i >= 0 && node.Content[i].Kind == yamlv3.AliasNode && node.Content[i].Alias != nil && override.Kind == yamlv3.MappingNode {
+			// Merge into a copy, leaving the anchored mapping as it is
+			node.Content[i] = copyNode(node.Content[i].Alias)
+			node.Content[i].Anchor = ""
+		}
+		switch {
+		case i >= 0 && node.Content[i].Kind == yamlv3.MappingNode && override.Kind == yamlv3.MappingNode:
+			mergeNodes(node.Content[i], override)
+		case i >= 0:
+			node.Content[i] = override
+		default:
+			node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: base}, override)
+		}
+	}
+	return true
+}
+
+// hasEnvironment returns true if environment is one of a tag's names
+func hasEnvironment(names, environment string) bool {
+	for _, name := range strings.Split(names, ",") {
+		if name == environment {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package loadfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectEnvironment(t *testing.T) {
+	type database struct {
+		Host string `json:"host" yaml:"host"`
+		Port int    `json:"port" yaml:"port"`
+	}
+	type config struct {
+		Port     int      `json:"port" yaml:"port"`
+		Version  string   `json:"version" yaml:"version"`
+		Flag     string   `json:"flag" yaml:"flag"`
+		Big      uint64   `json:"big" yaml:"big"`
+		Database database `json:"database" yaml:"database"`
+	}
+
+	for _, tc := range []struct {
+		name        string
+		file        string
+		environment string
+		content     string
+		want        config
+	}{{
+		name:        "selected and merged",
+		file:        "config.yaml",
+		environment: "prod",
+		content:     "port: 8080\nport@prod: 443\ndatabase:\n  host: localhost\n  port: 5432\ndatabase@prod,staging:\n  host: db.internal\n",
+		want:        config{Port: 443, Database: database{Host: "db.internal", Port: 5432}},
+	}, {
+		name:        "other environment dropped",
+		file:        "config.yaml",
+		environment: "dev",
+		content:     "port: 8080\nport@prod: 443\n",
+		want:        config{Port: 8080},
+	}, {
+		name:        "untouched values as written",
+		file:        "config.yaml",
+		environment: "prod",
+		content:     "version: 1.10\nflag: yes\nport@prod: 443\n",
+		want:        config{Port: 443, Version: "1.10", Flag: "yes"},
+	}, {
+		name:        "merged into an alias",
+		file:        "config.yaml",
+		environment: "prod",
+		content:     "base: &b {host: localhost, port: 5432}\ndatabase: *b\ndatabase@prod: {host: db.internal}\n",
+		want:        config{Database: database{Host: "db.internal", Port: 5432}},
+	}, {
+		name:        "json numbers as written",
+		file:        "config.json",
+		environment: "prod",
+		content:     `{"big": 18446744073709551615, "port@prod": 443}`,
+		want:        config{Port: 443, Big: 18446744073709551615},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := config{}
+			if err := NewLoader(WithEnvironment(tc.environment)).Load(writeTestFile(t, tc.file, tc.content), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
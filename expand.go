@@ -0,0 +1,65 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrUnresolvedVars is returned by a Loader created WithStrictEnvExpansion
+// when a file references variables which are unset or empty and have no
+// default
+var ErrUnresolvedVars = errors.New("Unresolved environment variables")
+
+var reEnvPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// WithEnvExpansion replaces ${VAR} placeholders in the raw text of JSON, YAML
+// and XML files with the value of the environment variable, before decoding.
+// ${VAR:-default} uses default when VAR is unset or empty. Anything else,
+// including a bare $VAR, is left alone. Values are inserted as they are, so a
+// value containing quotes can break the syntax of the file.
+//
+// An unset variable without a default expands to an empty string, unless
+// WithStrictEnvExpansion is used.
+func WithEnvExpansion() Option {
+	return func(l *Loader) {
+		l.envExpansion = true
+	}
+}
+
+// WithStrictEnvExpansion is WithEnvExpansion, but returns ErrUnresolvedVars
+// listing every variable without a value or a default, instead of expanding
+// them to empty strings
+func WithStrictEnvExpansion() Option {
+	return func(l *Loader) {
+		l.envExpansion = true
+		l.strictEnvExpansion = true
+	}
+}
+
+// expandEnv replaces the placeholders in content
+func expandEnv(content []byte, strict bool) ([]byte, error) {
+	unresolved := []string{}
+	seen := map[string]bool{}
+	expanded := reEnvPlaceholder.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+		match := reEnvPlaceholder.FindSubmatch(placeholder)
+		name := string(match[1])
+		if value := os.Getenv(name); value != "" {
+			return []byte(value)
+		}
+		if strings.Contains(string(placeholder), ":-") {
+			return match[2]
+		}
+		if !seen[name] {
+			seen[name] = true
+			unresolved = append(unresolved, name)
+		}
+		return nil
+	})
+	if strict && len(unresolved) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolvedVars, strings.Join(unresolved, ", "))
+	}
+	return expanded, nil
+}
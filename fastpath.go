@@ -0,0 +1,78 @@
+package loadfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// smallFileSize is the largest local file which is read into memory up front,
+// which every built in decoder ends up doing anyway
+const smallFileSize = 1 << 20
+
+// contentReader reads content which is already in memory, so that the steps
+// before decoding can look at it directly rather than buffering or recording
+// it as they would a stream
+type contentReader struct {
+	*bytes.Reader
+	content []byte
+}
+
+func newContentReader(content []byte) *contentReader {
+	return &contentReader{Reader: bytes.NewReader(content), content: content}
+}
+
+// Close does nothing, the content isn't owned by anything which needs closing
+func (cr *contentReader) Close() error {
+	return nil
+}
+
+// inMemory returns the contentReader which reader reads from, when it is one
+// or prepare's wrapping of one
+func inMemory(reader io.Reader) (*contentReader, bool) {
+	if rc, ok := reader.(*readCloser); ok {
+		reader = rc.Reader
+	}
+	cr, ok := reader.(*contentReader)
+	return cr, ok
+}
+
+// readSmallFile reads a small local file, as FileLoader returns, in one go,
+// which is the common case of loading config at startup. Anything else is
+// returned as it is.
+func readSmallFile(reader io.Reader) io.Reader {
+	file, ok := reader.(*os.File)
+	if !ok {
+		return reader
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() > smallFileSize {
+		return reader
+	}
+	// One more byte than the size, as os.ReadFile does, to see EOF in one read
+	// when the file hasn't grown
+	content := make([]byte, 0, info.Size()+1)
+	for {
+		n, err := file.Read(content[len(content):cap(content)])
+		content = content[:len(content)+n]
+		if err == io.EOF {
+			return newContentReader(content)
+		}
+		if err != nil {
+			// Leave the error to the decoder, from what is left of the file
+			return io.MultiReader(bytes.NewReader(content), &errReader{err: err})
+		}
+		if len(content) == cap(content) {
+			content = append(content, 0)[:len(content)]
+		}
+	}
+}
+
+// errReader returns err from every Read
+type errReader struct {
+	err error
+}
+
+func (er *errReader) Read([]byte) (int, error) {
+	return 0, er.err
+}
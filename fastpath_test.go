@@ -0,0 +1,58 @@
+package loadfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// streamedFileLoader opens files as FileLoader does, hiding the *os.File so
+// that Load streams it rather than taking the small file fast path
+type streamedFileLoader struct{}
+
+func (streamedFileLoader) GetReader(filename string) (io.Reader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &readCloser{Reader: struct{ io.Reader }{file}, Closer: file}, nil
+}
+
+// smallJSON returns a JSON object of about size bytes
+func smallJSON(size int) string {
+	fields := []string{}
+	for i := 0; len(strings.Join(fields, ",")) < size-32; i++ {
+		fields = append(fields, fmt.Sprintf(`"key_%03d": "value %03d"`, i, i))
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+func BenchmarkLoadSmallJSON(b *testing.B) {
+	content := smallJSON(1024)
+	filename := writeTestFile(b, "app.json", content)
+	streamed := NewLoader()
+	if err := streamed.Register(regexp.MustCompile(`^/`), streamedFileLoader{}); err != nil {
+		b.Fatal(err)
+	}
+	for _, bc := range []struct {
+		name   string
+		loader *Loader
+	}{
+		{name: "fast path", loader: NewLoader()},
+		{name: "streamed", loader: streamed},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				into := map[string]string{}
+				if err := bc.loader.Load(filename, &into); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
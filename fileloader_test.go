@@ -0,0 +1,250 @@
+package loadfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestFileLoaderSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	outside := writeTestFile(t, "secret.json", `{"name": "secret"}`)
+	baseDir := t.TempDir()
+	link := filepath.Join(baseDir, "config.json")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	regular := filepath.Join(baseDir, "regular.json")
+	if err := os.WriteFile(regular, []byte(`{"name": "regular"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		loader   *FileLoader
+		filename string
+		want     string
+		wantErr  error
+	}{{
+		name:     "following the link out of the directory",
+		loader:   &FileLoader{FollowSymlinks: true},
+		filename: link,
+		want:     "secret",
+	}, {
+		name:     "refusing the link",
+		loader:   &FileLoader{},
+		filename: link,
+		wantErr:  ErrSymlink,
+	}, {
+		name:     "refusing a file URL of the link",
+		loader:   &FileLoader{},
+		filename: "file://" + link,
+		wantErr:  ErrSymlink,
+	}, {
+		name:     "regular file without following",
+		loader:   &FileLoader{},
+		filename: regular,
+		want:     "regular",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &Loader{fallback: tc.loader}
+			got := struct{ Name string }{}
+			err := l.Load(tc.filename, &got)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tc.want {
+				t.Errorf("got %q, want %q", got.Name, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewLoaderFollowsSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	link := filepath.Join(t.TempDir(), "config.json")
+	if err := os.Symlink(writeTestFile(t, "target.json", `{"name": "target"}`), link); err != nil {
+		t.Fatal(err)
+	}
+	got := struct{ Name string }{}
+	if err := NewLoader().Load(link, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "target" {
+		t.Errorf("got %q, want target", got.Name)
+	}
+}
+
+func TestFileLoaderList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.yaml":          "a",
+		"sub/b.yaml":      "b",
+		".hidden/c.yaml":  "c",
+		".d.yaml":         "d",
+		"..data/e.yaml":   "e",
+		"linked/dir.yaml": "f",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "..data", "e.yaml"), filepath.Join(dir, "e.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "linked"), filepath.Join(dir, "sub", "linked")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		loader FileLoader
+		want   []string
+	}{{
+		name:   "following symlinks",
+		loader: FileLoader{FollowSymlinks: true},
+		want:   []string{"a.yaml", "e.yaml", "linked/dir.yaml", "sub/b.yaml"},
+	}, {
+		name:   "skipping symlinks",
+		loader: FileLoader{},
+		want:   []string{"a.yaml", "linked/dir.yaml", "sub/b.yaml"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			listed, err := tc.loader.List(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(listed))
+			for i, filename := range listed {
+				rel, err := filepath.Rel(dir, filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[i] = filepath.ToSlash(rel)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("listed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFileLoaderWriteFile(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		existing os.FileMode // zero for no existing file
+		wantPerm os.FileMode
+	}{
+		{name: "new file", wantPerm: 0o644},
+		{name: "existing file", existing: 0o600, wantPerm: 0o600},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "app.json")
+			if tc.existing != 0 {
+				if err := os.WriteFile(filename, []byte(`{"name": "old"}`), tc.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := (FileLoader{}).WriteFile(filename, []byte(`{"name": "new"}`)); err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != `{"name": "new"}` {
+				t.Errorf("got %q", content)
+			}
+			if runtime.GOOS == "windows" {
+				return
+			}
+			info, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tc.wantPerm {
+				t.Errorf("mode is %v, want %v", info.Mode().Perm(), tc.wantPerm)
+			}
+		})
+	}
+}
+
+func TestFileLoaderWriteFileInterrupted(t *testing.T) {
+	const original = `{"name": "original"}`
+	filename := writeTestFile(t, "app.json", original)
+	interrupted := errors.New("interrupted")
+	defer func(rename func(string, string) error) { renameFile = rename }(renameFile)
+	renameFile = func(from, to string) error {
+		// The new content is complete in the temporary file, but the
+		// original hasn't been touched
+		written, err := os.ReadFile(from)
+		if err != nil || string(written) != `{"name": "new"}` {
+			t.Errorf("temporary file has %q, %v", written, err)
+		}
+		if current, _ := os.ReadFile(to); string(current) != original {
+			t.Errorf("before the rename the file has %q", current)
+		}
+		return interrupted
+	}
+
+	if err := (FileLoader{}).WriteFile(filename, []byte(`{"name": "new"}`)); !errors.Is(err, interrupted) {
+		t.Fatalf("got %v, want the rename error", err)
+	}
+	got := struct{ Name string }{}
+	if err := NewLoader().Load(filename, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "original" {
+		t.Errorf("got %q, want the original", got.Name)
+	}
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("left %d files, want only the original", len(entries))
+	}
+}
+
+func TestFileLoaderWriteFileSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	target := writeTestFile(t, "target.json", `{"name": "old"}`)
+	link := filepath.Join(t.TempDir(), "app.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := (FileLoader{}).WriteFile(link, []byte(`{}`)); !errors.Is(err, ErrSymlink) {
+		t.Fatalf("without FollowSymlinks got %v, want ErrSymlink", err)
+	}
+	if err := (FileLoader{FollowSymlinks: true}).WriteFile(link, []byte(`{"name": "new"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("the link was replaced: %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != `{"name": "new"}` {
+		t.Errorf("target has %q", content)
+	}
+}
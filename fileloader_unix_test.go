@@ -0,0 +1,52 @@
+//go:build unix
+
+package loadfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFileLoaderNonRegular(t *testing.T) {
+	fifo := filepath.Join(t.TempDir(), "config.json")
+	if err := syscall.Mkfifo(fifo, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		filename string
+		wantErr  error
+	}{{
+		name:     "fifo",
+		filename: fifo,
+		wantErr:  ErrUnsupportedFileType,
+	}, {
+		name:     "directory",
+		filename: t.TempDir(),
+		wantErr:  ErrUnsupportedFileType,
+	}, {
+		name:     "missing",
+		filename: filepath.Join(t.TempDir(), "missing.json"),
+		wantErr:  os.ErrNotExist,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() {
+				_, err := FileLoader{}.GetReader(tc.filename)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("GetReader blocked")
+			}
+		})
+	}
+}
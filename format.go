@@ -0,0 +1,93 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Format names a decoding format by its canonical extension, e.g. "yaml" for
+// both .yaml and .yml. Formats added with RegisterFormat are named by the
+// extension they were registered with.
+type Format string
+
+// The built in formats
+const (
+	FormatJSON Format = "json"
+	FormatXML  Format = "xml"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// ErrUnknownFormat is returned by ParseFormat for a name which isn't a
+// registered format
+var ErrUnknownFormat = errors.New("Unknown format")
+
+func (f Format) String() string {
+	return string(f)
+}
+
+// ParseFormat returns the Format for a format name or extension, with or
+// without the dot, e.g. "JSON", ".yml" or "toml", or for a media type, e.g.
+// "application/json; charset=utf-8", "text/yaml" or
+// "application/vnd.api+json".
+func ParseFormat(s string) (Format, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if strings.Contains(name, "/") {
+		return formatForMediaType(s)
+	}
+	name = strings.TrimPrefix(name, ".")
+	if name == "yml" {
+		return FormatYAML, nil
+	}
+	if isFormat(name) {
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+// formatForMediaType finds the format from the subtype, using only what
+// follows any + or . structured syntax suffix, so application/ld+json is JSON
+// and text/x-yaml YAML
+func formatForMediaType(s string) (Format, error) {
+	mediaType, _, err := mime.ParseMediaType(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+	}
+	subtype := mediaType[strings.Index(mediaType, "/")+1:]
+	if idx := strings.LastIndexAny(subtype, "+."); idx >= 0 {
+		subtype = subtype[idx+1:]
+	}
+	if format, err := ParseFormat(strings.TrimPrefix(subtype, "x-")); err == nil {
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+// AliasExtension makes this Loader decode files with the extension ext
+// (without the dot, case insensitive) as format, e.g. .cfg as YAML, without
+// registering a decoder for it. format can be a built in format or one
+// registered with RegisterFormat, globally or on this Loader, and is looked up
+// as each file is loaded. Like RegisterFormat it is not safe to call
+// concurrently with loads.
+func (l *Loader) AliasExtension(ext string, format Format) error {
+	if err := l.checkFrozen(); err != nil {
+		return err
+	}
+	if l.aliases == nil {
+		l.aliases = map[string]string{}
+	}
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	l.aliases[ext] = strings.ToLower(string(format))
+	return nil
+}
+
+// aliasedExtension returns the extension of the format ext is an alias of,
+// or ext itself
+func (l *Loader) aliasedExtension(ext string) string {
+	if format, ok := l.aliases[ext]; ok {
+		return format
+	}
+	return ext
+}
@@ -0,0 +1,79 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	toml "github.com/pelletier/go-toml/v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ErrFormatMismatch is returned, with WithFormatConsistencyCheck, for a file
+// whose content is in a different format than its extension says
+var ErrFormatMismatch = errors.New("File content doesn't match the format of its extension")
+
+// WithFormatConsistencyCheck detects the format of each JSON, YAML, XML or
+// TOML file from its content as well as from its extension, or media type,
+// and returns ErrFormatMismatch, naming both, when they disagree, e.g. YAML
+// content in a .json file, rather than decoding it as the extension says.
+// Content which is valid JSON agrees with a YAML extension, as YAML is a
+// superset of JSON. Content which isn't clearly any one format isn't reported,
+// nor is a JSON file starting with { or [, which decodes with its syntax error.
+func WithFormatConsistencyCheck() Option {
+	return func(l *Loader) {
+		l.formatCheck = true
+	}
+}
+
+// checkFormat compares the format of ext with the format of content
+func (l *Loader) checkFormat(ext string, content []byte) error {
+	if !l.isFormat(ext) {
+		return nil
+	}
+	fromExt := l.docFormat(ext)
+	if fromExt == "" {
+		return nil
+	}
+	if fromExt == docJSON && startsLikeJSON(content) {
+		return nil
+	}
+	fromContent := sniffFormat(content)
+	if fromContent == "" || fromContent == fromExt || (fromExt == docYAML && fromContent == docJSON) {
+		return nil
+	}
+	return fmt.Errorf("%w: the extension says %s, the content is %s", ErrFormatMismatch, fromExt, fromContent)
+}
+
+// startsLikeJSON returns true if content starts as a JSON object or array
+func startsLikeJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	return bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))
+}
+
+// sniffFormat returns the format which content is valid in, preferring JSON,
+// then XML and TOML, or "" if it isn't a JSON, XML or TOML document nor a YAML
+// mapping or sequence
+func sniffFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if json.Valid(trimmed) {
+		return docJSON
+	}
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return docXML
+	}
+	// Checked before YAML, which would parse key = value as a string
+	var table map[string]interface{}
+	if toml.Unmarshal(trimmed, &table) == nil && len(table) > 0 {
+		return docTOML
+	}
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(trimmed, &doc) == nil && len(doc.Content) > 0 {
+		switch doc.Content[0].Kind {
+		case yamlv3.MappingNode, yamlv3.SequenceNode:
+			return docYAML
+		}
+	}
+	return ""
+}
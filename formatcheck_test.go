@@ -0,0 +1,54 @@
+package loadfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatConsistencyCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filename string
+		content  string
+		mismatch bool
+		wantErr  string
+	}{{
+		name:     "json",
+		filename: "config.json",
+		content:  `{"a": 1}`,
+	}, {
+		name:     "json in yaml",
+		filename: "config.yaml",
+		content:  `{"a": 1}`,
+	}, {
+		name:     "yaml in json",
+		filename: "config.json",
+		content:  "a: 1\n",
+		mismatch: true,
+	}, {
+		name:     "json with a trailing comma",
+		filename: "config.json",
+		content:  `{"a": 1,}`,
+		wantErr:  "invalid character",
+	}, {
+		name:     "xml in yaml",
+		filename: "config.yaml",
+		content:  "<config><a>1</a></config>",
+		mismatch: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := map[string]interface{}{}
+			err := NewLoader(WithFormatConsistencyCheck()).Load(writeTestFile(t, tc.filename, tc.content), &got)
+			if errors.Is(err, ErrFormatMismatch) != tc.mismatch {
+				t.Fatalf("got error %v, want mismatch %v", err, tc.mismatch)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+			if !tc.mismatch && tc.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// DecoderFunc decodes everything read from r into into
+type DecoderFunc func(r io.Reader, into interface{}) error
+
+var formatsLock sync.RWMutex
+var formats = map[string]DecoderFunc{
+	"json": decodeJSON,
+	"xml":  decodeXML,
+	"yml":  decodeYAML,
+	"yaml": decodeYAML,
+	"toml": decodeTOML,
+	"env":  decodeDotenv,
+}
+
+// RegisterFormat sets the decoder used for files with the given extension
+// (without the dot, case insensitive), replacing any existing decoder for that
+// extension, for every Loader. It is intended to be called from an init
+// function, see the proto subpackage for an example. Loader.RegisterFormat
+// registers a format for one Loader only.
+func RegisterFormat(ext string, d DecoderFunc) {
+	formatsLock.Lock()
+	defer formatsLock.Unlock()
+	formats[strings.ToLower(ext)] = d
+}
+
+// RegisterFormat sets the decoder used by this Loader for files with the given
+// extension, taking precedence over formats registered globally. Like
+// Register it is not safe to call concurrently with loads.
+func (l *Loader) RegisterFormat(ext string, d DecoderFunc) error {
+	if err := l.checkFrozen(); err != nil {
+		return err
+	}
+	if l.formats == nil {
+		l.formats = map[string]DecoderFunc{}
+	}
+	l.formats[strings.ToLower(ext)] = d
+	return nil
+}
+
+// isFormat returns true if there is a global decoder for the extension
+func isFormat(ext string) bool {
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+	_, ok := formats[ext]
+	return ok
+}
+
+// isFormat returns true if the Loader or the global registry has a decoder for
+// the extension
+func (l *Loader) isFormat(ext string) bool {
+	ext = l.aliasedExtension(ext)
+	if _, ok := l.formats[ext]; ok {
+		return true
+	}
+	_, ok := l.globalFormat(ext)
+	return ok
+}
+
+// globalFormat returns the global decoder for the extension, as it was when
+// the Loader was frozen if it is
+func (l *Loader) globalFormat(ext string) (DecoderFunc, bool) {
+	if r := l.frozenRegistries(); r != nil {
+		d, ok := r.formats[ext]
+		return d, ok
+	}
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+	d, ok := formats[ext]
+	return d, ok
+}
+
+// getFormat returns the decoder for the extension, the Loader's own first,
+// falling back to JSON
+func (l *Loader) getFormat(ext string) DecoderFunc {
+	ext = l.aliasedExtension(ext)
+	if d, ok := l.formats[ext]; ok {
+		return d
+	}
+	if d, ok := l.globalFormat(ext); ok {
+		return d
+	}
+	return decodeJSON
+}
+
+func decodeJSON(r io.Reader, into interface{}) error {
+	return json.NewDecoder(r).Decode(into)
+}
+
+func decodeXML(r io.Reader, into interface{}) error {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = xmlCharsetReader
+	return decodeKeyedXML(dec, into)
+}
+
+// yamlBuffers reuses the buffers which YAML is read into before unmarshalling,
+// as yaml.v2 can't decode from a stream
+var yamlBuffers = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+// maxPooledBuffer stops one unusually large file from keeping its buffer alive
+const maxPooledBuffer = 1 << 20
+
+func decodeYAML(r io.Reader, into interface{}) error {
+	buf := yamlBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			yamlBuffers.Put(buf)
+		}
+	}()
+	if _, err := buf.ReadFrom(r); err != nil {
+		return err
+	}
+	return yaml.Unmarshal(buf.Bytes(), into)
+}
+
+// WithYAMLv3 decodes YAML with gopkg.in/yaml.v3 rather than yaml.v2.
+//
+// Scalars decoded into typed struct fields behave the same in both, a string
+// field receives the text as written: 1.10 stays "1.10", 01234 keeps its
+// leading zero and on, off, yes and no stay as they are. The differences are
+// for untyped targets (interface{} and map values) and YAML 1.1 quirks:
+//
+//   - Only true and false are booleans for untyped values, v2 also converts
+//     on, off, yes, no, y and n. Typed bool fields accept either.
+//   - Untyped mappings decode as map[string]interface{} rather than
+//     map[interface{}]interface{}.
+//   - Duplicate mapping keys are an error rather than the last one winning.
+//
+// A document of only comments decodes nothing, as with yaml.v2, and
+// WithErrorOnEmpty returns ErrEmpty for it, as for an empty file.
+func WithYAMLv3() Option {
+	return func(l *Loader) {
+		l.yamlV3 = true
+	}
+}
+
+// decodeYAMLv3 decodes with yaml.v3, which returns io.EOF for a document of
+// only comments where yaml.v2 decodes nothing
+func (l *Loader) decodeYAMLv3(r io.Reader, into interface{}) error {
+	err := yamlv3.NewDecoder(r).Decode(into)
+	if err == io.EOF {
+		if l.errorOnEmpty {
+			return ErrEmpty
+		}
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package loadfile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// decodeYAMLUnpooled is decodeYAML reading into a new buffer each time
+func decodeYAMLUnpooled(r io.Reader, into interface{}) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(content, into)
+}
+
+func BenchmarkDecodeYAML(b *testing.B) {
+	lines := []string{}
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("key_%03d: value %03d", i, i))
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	for _, bc := range []struct {
+		name   string
+		decode func(r io.Reader, into interface{}) error
+	}{
+		{name: "pooled", decode: decodeYAML},
+		{name: "unpooled", decode: decodeYAMLUnpooled},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				into := map[string]string{}
+				if err := bc.decode(strings.NewReader(content), &into); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
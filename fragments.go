@@ -0,0 +1,92 @@
+package loadfile
+
+import (
+	"io"
+	"strings"
+)
+
+// FragmentDecoderFunc decodes r into into, given the #fragment of the
+// filename, or "" when there isn't one
+type FragmentDecoderFunc func(r io.Reader, fragment string, into interface{}) error
+
+var fragmentFormats = map[string]FragmentDecoderFunc{}
+
+// RegisterFragmentFormat is RegisterFormat for formats which select part of a
+// file with a #fragment, e.g. a worksheet with data.xlsx#Sheet1, see the xlsx
+// subpackage. The fragment is removed before the file is fetched.
+func RegisterFragmentFormat(ext string, d FragmentDecoderFunc) {
+	RegisterFormat(ext, func(r io.Reader, into interface{}) error {
+		return d(r, "", into)
+	})
+	formatsLock.Lock()
+	defer formatsLock.Unlock()
+	fragmentFormats[strings.ToLower(ext)] = d
+}
+
+// RegisterFragmentFormat is RegisterFormat for a format which selects part of
+// a file with a #fragment, for this Loader only, e.g. for a format which needs
+// the Loader's own settings. The fragment is only split off filenames with an
+// extension also registered globally, so this replaces the decoder of a
+// global fragment format.
+func (l *Loader) RegisterFragmentFormat(ext string, d FragmentDecoderFunc) error {
+	err := l.RegisterFormat(ext, func(r io.Reader, into interface{}) error {
+		return d(r, "", into)
+	})
+	if err != nil {
+		return err
+	}
+	if l.fragmentFormats == nil {
+		l.fragmentFormats = map[string]FragmentDecoderFunc{}
+	}
+	l.fragmentFormats[strings.ToLower(ext)] = d
+	return nil
+}
+
+// fragmentFormat returns the fragment decoder for ext, the Loader's own
+// first, unless a format the Loader registered replaces a global one
+func (l *Loader) fragmentFormat(ext string) FragmentDecoderFunc {
+	if d, ok := l.fragmentFormats[ext]; ok {
+		return d
+	}
+	if l.formats[ext] != nil {
+		return nil
+	}
+	return l.globalFragmentFormat(ext)
+}
+
+// globalFragmentFormat returns the global fragment decoder for ext, as it was
+// when the Loader was frozen if it is
+func (l *Loader) globalFragmentFormat(ext string) FragmentDecoderFunc {
+	if r := l.frozenRegistries(); r != nil {
+		return r.fragmentFormats[ext]
+	}
+	return getFragmentFormat(ext)
+}
+
+func getFragmentFormat(ext string) FragmentDecoderFunc {
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+	return fragmentFormats[ext]
+}
+
+// splitFragment splits a #fragment off filename when the part before it has
+// the extension of a global fragment format, otherwise '#' is part of the name
+func splitFragment(filename string) (string, string) {
+	return splitFragmentWith(filename, getFragmentFormat)
+}
+
+// splitFragment is splitFragment with the fragment formats the Loader uses
+func (l *Loader) splitFragment(filename string) (string, string) {
+	return splitFragmentWith(filename, l.globalFragmentFormat)
+}
+
+func splitFragmentWith(filename string, fragmentFormat func(ext string) FragmentDecoderFunc) (string, string) {
+	idx := strings.LastIndex(filename, "#")
+	if idx < 0 {
+		return filename, ""
+	}
+	if fragmentFormat(fileExtension(filename[:idx])) == nil {
+		return filename, ""
+	}
+	return filename[:idx], filename[idx+1:]
+}
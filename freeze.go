@@ -0,0 +1,83 @@
+package loadfile
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+)
+
+// ErrFrozen is returned by the methods which configure a Loader once it has
+// been frozen
+var ErrFrozen = errors.New("Loader is frozen")
+
+// Freeze stops the Loader being reconfigured: Register, RegisterScheme,
+// RegisterNamed, RegisterFormat, RegisterFragmentFormat, AliasExtension,
+// AllowSchemes and AllowLocal return ErrFrozen and change nothing from then
+// on. The Loader also keeps a copy of the global registries of the package
+// level RegisterFormat, RegisterFragmentFormat, RegisterTypeDecoder and
+// RegisterUnion as they are, so later calls to those don't change it either.
+// Loading is unaffected. A frozen Loader can't be unfrozen.
+func (l *Loader) Freeze() {
+	if l.checkFrozen() == nil {
+		l.registries.Store(copyRegistries())
+	}
+	atomic.StoreInt32(&l.frozen, 1)
+}
+
+// Freeze freezes DefaultLoader, for an application to call once its own setup
+// is done so that a library can't later repoint a scheme of the Loader every
+// package level function uses, or a format, type decoder or union it
+// decodes with.
+func Freeze() {
+	DefaultLoader.Freeze()
+}
+
+// registries is a copy of the global registries, which a frozen Loader uses
+// in place of them
+type registries struct {
+	formats         map[string]DecoderFunc
+	fragmentFormats map[string]FragmentDecoderFunc
+	typeDecoders    map[reflect.Type]func([]byte, interface{}) error
+	unions          []union
+}
+
+func copyRegistries() *registries {
+	r := &registries{
+		formats:         map[string]DecoderFunc{},
+		fragmentFormats: map[string]FragmentDecoderFunc{},
+		typeDecoders:    map[reflect.Type]func([]byte, interface{}) error{},
+	}
+	formatsLock.RLock()
+	for ext, d := range formats {
+		r.formats[ext] = d
+	}
+	for ext, d := range fragmentFormats {
+		r.fragmentFormats[ext] = d
+	}
+	formatsLock.RUnlock()
+
+	typeDecodersLock.RLock()
+	for t, fn := range typeDecoders {
+		r.typeDecoders[t] = fn
+	}
+	typeDecodersLock.RUnlock()
+
+	unionsLock.RLock()
+	r.unions = append([]union{}, unions...)
+	unionsLock.RUnlock()
+	return r
+}
+
+// frozenRegistries returns the registries copied when the Loader was frozen,
+// or nil when it isn't
+func (l *Loader) frozenRegistries() *registries {
+	r, _ := l.registries.Load().(*registries)
+	return r
+}
+
+func (l *Loader) checkFrozen() error {
+	if atomic.LoadInt32(&l.frozen) != 0 {
+		return ErrFrozen
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package loadfile
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type testFrozenPlugin interface{ frozenPlugin() }
+
+type testFrozenHTTP struct{ Type, URL string }
+
+func (testFrozenHTTP) frozenPlugin() {}
+
+type testFrozenLevel string
+
+func TestFreeze(t *testing.T) {
+	l := NewLoader()
+	l.Freeze()
+	for _, tc := range []struct {
+		name     string
+		register func() error
+	}{{
+		name:     "Register",
+		register: func() error { return l.Register(regexp.MustCompile(`^x:\/\/`), FileLoader{}) },
+	}, {
+		name: "RegisterFormat",
+		register: func() error {
+			return l.RegisterFormat("frozen", func(r io.Reader, into interface{}) error { return nil })
+		},
+	}, {
+		name: "RegisterNamed",
+		register: func() error {
+			return l.RegisterNamed("app.yaml", func() (io.Reader, error) { return strings.NewReader("{}"), nil })
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.register(); !errors.Is(err, ErrFrozen) {
+				t.Errorf("got %v, want ErrFrozen", err)
+			}
+		})
+	}
+}
+
+func TestFreezeGlobalRegistries(t *testing.T) {
+	frozen := NewLoader()
+	frozen.Freeze()
+
+	RegisterFormat("frozenfmt", func(r io.Reader, into interface{}) error { return nil })
+	RegisterTypeDecoder(reflect.TypeOf(testFrozenLevel("")), func(content []byte, into interface{}) error {
+		*into.(*testFrozenLevel) = "decoded"
+		return nil
+	})
+	RegisterUnion("type", map[string]reflect.Type{"http": reflect.TypeOf(testFrozenHTTP{})})
+
+	if frozen.isFormat("frozenfmt") {
+		t.Error("frozen Loader has a format registered after it was frozen")
+	}
+	if !NewLoader().isFormat("frozenfmt") {
+		t.Error("unfrozen Loader doesn't have the format")
+	}
+
+	if _, ok := frozen.unionFor(reflect.TypeOf((*testFrozenPlugin)(nil)).Elem()); ok {
+		t.Error("frozen Loader has a union registered after it was frozen")
+	}
+
+	filename := writeTestFile(t, "app.yaml", "level: info\n")
+	into := struct{ Level testFrozenLevel }{}
+	if err := frozen.Load(filename, &into); err != nil {
+		t.Fatal(err)
+	}
+	if into.Level != "info" {
+		t.Errorf("frozen Loader decoded %q with a type decoder registered after it was frozen", into.Level)
+	}
+
+	filename = writeTestFile(t, "app.yaml", "level: info\nplugins:\n- type: http\n  url: x\n")
+	unfrozen := struct {
+		Level   testFrozenLevel
+		Plugins []testFrozenPlugin
+	}{}
+	if err := NewLoader().Load(filename, &unfrozen); err != nil {
+		t.Fatal(err)
+	}
+	if unfrozen.Level != "decoded" || len(unfrozen.Plugins) != 1 {
+		t.Errorf("unfrozen Loader decoded %+v", unfrozen)
+	}
+}
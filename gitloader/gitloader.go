@@ -0,0 +1,158 @@
+// Package gitloader adds a loadfile.TypeLoader which reads a file from a Git
+// repository at a given ref, using go-git. Register it with a Loader:
+//
+//	l := loadfile.NewLoader()
+//	l.Register(gitloader.Pattern, &gitloader.GitLoader{})
+//	l.Load("git+https://github.com/org/repo.git@v1.2.3/configs/app.yaml", &cfg)
+//
+// It is kept out of the core package because of the size of the go-git
+// dependency.
+package gitloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/daemonl/loadfile"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Pattern matches git:// and git+<transport>:// filenames, e.g. git+https://
+// or git+ssh://
+var Pattern = regexp.MustCompile(`^git(\+[a-z]+)?:\/\/`)
+
+// GitLoader fetches a single file from a Git repository. Filenames have the
+// form
+//
+//	git+https://host/org/repo.git@<ref>/<path in repo>
+//
+// where ref is a tag, branch or commit hash, and cannot itself contain a '/'.
+// The git+ prefix is removed to give the clone URL, so git+ssh://git@host/...
+// clones over SSH, and a plain git:// URL uses the git protocol.
+//
+// Tags and branches are fetched as a shallow, single branch clone into memory.
+// A commit hash requires the full history to be fetched.
+type GitLoader struct {
+	// Auth is used for every clone, e.g. &http.BasicAuth{Username: "x",
+	// Password: token} from go-git's plumbing/transport/http, or an SSH key
+	// from plumbing/transport/ssh. Anonymous when nil.
+	Auth transport.AuthMethod
+}
+
+// parseFilename splits a filename into the clone URL, ref and path
+func parseFilename(filename string) (repoURL, ref, path string, err error) {
+	rest := strings.TrimPrefix(filename, "git+")
+	schemeEnd := strings.Index(rest, "://")
+	if schemeEnd < 0 {
+		return "", "", "", fmt.Errorf("Invalid git filename %s", loadfile.RedactURL(filename))
+	}
+	afterScheme := rest[schemeEnd+3:]
+	// Any user@ belongs to the host, the ref's @ comes after the first /
+	hostEnd := strings.Index(afterScheme, "/")
+	if hostEnd < 0 {
+		return "", "", "", fmt.Errorf("Invalid git filename %s", loadfile.RedactURL(filename))
+	}
+	at := strings.Index(afterScheme[hostEnd:], "@")
+	if at < 0 {
+		return "", "", "", fmt.Errorf("Git filename %s has no @ref", loadfile.RedactURL(filename))
+	}
+	at += hostEnd
+	refAndPath := afterScheme[at+1:]
+	slash := strings.Index(refAndPath, "/")
+	if slash <= 0 || slash == len(refAndPath)-1 {
+		return "", "", "", fmt.Errorf("Git filename %s has no path after the ref", loadfile.RedactURL(filename))
+	}
+	return rest[:schemeEnd+3] + afterScheme[:at], refAndPath[:slash], refAndPath[slash+1:], nil
+}
+
+func (gl *GitLoader) clone(repoURL, ref string) (*git.Repository, *plumbing.Hash, error) {
+	var lastErr error
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewTagReferenceName(ref),
+		plumbing.NewBranchReferenceName(ref),
+	} {
+		repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+			URL:           repoURL,
+			Auth:          gl.Auth,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         1,
+			NoCheckout:    true,
+			Tags:          git.NoTags,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		head, err := repo.Head()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash := head.Hash()
+		return repo, &hash, nil
+	}
+
+	if !plumbing.IsHash(ref) {
+		return nil, nil, &cloneError{url: repoURL, err: lastErr}
+	}
+	repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL:        repoURL,
+		Auth:       gl.Auth,
+		NoCheckout: true,
+	})
+	if err != nil {
+		return nil, nil, &cloneError{url: repoURL, err: err}
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, hash, nil
+}
+
+// cloneError names the repository cloned without any credentials in its URL,
+// removing them from go-git's error too, should it quote the URL
+type cloneError struct {
+	url string
+	err error
+}
+
+func (e *cloneError) Error() string {
+	redacted := loadfile.RedactURL(e.url)
+	return fmt.Sprintf("Cloning %s: %s", redacted, strings.ReplaceAll(e.err.Error(), e.url, redacted))
+}
+
+func (e *cloneError) Unwrap() error {
+	return e.err
+}
+
+func (gl *GitLoader) GetReader(filename string) (io.Reader, error) {
+	repoURL, ref, path, err := parseFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, hash, err := gl.clone(repoURL, ref)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(path)
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, fmt.Errorf("%w: %s at %s", loadfile.ErrNotFound, path, ref)
+		}
+		return nil, err
+	}
+	return file.Reader()
+}
@@ -0,0 +1,99 @@
+// Package hcl adds Terraform variable files to loadfile. It is imported for its
+// side effects:
+//
+//	import _ "github.com/daemonl/loadfile/hcl"
+//
+// Files ending in .tfvars are parsed as HCL, which must hold only top level
+// assignments, as Terraform requires of variable files. Each value is
+// evaluated without variables or functions, so strings, numbers, bools,
+// lists and maps, and expressions of them like "${1 + 1}", are allowed but
+// references aren't. The assignments then decode into into as a JSON object
+// would, so json tags and map targets work as they do for .json files.
+//
+// This lives outside of the core package to keep the hashicorp/hcl dependency
+// out of builds which don't need it.
+package hcl
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/daemonl/loadfile"
+	hclv2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+func init() {
+	loadfile.RegisterFormat("tfvars", decodeTFVars)
+}
+
+func decodeTFVars(r io.Reader, into interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	asJSON, err := tfvarsToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(asJSON, into)
+}
+
+// tfvarsToJSON evaluates the top level assignments in data into a JSON object
+func tfvarsToJSON(data []byte) ([]byte, error) {
+	file, diags := hclsyntax.ParseConfig(data, "", hclv2.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diagnosticError{diags}
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diagnosticError{diags}
+	}
+	values := map[string]cty.Value{}
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diagnosticError{diags}
+		}
+		values[name] = value
+	}
+	object := cty.ObjectVal(values)
+	return ctyjson.Marshal(object, object.Type())
+}
+
+// diagnosticError reports the first error of diags, with its position for
+// loadfile.DecodeError
+type diagnosticError struct {
+	diags hclv2.Diagnostics
+}
+
+func (e diagnosticError) first() *hclv2.Diagnostic {
+	for _, diag := range e.diags {
+		if diag.Severity == hclv2.DiagError {
+			return diag
+		}
+	}
+	return e.diags[0]
+}
+
+func (e diagnosticError) Error() string {
+	diag := e.first()
+	if diag.Detail == "" {
+		return diag.Summary
+	}
+	return diag.Summary + "; " + diag.Detail
+}
+
+func (e diagnosticError) Position() (int, int) {
+	if diag := e.first(); diag.Subject != nil {
+		return diag.Subject.Start.Line, diag.Subject.Start.Column
+	}
+	return 0, 0
+}
+
+func (e diagnosticError) Unwrap() error {
+	return e.diags
+}
@@ -0,0 +1,24 @@
+package loadfile
+
+import "fmt"
+
+// AfterLoader is implemented by targets which derive fields from what was
+// decoded, e.g. parsing a DSN into its parts. AfterLoad is called after each
+// successful decode into the target, so once per file with LoadLayered, but
+// not for an empty file, which isn't decoded. Its error is returned prefixed
+// with the filename. Only the target itself is checked, not nested values.
+type AfterLoader interface {
+	AfterLoad() error
+}
+
+// afterLoad calls into's AfterLoad, if it has one
+func afterLoad(filename string, into interface{}) error {
+	hook, ok := into.(AfterLoader)
+	if !ok {
+		return nil
+	}
+	if err := hook.AfterLoad(); err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	return nil
+}
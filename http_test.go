@@ -0,0 +1,236 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPLoaderBasicAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"name": %q}`, username)
+	}))
+	defer srv.Close()
+	withUser := func(userinfo string) string {
+		return strings.Replace(srv.URL, "://", "://"+userinfo+"@", 1) + "/app.json"
+	}
+
+	for _, tc := range []struct {
+		name       string
+		loader     *HTTPLoader
+		filename   string
+		want       string
+		wantStatus int
+	}{{
+		name:     "loader credentials",
+		loader:   &HTTPLoader{Username: "loader", Password: "secret"},
+		filename: srv.URL + "/app.json",
+		want:     "loader",
+	}, {
+		name:     "URL credentials",
+		loader:   &HTTPLoader{},
+		filename: withUser("url:secret"),
+		want:     "url",
+	}, {
+		name:     "URL credentials take precedence",
+		loader:   &HTTPLoader{Username: "loader", Password: "wrong"},
+		filename: withUser("url:secret"),
+		want:     "url",
+	}, {
+		name: "credentials replace an Authorization header",
+		loader: &HTTPLoader{
+			Username: "loader",
+			Password: "secret",
+			Headers:  http.Header{"Authorization": {"Bearer token"}},
+		},
+		filename: srv.URL + "/app.json",
+		want:     "loader",
+	}, {
+		name:       "no credentials",
+		loader:     &HTTPLoader{},
+		filename:   srv.URL + "/app.json",
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		name:       "wrong URL credentials",
+		loader:     &HTTPLoader{},
+		filename:   withUser("url:hunter2"),
+		wantStatus: http.StatusUnauthorized,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader()
+			if err := l.Register(reHTTPFilename, tc.loader); err != nil {
+				t.Fatal(err)
+			}
+			into := struct{ Name string }{}
+			err := l.Load(tc.filename, &into)
+			if tc.wantStatus != 0 {
+				var httpErr *HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tc.wantStatus {
+					t.Fatalf("got %v, want status %d", err, tc.wantStatus)
+				}
+				if strings.Contains(err.Error(), "hunter2") || strings.Contains(err.Error(), "url:") {
+					t.Errorf("error %q includes the credentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPLoaderRedirects(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "plain"}`)
+	}))
+	defer plain.Close()
+	var secure *httptest.Server
+	secure = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/downgrade.json":
+			http.Redirect(w, r, plain.URL+"/app.json", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/chain/"):
+			var n int
+			fmt.Sscanf(r.URL.Path, "/chain/%d.json", &n)
+			if n > 0 {
+				http.Redirect(w, r, fmt.Sprintf("/chain/%d.json", n-1), http.StatusFound)
+				return
+			}
+			fmt.Fprint(w, `{"name": "secure"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer secure.Close()
+	upgrade := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, secure.URL+"/chain/0.json", http.StatusFound)
+	}))
+	defer upgrade.Close()
+
+	for _, tc := range []struct {
+		name     string
+		filename string
+		want     string
+		wantErr  error
+	}{{
+		name:     "redirect chain",
+		filename: secure.URL + "/chain/3.json",
+		want:     "secure",
+	}, {
+		name:     "redirect limit",
+		filename: secure.URL + fmt.Sprintf("/chain/%d.json", maxRedirects),
+		wantErr:  errors.New("Stopped after 10 redirects"),
+	}, {
+		name:     "downgrade",
+		filename: secure.URL + "/downgrade.json",
+		wantErr:  ErrRedirectDowngrade,
+	}, {
+		name:     "upgrade",
+		filename: upgrade.URL + "/app.json",
+		want:     "secure",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader()
+			if err := l.Register(reHTTPFilename, &HTTPLoader{Client: secure.Client()}); err != nil {
+				t.Fatal(err)
+			}
+			into := struct{ Name string }{}
+			err := l.Load(tc.filename, &into)
+			if tc.wantErr != nil {
+				if err == nil || !errors.Is(err, tc.wantErr) && !strings.Contains(err.Error(), tc.wantErr.Error()) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				if into.Name != "" {
+					t.Errorf("Name is %q, want nothing decoded", into.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPLoaderRedirectHeaders(t *testing.T) {
+	var gotKey string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-Api-Key")
+		fmt.Fprint(w, `{"name": "other"}`)
+	}))
+	defer other.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/elsewhere.json":
+			http.Redirect(w, r, other.URL+"/app.json", http.StatusFound)
+		case "/moved.json":
+			http.Redirect(w, r, "/app.json", http.StatusFound)
+		default:
+			gotKey = r.Header.Get("X-Api-Key")
+			fmt.Fprint(w, `{"name": "origin"}`)
+		}
+	}))
+	defer origin.Close()
+
+	for _, tc := range []struct {
+		name     string
+		filename string
+		wantKey  string
+	}{{
+		name:     "same host",
+		filename: origin.URL + "/moved.json",
+		wantKey:  "hunter2",
+	}, {
+		name:     "other host",
+		filename: origin.URL + "/elsewhere.json",
+		wantKey:  "",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			gotKey = "unset"
+			l := NewLoader()
+			if err := l.Register(reHTTPFilename, &HTTPLoader{Headers: http.Header{"X-Api-Key": {"hunter2"}}}); err != nil {
+				t.Fatal(err)
+			}
+			into := struct{ Name string }{}
+			if err := l.Load(tc.filename, &into); err != nil {
+				t.Fatal(err)
+			}
+			if gotKey != tc.wantKey {
+				t.Errorf("X-Api-Key is %q, want %q", gotKey, tc.wantKey)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	l := NewLoader()
+	if err := l.Register(reHTTPFilename, &HTTPLoader{}); err != nil {
+		t.Fatal(err)
+	}
+	into := struct{ Name string }{}
+	err := l.Load(srv.URL+"/app.json?token=hunter2", &into)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("got %v, want an HTTPError", err)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error %q includes the query string", err)
+	}
+}
@@ -0,0 +1,40 @@
+package loadfile
+
+import "reflect"
+
+// Immutable holds a loaded value which can only be read through Get
+type Immutable[T any] struct {
+	value T
+}
+
+// LoadImmutable loads filename into a new T and returns it as an Immutable,
+// for config which nothing should change once it is loaded. Go can't stop a
+// value being mutated, so Immutable instead never hands out what it holds:
+// the value is deep copied once loaded, so it shares no maps, slices or
+// pointers with anything the Loader keeps, such as cached content, and Get
+// returns a new deep copy each call, so changes to one only affect that
+// copy. Only exported fields are copied deeply, unexported ones are copied as
+// they are. Like LoadAtomic it is a function, and a nil Loader uses the
+// DefaultLoader.
+func LoadImmutable[T any](l *Loader, filename string) (Immutable[T], error) {
+	if l == nil {
+		l = DefaultLoader
+	}
+	into := new(T)
+	if err := l.Load(filename, into); err != nil {
+		return Immutable[T]{}, err
+	}
+	return Immutable[T]{value: copyOf(*into)}, nil
+}
+
+// Get returns a deep copy of the value. Callers which read it often should
+// keep the copy rather than calling Get each time.
+func (i Immutable[T]) Get() T {
+	return copyOf(i.value)
+}
+
+func copyOf[T any](v T) T {
+	var copied T
+	reflect.ValueOf(&copied).Elem().Set(deepCopy(reflect.ValueOf(&v).Elem()))
+	return copied
+}
@@ -0,0 +1,107 @@
+package loadfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type immutableConfig struct {
+	Name    string
+	Port    *int
+	Tags    []string
+	Labels  map[string]string
+	Extra   map[string]interface{}
+	Raw     json.RawMessage
+	Servers []struct{ Host string }
+}
+
+func TestLoadImmutable(t *testing.T) {
+	filename := writeTestFile(t, "app.json", `{
+		"name": "app",
+		"port": 8080,
+		"tags": ["a", "b"],
+		"labels": {"team": "core"},
+		"extra": {"nested": {"list": [1, 2]}},
+		"raw": {"kept": true},
+		"servers": [{"host": "one"}]
+	}`)
+	immutable, err := LoadImmutable[immutableConfig](nil, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := immutable.Get()
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(cfg immutableConfig)
+	}{
+		{name: "pointer", mutate: func(cfg immutableConfig) { *cfg.Port = 1 }},
+		{name: "slice", mutate: func(cfg immutableConfig) { cfg.Tags[0] = "changed" }},
+		{name: "map", mutate: func(cfg immutableConfig) { cfg.Labels["team"] = "changed" }},
+		{name: "map added to", mutate: func(cfg immutableConfig) { cfg.Labels["other"] = "added" }},
+		{name: "nested interface", mutate: func(cfg immutableConfig) {
+			nested := cfg.Extra["nested"].(map[string]interface{})
+			nested["list"].([]interface{})[0] = "changed"
+		}},
+		{name: "raw bytes", mutate: func(cfg immutableConfig) { cfg.Raw[0] = '[' }},
+		{name: "struct in a slice", mutate: func(cfg immutableConfig) { cfg.Servers[0].Host = "changed" }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mutate(immutable.Get())
+			if got := immutable.Get(); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v after changing a copy, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadImmutableCached(t *testing.T) {
+	// Both loads are served the same cached bytes
+	hits := []bool{}
+	sl := &S3Loader{
+		CacheByETag:   true,
+		OnCacheResult: func(filename string, hit bool) { hits = append(hits, hit) },
+		state: &s3State{client: fakeS3(t, map[string][]byte{
+			"app.json": []byte(`{"raw": {"kept": true}}`),
+		})},
+	}
+	l := NewLoader()
+	if err := l.Register(reS3Filename, sl); err != nil {
+		t.Fatal(err)
+	}
+	first, err := LoadImmutable[immutableConfig](l, "s3://bucket/app.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := first.Get().Raw
+	raw[0] = '['
+	second, err := LoadImmutable[immutableConfig](l, "s3://bucket/app.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(second.Get().Raw); got != `{"kept": true}` {
+		t.Errorf("second load got %s", got)
+	}
+	if got := string(first.Get().Raw); got != `{"kept": true}` {
+		t.Errorf("first load got %s", got)
+	}
+	if len(hits) != 2 || !hits[1] {
+		t.Errorf("cache results are %v, want the second load cached", hits)
+	}
+}
+
+func TestLoadImmutableError(t *testing.T) {
+	immutable, err := LoadImmutable[immutableConfig](NewLoader(), writeTestFile(t, "app.json", `{"name": [}`))
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	if got := immutable.Get(); !reflect.DeepEqual(got, immutableConfig{}) {
+		t.Errorf("got %+v, want the zero value", got)
+	}
+	if _, err := LoadImmutable[immutableConfig](nil, "/nonexistent/app.json"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,324 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth limits how deeply includes can nest, catching cycles which
+// the visited check misses because the same file is reached by different names
+const maxIncludeDepth = 16
+
+// ErrIncludeCycle is returned when a file includes itself, directly or
+// indirectly, or includes are nested too deeply
+var ErrIncludeCycle = errors.New("Include cycle detected")
+
+// WithIncludes enables include directives, which are replaced by the content
+// of the referenced file while decoding:
+//
+//	YAML: database: !include database.yaml
+//	JSON: {"database": {"$include": "database.json"}}
+//	XML:  <xi:include href="database.xml"/>
+//
+// In JSON the directive object must have no other keys. XML uses XInclude,
+// with xi bound to http://www.w3.org/2001/XInclude, splicing in the root
+// element of the referenced document, or its text with parse="text".
+// Relative references are resolved against the including file's location, and
+// fetched through the same Loader, so an S3 file can include its neighbours. A
+// JSON file can include YAML and vice versa. Cycles return ErrIncludeCycle.
+// Within one load, a file included from several places is only fetched once.
+//
+// In YAML an include can be the value of a merge key, merging the mapping of
+// another file, such as shared defaults, into the enclosing mapping:
+//
+//	service:
+//	  <<: !include defaults.yaml
+//	  port: 8080
+//
+// As with anchors, keys of the enclosing mapping win over merged ones, and
+// with several files, <<: [!include a.yaml, !include b.yaml], earlier files
+// win over later ones. A merged file must be a mapping, and can merge in
+// files of its own, with cycles caught as for any include.
+//
+// A remote file, such as one on S3 or HTTP, can't include a local file by a
+// file:// URL, which would let whoever controls it read local files, unless
+// the Loader was also created WithLocalIncludesFromRemote. It returns
+// ErrSchemeNotAllowed. Paths without a scheme resolve against its location.
+func WithIncludes() Option {
+	return func(l *Loader) {
+		l.includes = true
+	}
+}
+
+// WithLocalIncludesFromRemote lets remote files include local paths, for
+// remote config which is trusted as much as the local filesystem
+func WithLocalIncludesFromRemote() Option {
+	return func(l *Loader) {
+		l.localIncludesFromRemote = true
+	}
+}
+
+// includer resolves the includes for a single top level load
+//
+// Each file is fetched, and its own includes resolved, at most once per
+// includer, so a file included from several places, such as both sides of a
+// diamond, is only fetched once and is the same everywhere it is included.
+type includer struct {
+	loader *Loader
+	stack  []string
+
+	// fetched is the decompressed content of each file fetched, and resolved
+	// the content with its includes resolved, by filename
+	fetched  map[string]includedFile
+	resolved map[string]includedFile
+}
+
+type includedFile struct {
+	ext     string
+	content []byte
+}
+
+func (inc *includer) push(filename string) error {
+	for _, parent := range inc.stack {
+		if parent == filename {
+			return fmt.Errorf("%w: %s -> %s", ErrIncludeCycle, strings.Join(inc.stack, " -> "), filename)
+		}
+	}
+	if len(inc.stack) > maxIncludeDepth {
+		return fmt.Errorf("%w: more than %d levels including %s", ErrIncludeCycle, maxIncludeDepth, filename)
+	}
+	inc.stack = append(inc.stack, filename)
+	return nil
+}
+
+func (inc *includer) pop() {
+	inc.stack = inc.stack[:len(inc.stack)-1]
+}
+
+// resolve returns content with every include directive replaced, in the same
+// format as it was given. Formats without include support are unchanged.
+func (inc *includer) resolve(filename, ext string, content []byte) ([]byte, error) {
+	switch inc.loader.docFormat(ext) {
+	case docJSON:
+		doc, err := decodeJSONNumbers(content)
+		if err != nil {
+			return nil, err
+		}
+		doc, included, err := inc.walkGeneric(filename, doc)
+		if err != nil {
+			return nil, err
+		}
+		if !included {
+			// Unchanged, so decode errors point into the file as written
+			return content, nil
+		}
+		return json.Marshal(doc)
+
+	case docYAML:
+		root := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(content, root); err != nil {
+			return nil, err
+		}
+		if err := inc.walkNode(filename, root); err != nil {
+			return nil, err
+		}
+		return yamlv3.Marshal(root)
+
+	case docXML:
+		return inc.resolveXInclude(filename, content)
+	}
+	return content, nil
+}
+
+// fetch loads and decompresses an included file with its own includes
+// resolved, returning the extension of its format
+func (inc *includer) fetch(base, ref string) (string, []byte, error) {
+	filename, err := inc.resolveRef(base, ref)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := inc.push(filename); err != nil {
+		return "", nil, err
+	}
+	defer inc.pop()
+
+	if file, ok := inc.resolved[filename]; ok {
+		inc.loader.tracer.end(inc.loader.tracer.begin("include", filename, "already fetched"), nil)
+		return file.ext, file.content, nil
+	}
+	step := inc.loader.tracer.begin("include", filename)
+	ext, content, err := inc.fetchContent(filename)
+	if err == nil {
+		content, err = inc.resolve(filename, ext, content)
+	}
+	inc.loader.tracer.end(step, err)
+	if err != nil {
+		return "", nil, fmt.Errorf("include %s: %w", redactURL(filename), err)
+	}
+	inc.loader.commit(filename)
+	if inc.resolved == nil {
+		inc.resolved = map[string]includedFile{}
+	}
+	inc.resolved[filename] = includedFile{ext: ext, content: content}
+	return ext, content, nil
+}
+
+// fetchText loads and decompresses an included file as it is, without
+// resolving anything in it
+func (inc *includer) fetchText(base, ref string) ([]byte, error) {
+	filename, err := inc.resolveRef(base, ref)
+	if err != nil {
+		return nil, err
+	}
+	_, content, err := inc.fetchContent(filename)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", redactURL(filename), err)
+	}
+	return content, nil
+}
+
+// resolveRef resolves ref against base, refusing a local path included by a
+// remote file unless that is allowed
+func (inc *includer) resolveRef(base, ref string) (string, error) {
+	filename, err := ResolveRelative(base, ref)
+	if err != nil {
+		return "", err
+	}
+	if isLocalPath(filename) && !isLocalPath(base) && !inc.loader.localIncludesFromRemote {
+		return "", fmt.Errorf("%w: remote %s including local path %s", ErrSchemeNotAllowed, redactURL(base), filename)
+	}
+	return filename, nil
+}
+
+func (inc *includer) fetchContent(filename string) (string, []byte, error) {
+	if file, ok := inc.fetched[filename]; ok {
+		return file.ext, file.content, nil
+	}
+	content, err := inc.loader.readAll(filename)
+	if err != nil {
+		return "", nil, err
+	}
+	formatName, reader, err := inc.loader.decompress(filename, bytes.NewReader(content))
+	if err != nil {
+		return "", nil, err
+	}
+	defer reader.Close()
+	content, err = ioutil.ReadAll(reader)
+	if err != nil {
+		return "", nil, err
+	}
+	if inc.fetched == nil {
+		inc.fetched = map[string]includedFile{}
+	}
+	inc.fetched[filename] = includedFile{ext: fileExtension(formatName), content: content}
+	return fileExtension(formatName), content, nil
+}
+
+// walkGeneric replaces each $include object in a JSON document with the
+// document it includes, returning whether there were any
+func (inc *includer) walkGeneric(base string, v interface{}) (interface{}, bool, error) {
+	included := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := val["$include"].(string); ok && len(val) == 1 {
+			ext, content, err := inc.fetch(base, ref)
+			if err != nil {
+				return nil, false, err
+			}
+			var doc interface{}
+			if format := inc.loader.docFormat(ext); format == docJSON {
+				doc, err = decodeJSONNumbers(content)
+			} else {
+				doc, err = parseDocument(format, content)
+			}
+			if err != nil {
+				return nil, false, fmt.Errorf("include %s: %w", ref, err)
+			}
+			return doc, true, nil
+		}
+		for key, child := range val {
+			resolved, childIncluded, err := inc.walkGeneric(base, child)
+			if err != nil {
+				return nil, false, err
+			}
+			val[key] = resolved
+			included = included || childIncluded
+		}
+	case []interface{}:
+		for idx, child := range val {
+			resolved, childIncluded, err := inc.walkGeneric(base, child)
+			if err != nil {
+				return nil, false, err
+			}
+			val[idx] = resolved
+			included = included || childIncluded
+		}
+	}
+	return v, included, nil
+}
+
+func (inc *includer) walkNode(base string, node *yamlv3.Node) error {
+	if node.Kind == yamlv3.ScalarNode && node.Tag == "!include" {
+		_, content, err := inc.fetch(base, node.Value)
+		if err != nil {
+			return err
+		}
+		// JSON is YAML, so whichever format was included parses as a node
+		included := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(content, included); err != nil {
+			return fmt.Errorf("include %s: %w", node.Value, err)
+		}
+		if included.Kind == yamlv3.DocumentNode && len(included.Content) == 1 {
+			included = included.Content[0]
+		} else {
+			included = &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null", Value: "null"}
+		}
+		*node = *included
+		return nil
+	}
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Kind == yamlv3.ScalarNode && key.Value == "<<" {
+				if err := inc.walkMerge(base, node.Content[i+1]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if err := inc.walkNode(base, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkMerge resolves the includes merged by a << key, which must be mappings
+func (inc *includer) walkMerge(base string, value *yamlv3.Node) error {
+	merged := []*yamlv3.Node{value}
+	if value.Kind == yamlv3.SequenceNode {
+		merged = value.Content
+	}
+	for _, node := range merged {
+		if node.Kind != yamlv3.ScalarNode || node.Tag != "!include" {
+			continue
+		}
+		ref := node.Value
+		step := inc.loader.tracer.begin("merge", ref, "into ", base)
+		err := inc.walkNode(base, node)
+		inc.loader.tracer.end(step, err)
+		if err != nil {
+			return err
+		}
+		if node.Kind != yamlv3.MappingNode {
+			return fmt.Errorf("include %s: merged with <<, but isn't a mapping", ref)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// memLoader serves files from memory, as a remote backend
+type memLoader map[string]string
+
+func (ml memLoader) GetReader(filename string) (io.Reader, error) {
+	content, ok := ml[filename]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+	}
+	return strings.NewReader(content), nil
+}
+
+func TestIncludesFromRemote(t *testing.T) {
+	local := writeTestFile(t, "local.yaml", "password: hunter2\n")
+	remote := memLoader{
+		"mem://config/app.yaml":     "name: app\ndb: !include db.yaml\n",
+		"mem://config/db.yaml":      "password: remote\n",
+		"mem://config/fileurl.yaml": "name: app\ndb: !include file://" + local + "\n",
+	}
+	for _, tc := range []struct {
+		name     string
+		opts     []Option
+		filename string
+		want     string
+		wantErr  error
+	}{{
+		name:     "remote including remote",
+		filename: "mem://config/app.yaml",
+		want:     "remote",
+	}, {
+		name:     "remote including a file url",
+		filename: "mem://config/fileurl.yaml",
+		wantErr:  ErrSchemeNotAllowed,
+	}, {
+		name:     "remote including a file url when allowed",
+		opts:     []Option{WithLocalIncludesFromRemote()},
+		filename: "mem://config/fileurl.yaml",
+		want:     "hunter2",
+	}, {
+		name:     "local including a local path",
+		filename: writeTestFile(t, "app.yaml", "name: app\ndb: !include "+local+"\n"),
+		want:     "hunter2",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader(append([]Option{WithIncludes()}, tc.opts...)...)
+			if err := l.Register(regexp.MustCompile(`^mem:\/\/`), remote); err != nil {
+				t.Fatal(err)
+			}
+			got := struct {
+				DB struct{ Password string }
+			}{}
+			err := l.Load(tc.filename, &got)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.DB.Password != tc.want {
+				t.Errorf("got %q, want %q", got.DB.Password, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONIncludesNumbers(t *testing.T) {
+	remote := memLoader{
+		"mem://config/plain.json": `{"big": 18446744073709551615, "db": {"port": 5432}}`,
+		"mem://config/app.json":   `{"big": 18446744073709551615, "db": {"$include": "db.json"}}`,
+		"mem://config/db.json":    `{"port": 5432, "big": 18446744073709551614}`,
+		"mem://config/bad.json":   "{\n  \"big\": 1,\n  \"db\": {\"port\": \"high\"}\n}",
+	}
+	type db struct {
+		Port int
+		Big  uint64
+	}
+	type config struct {
+		Big uint64
+		DB  db
+	}
+	for _, tc := range []struct {
+		name     string
+		filename string
+		want     config
+		wantLine int
+	}{{
+		name:     "no includes",
+		filename: "mem://config/plain.json",
+		want:     config{Big: 18446744073709551615, DB: db{Port: 5432}},
+	}, {
+		name:     "spliced",
+		filename: "mem://config/app.json",
+		want:     config{Big: 18446744073709551615, DB: db{Port: 5432, Big: 18446744073709551614}},
+	}, {
+		name:     "error position in the file as written",
+		filename: "mem://config/bad.json",
+		wantLine: 3,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader(WithIncludes())
+			if err := l.Register(regexp.MustCompile(`^mem:\/\/`), remote); err != nil {
+				t.Fatal(err)
+			}
+			got := config{}
+			err := l.Load(tc.filename, &got)
+			if tc.wantLine != 0 {
+				var decodeErr *DecodeError
+				if !errors.As(err, &decodeErr) || decodeErr.Line != tc.wantLine {
+					t.Fatalf("got %v, want a DecodeError on line %d", err, tc.wantLine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package loadfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Iterate streams the records of a large data file to fn one at a time,
+// rather than loading them all, stopping at and returning the first error fn
+// returns. The format is taken from the extension, after any compression
+// extension:
+//
+//   - .ndjson and .jsonl: each non blank line is a record, passed as it is
+//   - .csv: the first row is the header, each following row is passed as a
+//     JSON object of header to value, so every record can be decoded with
+//     json.Unmarshal
+//
+// fn may keep raw, it isn't reused. Other formats return ErrUnsupported.
+func (l *Loader) Iterate(filename string, fn func(raw []byte) error) error {
+	reader, err := l.GetReadCloser(filename)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	formatName, decompressed, err := l.decompress(l.formatName(filename, reader), reader)
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	defer decompressed.Close()
+	ext := l.defaultExtension(filename, fileExtension(formatName))
+
+	switch ext {
+	case "ndjson", "jsonl":
+		err = iterateLines(l.transcode(ext, decompressed), fn)
+	case "csv":
+		err = iterateCSV(decompressed, fn)
+	default:
+		return fmt.Errorf("%w: iterating %s files", ErrUnsupported, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	return nil
+}
+
+// Iterate streams the records of a file to fn, using the default loader
+func Iterate(filename string, fn func(raw []byte) error) error {
+	return DefaultLoader.Iterate(filename, fn)
+}
+
+func iterateLines(r io.Reader, fn func(raw []byte) error) error {
+	buffered := bufio.NewReader(r)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if record := bytes.TrimSpace(line); len(record) > 0 {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func iterateCSV(r io.Reader, fn func(raw []byte) error) error {
+	records := csv.NewReader(r)
+	header, err := records.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	records.FieldsPerRecord = len(header)
+	records.ReuseRecord = true
+	for {
+		row, err := records.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		object := make(map[string]string, len(header))
+		for i, name := range header {
+			object[name] = row[i]
+		}
+		raw, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var reK8sDirFilename = regexp.MustCompile(`^k8sdir:\/\/(.+)$`)
+
+// k8sDataLink is the symlink Kubernetes swaps to update a mounted volume
+// atomically
+const k8sDataLink = "..data"
+
+// K8sDirLoader reads a Kubernetes Secret or ConfigMap volume mount, named like
+// k8sdir:///etc/secrets/app, as a JSON object with a string value per key
+// (file), which decodes into a struct or map like any JSON file.
+//
+// Kubernetes writes each update to a new directory and swaps the ..data
+// symlink to it, with each key a symlink through ..data. The loader resolves
+// ..data once and reads every key from that directory, so a load never mixes
+// keys from before and after an update. Entries starting with .. are ignored.
+// A directory without ..data is read as it is.
+type K8sDirLoader struct{}
+
+// k8sSwapRetries is how many times a read is retried when the resolved data
+// directory is removed by an update mid read
+const k8sSwapRetries = 3
+
+func (kl *K8sDirLoader) GetReader(filename string) (io.Reader, error) {
+	parts := reK8sDirFilename.FindStringSubmatch(filename)
+	if len(parts) != 2 {
+		return nil, errors.New("Impossible bad match passed to K8sDirLoader")
+	}
+	dir := parts[1]
+
+	var err error
+	for attempt := 0; attempt < k8sSwapRetries; attempt++ {
+		var values map[string]string
+		values, err = readK8sDir(dir)
+		if err == nil {
+			content, err := json.Marshal(values)
+			if err != nil {
+				return nil, err
+			}
+			return formatReader{Reader: bytes.NewReader(content), ext: "json"}, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if _, statErr := os.Stat(dir); statErr != nil {
+			// The mount itself is missing, not swapped
+			break
+		}
+	}
+	return nil, err
+}
+
+// readK8sDir reads every key from the directory ..data points to
+func readK8sDir(dir string) (map[string]string, error) {
+	dataDir := dir
+	if target, err := os.Readlink(filepath.Join(dir, k8sDataLink)); err == nil {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		dataDir = target
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "..") {
+			continue
+		}
+		path := filepath.Join(dataDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		values[name] = string(content)
+	}
+	return values, nil
+}
@@ -0,0 +1,103 @@
+// Package k8sloader adds a loadfile.TypeLoader which reads a key of a
+// Kubernetes ConfigMap through the Kubernetes API, using client-go, for
+// ConfigMaps which aren't mounted, such as those in another namespace. Register
+// it with a Loader:
+//
+//	l := loadfile.NewLoader()
+//	l.Register(k8sloader.Pattern, &k8sloader.K8sAPILoader{})
+//	l.Load("k8s://platform/shared-config/app.yaml", &cfg)
+//
+// Mounted ConfigMaps are better read with the core package's k8sdir:// loader,
+// which needs no API access. This is kept out of the core package because of
+// the size of the client-go dependency.
+package k8sloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/daemonl/loadfile"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Pattern matches k8s:// filenames
+var Pattern = regexp.MustCompile(`^k8s:\/\/`)
+
+var reFilename = regexp.MustCompile(`^k8s:\/\/([^\/]+)\/([^\/]+)\/([^\/]+)$`)
+
+// K8sAPILoader fetches a key of a ConfigMap, named like
+// k8s://namespace/configmap/key. The format is taken from the extension of
+// the key, e.g. app.yaml. Keys are looked for in the ConfigMap's data, then its
+// binaryData. A missing ConfigMap or key returns loadfile.ErrNotFound.
+//
+// The service account needs permission to get configmaps in the namespace.
+type K8sAPILoader struct {
+	// Client makes the API requests. When nil, a client is built from the
+	// in-cluster config, the service account the pod runs as, on first use.
+	Client kubernetes.Interface
+
+	mu     sync.Mutex
+	client kubernetes.Interface
+}
+
+func (kl *K8sAPILoader) getClient() (kubernetes.Interface, error) {
+	if kl.Client != nil {
+		return kl.Client, nil
+	}
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	if kl.client != nil {
+		return kl.client, nil
+	}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	kl.client = client
+	return client, nil
+}
+
+func (kl *K8sAPILoader) GetReader(filename string) (io.Reader, error) {
+	return kl.GetReaderContext(context.Background(), filename)
+}
+
+// GetReaderContext is GetReader, with ctx covering the request
+func (kl *K8sAPILoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	parts := reFilename.FindStringSubmatch(filename)
+	if len(parts) != 4 {
+		return nil, errors.New("K8s filenames must be k8s://namespace/configmap/key")
+	}
+	namespace, name, key := parts[1], parts[2], parts[3]
+
+	client, err := kl.getClient()
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %v", loadfile.ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := configMap.Data[key]; ok {
+		return strings.NewReader(value), nil
+	}
+	if value, ok := configMap.BinaryData[key]; ok {
+		return bytes.NewReader(value), nil
+	}
+	return nil, fmt.Errorf("%w: ConfigMap %s/%s has no key %s", loadfile.ErrNotFound, namespace, name, key)
+}
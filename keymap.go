@@ -0,0 +1,153 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// WithKeyMap binds top level keys of JSON and YAML files to struct fields by
+// name rather than by tag, for files whose keys don't match the fields of a
+// type which can't be tagged, e.g. one which is generated. Each key of keyMap
+// is a key in the file, and its value the Go name of the field to decode it
+// into, e.g. {"listen_port": "Port"}. The key is renamed to the one the field
+// would otherwise be decoded from, replacing any key of that name in the
+// file. Keys which aren't mapped, and nested keys, bind as usual. A mapping to
+// a field the target doesn't have is an error.
+func WithKeyMap(keyMap map[string]string) Option {
+	return func(l *Loader) {
+		l.keyMap = keyMap
+	}
+}
+
+// keyMappingDecoder wraps decoder to rename the top level keys of keyMap to
+// the keys of the fields of t they are mapped to
+func keyMappingDecoder(format string, decoder DecoderFunc, keyMap map[string]string, t reflect.Type) DecoderFunc {
+	if t = keyMappedStruct(format, t); t == nil {
+		return decoder
+	}
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		content, err = mapKeys(format, content, keyMap, t)
+		if err != nil {
+			return err
+		}
+		return decoder(bytes.NewReader(content), into)
+	}
+}
+
+// keyMappedStruct returns the struct a target of type t points to, or nil if
+// it isn't one or format isn't JSON or YAML, as only those have keys mapped
+func keyMappedStruct(format string, t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if (format != docJSON && format != docYAML) || t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// mapKeys returns content with the top level keys of keyMap renamed to the
+// keys of the fields of struct t they are mapped to. Only keys are changed,
+// JSON values are kept as raw messages and YAML as yaml.v3 nodes, and content
+// with none of the keys, or which doesn't parse, is returned as it is.
+func mapKeys(format string, content []byte, keyMap map[string]string, t reflect.Type) ([]byte, error) {
+	renamed := func(fileKey string) (string, bool, error) {
+		fieldName, ok := keyMap[fileKey]
+		if !ok {
+			return "", false, nil
+		}
+		field, ok := t.FieldByName(fieldName)
+		if !ok {
+			return "", false, fmt.Errorf("Key %q is mapped to field %s, which %s doesn't have", fileKey, fieldName, t)
+		}
+		return decodedKeyName(format, field), true, nil
+	}
+
+	switch format {
+	case docJSON:
+		object := map[string]json.RawMessage{}
+		if err := json.Unmarshal(content, &object); err != nil {
+			return content, nil
+		}
+		mapped := map[string]json.RawMessage{}
+		for fileKey, value := range object {
+			key, ok, err := renamed(fileKey)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				delete(object, fileKey)
+				mapped[key] = value
+			}
+		}
+		if len(mapped) == 0 {
+			return content, nil
+		}
+		for key, value := range mapped {
+			object[key] = value
+		}
+		return json.Marshal(object)
+
+	case docYAML:
+		doc := &yamlv3.Node{}
+		if err := yamlv3.Unmarshal(content, doc); err != nil || len(doc.Content) != 1 || doc.Content[0].Kind != yamlv3.MappingNode {
+			return content, nil
+		}
+		root := doc.Content[0]
+		mapped := map[string]bool{}
+		renamedAt := map[int]bool{}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Kind != yamlv3.ScalarNode {
+				continue
+			}
+			key, ok, err := renamed(root.Content[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				root.Content[i].Value = key
+				root.Content[i].Tag = "!!str"
+				root.Content[i].Style = 0
+				mapped[key] = true
+				renamedAt[i] = true
+			}
+		}
+		if len(mapped) == 0 {
+			return content, nil
+		}
+		// A mapped key replaces any key of its new name in the file
+		kept := make([]*yamlv3.Node, 0, len(root.Content))
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if !renamedAt[i] && mapped[root.Content[i].Value] {
+				continue
+			}
+			kept = append(kept, root.Content[i], root.Content[i+1])
+		}
+		root.Content = kept
+		return yamlv3.Marshal(doc)
+	}
+	return content, nil
+}
+
+// decodedKeyName returns the key the format's decoder decodes field from:
+// the name in its tag, otherwise its name, lower cased for YAML
+func decodedKeyName(format string, field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get(format), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	if format == docYAML {
+		return strings.ToLower(field.Name)
+	}
+	return field.Name
+}
@@ -0,0 +1,92 @@
+package loadfile
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyMap(t *testing.T) {
+	type config struct {
+		Port    int    `json:"port" yaml:"port"`
+		Version string `json:"version" yaml:"version"`
+		Flag    string `json:"flag" yaml:"flag"`
+	}
+	keyMap := map[string]string{"listen_port": "Port"}
+
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		options []Option
+		want    config
+		wantErr error
+	}{{
+		name:    "yaml values as written",
+		file:    "config.yaml",
+		content: "listen_port: 8080\nversion: 1.10\nflag: yes\n",
+		want:    config{Port: 8080, Version: "1.10", Flag: "yes"},
+	}, {
+		name:    "mapped key replaces the field's own",
+		file:    "config.yaml",
+		content: "port: 1\nlisten_port: 8080\n",
+		want:    config{Port: 8080},
+	}, {
+		name:    "json",
+		file:    "config.json",
+		content: `{"listen_port": 8080, "version": "1.10"}`,
+		want:    config{Port: 8080, Version: "1.10"},
+	}, {
+		name:    "strict accepts mapped keys",
+		file:    "config.yaml",
+		content: "listen_port: 8080\n",
+		options: []Option{WithStrict()},
+		want:    config{Port: 8080},
+	}, {
+		name:    "strict still rejects unknown keys",
+		file:    "config.json",
+		content: `{"listen_port": 8080, "other": 1}`,
+		options: []Option{WithStrict()},
+		wantErr: ErrUnknownKey,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := config{}
+			loader := NewLoader(append(tc.options, WithKeyMap(keyMap))...)
+			err := loader.Load(writeTestFile(t, tc.file, tc.content), &got)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyMapWarnings(t *testing.T) {
+	type config struct {
+		Port int
+	}
+	filename := writeTestFile(t, "config.yaml", "listen_port: 8080\nother: 1\n")
+	warnings, err := NewLoader(WithKeyMap(map[string]string{"listen_port": "Port"})).LoadWithWarnings(filename, &config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Key != "other" {
+		t.Errorf("got warnings %v, want only other", warnings)
+	}
+}
+
+func TestKeyMapMissingField(t *testing.T) {
+	err := NewLoader(WithKeyMap(map[string]string{"a": "Missing"})).Load(writeTestFile(t, "config.yaml", "a: 1\n"), &struct{ B int }{})
+	if err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Fatalf("got error %v, want one naming the missing field", err)
+	}
+}
@@ -0,0 +1,91 @@
+package loadfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+)
+
+// WithKeyNormalizer applies normalize to every key when decoding into a map
+// (or an interface{}), e.g. strings.ToLower so that Timeout and timeout bind
+// the same. Nested keys are normalized too, including within slices. Two keys
+// in one mapping which normalize to the same key are an error. Structs are
+// decoded as usual, their tags already control binding. Only JSON and YAML are
+// normalized.
+func WithKeyNormalizer(normalize func(string) string) Option {
+	return func(l *Loader) {
+		l.keyNormalizer = normalize
+	}
+}
+
+// isMapTarget returns true if into points to a map or an interface{}
+func isMapTarget(into interface{}) bool {
+	t := reflect.TypeOf(into)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return false
+	}
+	switch t.Elem().Kind() {
+	case reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// normalizingDecoder wraps decoder to normalize the keys of JSON and YAML
+// documents first
+func normalizingDecoder(format string, decoder DecoderFunc, normalize func(string) string) DecoderFunc {
+	if format != docJSON && format != docYAML {
+		return decoder
+	}
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		doc, err := parseDocument(format, content)
+		if err != nil {
+			return err
+		}
+		doc, err = normalizeKeys(doc, normalize)
+		if err != nil {
+			return err
+		}
+		content, err = encodeDocument(format, doc)
+		if err != nil {
+			return err
+		}
+		return decoder(bytes.NewReader(content), into)
+	}
+}
+
+func normalizeKeys(doc interface{}, normalize func(string) string) (interface{}, error) {
+	switch val := doc.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(val))
+		from := make(map[string]string, len(val))
+		for key, child := range val {
+			newKey := normalize(key)
+			if other, ok := from[newKey]; ok {
+				return nil, fmt.Errorf("Keys %q and %q both normalize to %q", other, key, newKey)
+			}
+			from[newKey] = key
+			child, err := normalizeKeys(child, normalize)
+			if err != nil {
+				return nil, err
+			}
+			normalized[newKey] = child
+		}
+		return normalized, nil
+	case []interface{}:
+		for i, child := range val {
+			child, err := normalizeKeys(child, normalize)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = child
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,171 @@
+// Package keystore adds PKCS #12 keystores, .p12 and .pfx files, to
+// loadfile, reading one entry of the keystore by its alias. It registers the
+// formats when imported, and WithPassword gives a Loader the password:
+//
+//	l := loadfile.NewLoader(keystore.WithPassword(password))
+//	var cert tls.Certificate
+//	l.Load("s3://bucket/bundle.p12#service", &cert)
+//
+// The alias is the entry's friendly name, as keytool -alias or openssl -name
+// sets it, matched ignoring case. Without an #alias the keystore must have
+// only one. An entry decodes into a *tls.Certificate, an *Entry, or a *[]byte
+// or *string, which get its certificates and key PEM encoded.
+//
+// Keystores are decrypted with golang.org/x/crypto/pkcs12, which reads the
+// legacy 3DES and RC2 encryption keytool and Windows use, but not the AES
+// encryption OpenSSL 3 uses by default, so export those with -legacy.
+//
+// The password is never included in an error. This lives outside of the core
+// package as few builds need it.
+package keystore
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/daemonl/loadfile"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// ErrNoAlias is returned when a keystore has no entry with the alias given,
+// or has several and no alias is given
+var ErrNoAlias = errors.New("No keystore entry with that alias")
+
+// Entry is an entry of a keystore
+type Entry struct {
+	Alias        string
+	Certificates []*x509.Certificate
+	// PrivateKey is nil for an entry with only certificates
+	PrivateKey crypto.PrivateKey
+}
+
+func init() {
+	decode := passwordDecoder("")
+	loadfile.RegisterFragmentFormat("p12", decode)
+	loadfile.RegisterFragmentFormat("pfx", decode)
+}
+
+// WithPassword decrypts the Loader's keystores with password. Without it they
+// are read with an empty password.
+func WithPassword(password string) loadfile.Option {
+	return func(l *loadfile.Loader) {
+		decode := passwordDecoder(password)
+		l.RegisterFragmentFormat("p12", decode)
+		l.RegisterFragmentFormat("pfx", decode)
+	}
+}
+
+func passwordDecoder(password string) loadfile.FragmentDecoderFunc {
+	return func(r io.Reader, alias string, into interface{}) error {
+		return decodeKeystore(r, password, alias, into)
+	}
+}
+
+func decodeKeystore(r io.Reader, password, alias string, into interface{}) error {
+	switch into.(type) {
+	case *tls.Certificate, *Entry, *[]byte, *string:
+	default:
+		return fmt.Errorf("Keystore target must be a *tls.Certificate, *keystore.Entry, *[]byte or *string, not %T", into)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return err
+	}
+	blocks, alias, err = entryBlocks(blocks, alias)
+	if err != nil {
+		return err
+	}
+
+	entry := Entry{Alias: alias}
+	encoded := []byte{}
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return err
+			}
+			entry.Certificates = append(entry.Certificates, cert)
+		case "PRIVATE KEY":
+			// ToPEM gives PKCS #1 RSA and SEC 1 EC keys, in spite of the type
+			if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+				entry.PrivateKey = key
+				block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: block.Bytes}
+			} else if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+				entry.PrivateKey = key
+				block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: block.Bytes}
+			} else {
+				return errors.New("Keystore entry has a private key of an unsupported type")
+			}
+		default:
+			continue
+		}
+		encoded = append(encoded, pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})...)
+	}
+
+	switch into := into.(type) {
+	case *tls.Certificate:
+		cert, err := tls.X509KeyPair(encoded, encoded)
+		if err != nil {
+			return fmt.Errorf("Keystore entry %s: %w", alias, err)
+		}
+		*into = cert
+	case *Entry:
+		*into = entry
+	case *[]byte:
+		*into = encoded
+	case *string:
+		*into = string(encoded)
+	}
+	return nil
+}
+
+// entryBlocks returns the blocks with the alias as their friendly name, and
+// the alias as the keystore has it, or those of the only alias when alias is
+// ""
+func entryBlocks(blocks []*pem.Block, alias string) ([]*pem.Block, string, error) {
+	byAlias := map[string][]*pem.Block{}
+	names := map[string]string{}
+	for _, block := range blocks {
+		name := block.Headers["friendlyName"]
+		byAlias[strings.ToLower(name)] = append(byAlias[strings.ToLower(name)], block)
+		names[strings.ToLower(name)] = name
+	}
+	if alias == "" {
+		if len(byAlias) == 1 {
+			for key, blocks := range byAlias {
+				return blocks, names[key], nil
+			}
+		}
+		return nil, "", fmt.Errorf("%w: the keystore has %s, name one with file.p12#alias", ErrNoAlias, aliasList(names))
+	}
+	matched, ok := byAlias[strings.ToLower(alias)]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %q, the keystore has %s", ErrNoAlias, alias, aliasList(names))
+	}
+	return matched, names[strings.ToLower(alias)], nil
+}
+
+func aliasList(names map[string]string) string {
+	if len(names) == 0 {
+		return "no entries"
+	}
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		list = append(list, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,143 @@
+package loadfile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LastKnownGoodLoader wraps another TypeLoader, keeping a copy of every file it
+// successfully fetches and decodes in CacheDir. When a later fetch fails with
+// an error RetryLoader would retry, such as a timeout or a server error, the
+// cached copy is served instead and GetReaderInfo reports it as Stale, so a
+// service can still start during an outage of the config backend. Other
+// errors, including not found, are returned as they are.
+//
+// A fetched copy is only cached once Commit is called for it, which a Loader
+// does when the file has decoded, so a malformed response never replaces a
+// good copy. Used without a Loader, Commit has to be called instead.
+type LastKnownGoodLoader struct {
+	Inner TypeLoader
+
+	// CacheDir holds the cached copies, one file per filename. It must
+	// already exist.
+	CacheDir string
+
+	mu      sync.Mutex
+	pending map[string][]byte
+}
+
+func (ll *LastKnownGoodLoader) cachePath(filename string) string {
+	sum := sha256.Sum256([]byte(filename))
+	return filepath.Join(ll.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+func (ll *LastKnownGoodLoader) GetReader(filename string) (io.Reader, error) {
+	r, _, err := ll.GetReaderInfo(filename)
+	return r, err
+}
+
+func (ll *LastKnownGoodLoader) GetReaderInfo(filename string) (io.Reader, Info, error) {
+	return ll.GetReaderInfoContext(context.Background(), filename)
+}
+
+// GetReaderInfoContext is GetReaderInfo, fetching through Inner with ctx when
+// it is a ContextLoader, so that a timeout falls back to the cached copy
+func (ll *LastKnownGoodLoader) GetReaderInfoContext(ctx context.Context, filename string) (io.Reader, Info, error) {
+	b, err := ll.fetch(ctx, filename)
+	if err == nil {
+		ll.mu.Lock()
+		if ll.pending == nil {
+			ll.pending = map[string][]byte{}
+		}
+		ll.pending[filename] = b
+		ll.mu.Unlock()
+		return bytes.NewReader(b), Info{}, nil
+	}
+
+	// Only an outage falls back, a file which is now missing or forbidden
+	// would be the same on every attempt
+	if !isRetryable(err) {
+		return nil, Info{}, err
+	}
+	cached, cacheErr := ioutil.ReadFile(ll.cachePath(filename))
+	if cacheErr != nil {
+		return nil, Info{}, err
+	}
+	return bytes.NewReader(cached), Info{Stale: true}, nil
+}
+
+// Commit caches the copy of filename last fetched, now it is known to be good
+func (ll *LastKnownGoodLoader) Commit(filename string) error {
+	ll.mu.Lock()
+	b, ok := ll.pending[filename]
+	delete(ll.pending, filename)
+	ll.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return writeFileAtomic(ll.cachePath(filename), b, 0600)
+}
+
+func (ll *LastKnownGoodLoader) fetch(ctx context.Context, filename string) ([]byte, error) {
+	var r io.Reader
+	var err error
+	if contextLoader, ok := ll.Inner.(ContextLoader); ok {
+		r, err = contextLoader.GetReaderContext(ctx, filename)
+	} else {
+		r, err = ll.Inner.GetReader(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return ioutil.ReadAll(r)
+}
+
+// renameFile is os.Rename, replaced in tests to interrupt a write before it
+// is renamed into place
+var renameFile = os.Rename
+
+// writeFileAtomic writes to a temporary file in the same directory and renames
+// it into place, so readers see either the old or the new content, never a
+// partial write
+func writeFileAtomic(path string, b []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := renameFile(tmp.Name(), path); err != nil {
+		return err
+	}
+	// Sync the directory so the rename itself survives a crash. Not every
+	// platform can sync a directory, and the file is already complete, so
+	// this is best effort.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package loadfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyLoader serves body, fails with err when it is set, or waits for the
+// context to end when hang is set
+type flakyLoader struct {
+	body string
+	err  error
+	hang bool
+}
+
+func (fl *flakyLoader) GetReader(filename string) (io.Reader, error) {
+	return fl.GetReaderContext(context.Background(), filename)
+}
+
+func (fl *flakyLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	if fl.err != nil {
+		return nil, fl.err
+	}
+	if fl.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return strings.NewReader(fl.body), nil
+}
+
+func TestLastKnownGoodTimeout(t *testing.T) {
+	inner := &flakyLoader{body: `{"Port": 8080}`}
+	l := NewLoader(WithDefaultTimeout(20 * time.Millisecond))
+	if err := l.Register(regexp.MustCompile(`^flaky:\/\/`), &LastKnownGoodLoader{Inner: inner, CacheDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := struct{ Port int }{}
+	if info, err := l.LoadInfo("flaky://config.json", &got); err != nil || info.Stale || got.Port != 8080 {
+		t.Fatalf("got %+v, %+v, %v, want port 8080 fresh", got, info, err)
+	}
+
+	inner.hang = true
+	got.Port = 0
+	start := time.Now()
+	info, err := l.LoadInfo("flaky://config.json", &got)
+	if err != nil || !info.Stale || got.Port != 8080 {
+		t.Fatalf("got %+v, %+v, %v, want port 8080 stale", got, info, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s, want the default timeout to apply", elapsed)
+	}
+}
+
+func TestLastKnownGoodBudget(t *testing.T) {
+	l := NewLoader(WithTotalTimeout(time.Minute))
+	if err := l.Register(regexp.MustCompile(`^flaky:\/\/`), &LastKnownGoodLoader{Inner: &flakyLoader{body: `{}`}, CacheDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	l = l.withBudget()
+	l.budget.deadline = time.Now().Add(-time.Second)
+	if _, err := l.LoadInfo("flaky://config.json", &struct{}{}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("got %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestLastKnownGoodMalformed(t *testing.T) {
+	inner := &flakyLoader{body: `{"Port": 8080}`}
+	l := NewLoader(WithDefaultTimeout(20 * time.Millisecond))
+	if err := l.Register(regexp.MustCompile(`^flaky:\/\/`), &LastKnownGoodLoader{Inner: inner, CacheDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		body      string
+		hang      bool
+		wantErr   bool
+		wantStale bool
+	}{
+		{name: "good", body: `{"Port": 8080}`},
+		{name: "malformed", body: `{"Port": `, wantErr: true},
+		{name: "outage", hang: true, wantStale: true},
+	} {
+		inner.body, inner.hang = tc.body, tc.hang
+		got := struct{ Port int }{}
+		info, err := l.LoadInfo("flaky://config.json", &got)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("%s: want an error", tc.name)
+			}
+			continue
+		}
+		if err != nil || info.Stale != tc.wantStale || got.Port != 8080 {
+			t.Fatalf("%s: got %+v, %+v, %v, want port 8080, stale %v", tc.name, got, info, err, tc.wantStale)
+		}
+	}
+}
+
+func TestLastKnownGoodErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		wantStale bool
+	}{
+		{name: "server error", err: &HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, wantStale: true},
+		{name: "not found", err: fmt.Errorf("%w: config.json", ErrNotFound)},
+		{name: "forbidden", err: &HTTPError{StatusCode: 403, Status: "403 Forbidden"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &flakyLoader{body: `{"Port": 8080}`}
+			l := NewLoader()
+			if err := l.Register(regexp.MustCompile(`^flaky:\/\/`), &LastKnownGoodLoader{Inner: inner, CacheDir: t.TempDir()}); err != nil {
+				t.Fatal(err)
+			}
+			got := struct{ Port int }{}
+			if _, err := l.LoadInfo("flaky://config.json", &got); err != nil {
+				t.Fatal(err)
+			}
+
+			inner.err = tc.err
+			got.Port = 0
+			info, err := l.LoadInfo("flaky://config.json", &got)
+			if tc.wantStale {
+				if err != nil || !info.Stale || got.Port != 8080 {
+					t.Fatalf("got %+v, %+v, %v, want port 8080 stale", got, info, err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.err) || got.Port != 0 {
+				t.Fatalf("got %+v, %v, want error %v and nothing decoded", got, err, tc.err)
+			}
+		})
+	}
+}
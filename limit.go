@@ -0,0 +1,80 @@
+package loadfile
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrTooLarge is returned when a file is larger than the limit set
+// WithReadAllLimit
+var ErrTooLarge = errors.New("File exceeds the read limit")
+
+// WithReadAllLimit limits how many bytes are read from any one file. The limit
+// applies after decompression, so a small compressed file which expands beyond
+// n (a decompression bomb) fails with ErrTooLarge rather than being read in
+// full. Decoders which read the whole file first, such as YAML, never buffer
+// more than n bytes. Files read whole as they were fetched, such as by
+// LoadSigned or for includes, are limited to n before decompression too.
+func WithReadAllLimit(n int64) Option {
+	return func(l *Loader) {
+		l.readLimit = n
+	}
+}
+
+// limitedReader reads up to n bytes from r, then returns ErrTooLarge if r has
+// any more
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		// Check for one more byte to tell an exact fit from an overflow
+		var probe [1]byte
+		n, err := lr.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	return n, err
+}
+
+// limit wraps reader in the Loader's read limit, if one is set, keeping its
+// Close
+func (l *Loader) limit(reader io.ReadCloser) io.ReadCloser {
+	if l.readLimit <= 0 {
+		return reader
+	}
+	return &readCloser{
+		Reader: &limitedReader{r: reader, n: l.readLimit},
+		Closer: reader,
+	}
+}
+
+// readLimitKey is the context key fetches carry the Loader's read limit in
+type readLimitKey struct{}
+
+// withReadLimit returns ctx carrying the read limit n, for TypeLoaders which
+// read a body whole themselves, such as S3Loader's CacheByETag
+func withReadLimit(ctx context.Context, n int64) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, readLimitKey{}, n)
+}
+
+// limitRead wraps r in the read limit ctx carries, if any
+func limitRead(ctx context.Context, r io.Reader) io.Reader {
+	if n, ok := ctx.Value(readLimitKey{}).(int64); ok && n > 0 {
+		return &limitedReader{r: r, n: n}
+	}
+	return r
+}
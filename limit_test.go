@@ -0,0 +1,70 @@
+package loadfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadAllLimit(t *testing.T) {
+	// A megabyte of one repeated byte compresses to about a kilobyte
+	bomb := gzipped(t, "name: "+strings.Repeat("x", 1<<20)+"\n")
+	if len(bomb) >= 4096 {
+		t.Fatalf("compressed payload is %d bytes, want it under the limit", len(bomb))
+	}
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		limit   int64
+		wantErr error
+	}{{
+		name:    "decompression bomb",
+		file:    "bomb.yaml.gz",
+		content: string(bomb),
+		limit:   4096,
+		wantErr: ErrTooLarge,
+	}, {
+		name:    "within the limit decompressed",
+		file:    "app.yaml.gz",
+		content: string(gzipped(t, "name: app\n")),
+		limit:   4096,
+	}, {
+		name:    "exact fit",
+		file:    "app.yaml",
+		content: "name: app\n",
+		limit:   int64(len("name: app\n")),
+	}, {
+		name:    "one byte over",
+		file:    "app.yaml",
+		content: "name: app\n",
+		limit:   int64(len("name: app\n")) - 1,
+		wantErr: ErrTooLarge,
+	}, {
+		name:    "streamed format",
+		file:    "app.json",
+		content: `{"name": "` + strings.Repeat("x", 8192) + `"}`,
+		limit:   4096,
+		wantErr: ErrTooLarge,
+	}, {
+		name:    "no limit",
+		file:    "bomb.yaml.gz",
+		content: string(bomb),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			l := NewLoader(WithReadAllLimit(tc.limit))
+			into := struct{ Name string }{}
+			err := l.Load(filename, &into)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,70 +1,703 @@
 package loadfile
 
 import (
-	"encoding/json"
-	"encoding/xml"
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
 
-	"gopkg.in/yaml.v2"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"golang.org/x/text/encoding"
 )
 
 type Loader struct {
-	types    map[*regexp.Regexp]TypeLoader
+	types    []registration
 	fallback TypeLoader
+
+	errorOnEmpty bool
+	includes     bool
+	detectGzip   bool
+	xmlRoot      string
+	yamlV3       bool
+	readLimit    int64
+
+	keyNormalizer  func(string) string
+	keyMap         map[string]string
+	defaultTimeout time.Duration
+	strict         bool
+	sliceMerge     SliceMergeStrategy
+	charset        encoding.Encoding
+
+	signatureLocation func(filename string) string
+
+	durationStrings bool
+	stringCoercion  bool
+	timeLayout      string
+	refs            bool
+	partialDecode   bool
+
+	formats         map[string]DecoderFunc
+	fragmentFormats map[string]FragmentDecoderFunc
+	aliases         map[string]string
+	maxDepth        int
+
+	preserveComments bool
+	jsonComments     bool
+	formatCheck      bool
+	utf8Validation   bool
+
+	caseInsensitiveYAML bool
+
+	transforms    []func([]byte) ([]byte, error)
+	secretSchemes map[string]bool
+	environment   string
+	hostname      string
+
+	template      bool
+	templateFuncs template.FuncMap
+
+	envExpansion       bool
+	strictEnvExpansion bool
+
+	totalTimeout   time.Duration
+	totalReadLimit int64
+	budget         *budget
+	ctx            context.Context
+	tracer         *tracer
+
+	allowedSchemes          map[string]bool
+	denyLocal               bool
+	localIncludesFromRemote bool
+	unknownSchemeFallback   bool
+
+	stats  *loaderStats
+	frozen int32
+	named  *namedLoader
+
+	// registries is the *registries copied from the global ones by Freeze
+	registries atomic.Value
+
+	// skipRequired leaves required fields to be checked by the caller, once
+	// every file is decoded
+	skipRequired bool
+}
+
+type registration struct {
+	re     *regexp.Regexp
+	loader TypeLoader
+}
+
+// NewLoader returns a Loader with the same types registered as DefaultLoader,
+// configured by the given options
+func NewLoader(opts ...Option) *Loader {
+	l := &Loader{
+		types: []registration{
+			{re: reS3Filename, loader: &S3Loader{state: &s3State{}}},
+			{re: reAppConfigFilename, loader: &AppConfigLoader{}},
+			{re: reHTTPFilename, loader: &HTTPLoader{}},
+			{re: reUnixHTTPFilename, loader: &HTTPLoader{}},
+			{re: reEnvFilename, loader: &EnvLoader{}},
+			{re: reK8sDirFilename, loader: &K8sDirLoader{}},
+			{re: reSQLFilename, loader: &SQLLoader{}},
+		},
+		fallback: &FileLoader{FollowSymlinks: true},
+		stats:    &loaderStats{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Register adds a TypeLoader for filenames matching re. Later registrations
+// take precedence over earlier ones, so a default pattern (e.g. s3://) can be
+// replaced with a differently configured loader. Register is not safe to call
+// concurrently with loads. It returns ErrFrozen once the Loader is frozen.
+func (l *Loader) Register(re *regexp.Regexp, loader TypeLoader) error {
+	if err := l.checkFrozen(); err != nil {
+		return err
+	}
+	l.types = append([]registration{{re: re, loader: registeredLoader(loader)}}, l.types...)
+	return nil
+}
+
+// Registration is a pattern and the TypeLoader used for filenames matching it
+type Registration struct {
+	Pattern *regexp.Regexp
+	Loader  TypeLoader
+}
+
+// Registered returns a copy of the registered patterns in the order they are
+// matched, the most recently registered first. The fallback, used when none
+// match, is returned by Fallback.
+func (l *Loader) Registered() []Registration {
+	registered := make([]Registration, len(l.types))
+	for i, reg := range l.types {
+		registered[i] = Registration{Pattern: reg.re, Loader: reg.loader}
+	}
+	return registered
+}
+
+// Fallback returns the TypeLoader used for filenames no pattern matches, the
+// FileLoader for Loaders from NewLoader, or nil for a zero Loader
+func (l *Loader) Fallback() TypeLoader {
+	return l.fallback
 }
 
-// Load fetches a file and unmarshals into a struct. JSON, XML and YML encoding
-// supported by filename extension. Tries JSON if none match.
+// Load fetches a file and unmarshals into a struct. JSON, XML, YML, TOML and
+// .env encoding supported by filename extension, further formats can be added
+// with RegisterFormat. Tries JSON if none match. Decode errors are prefixed
+// with the filename.
+//
+// A .env file is KEY=value lines, decoded as a YAML mapping of those keys.
+// ${KEY} in a value is replaced by the value of an earlier line with that key,
+// otherwise by the environment variable, and referring to a key set on a later
+// line is an error. Single quoted values are left as they are.
+//
+// A single file can be loaded from a tar archive with archive!member, e.g.
+// bundle.tar.gz!config.yaml.
+//
+// The fields of an untagged embedded struct are decoded from the enclosing
+// object in YAML, as they are in JSON, without needing `yaml:",inline"`.
+//
+// A map[string]interface{} or map[string]json.RawMessage field of the target
+// struct tagged `loadfile:",unknown"` receives the top level keys of a JSON or
+// YAML file which no other field decodes, rather than them being ignored, e.g.
+// to pass through settings a newer version added. Only the top level is
+// captured. JSON values arrive as RawMessages as written, YAML values encoded
+// as JSON, and in the generic map both as for an interface{} field, with
+// map[string]interface{} objects. It isn't set for other formats.
+//
+// In XML, a map field tagged with a key collects repeated elements, keyed by
+// an attribute with key=@name or by the text of a child element with key=name:
+//
+//	Servers map[string]ServerConfig `xml:"server" loadfile:",key=@name"`
+//	Users   map[string]User         `xml:"users>user" loadfile:",key=login"`
+//
+// The whole element is still decoded into the value. An element without the
+// key, or repeating a key within the file, is an error. Entries are decoded
+// onto those already in the map, so a later file can override one.
+//
+// A field tagged `loadfile:"required"` must be set by the file: one still at
+// its zero value after decoding returns ErrMissingRequired, naming it by its
+// path of keys, e.g. database.host. Explicitly zero values, such as port: 0 or
+// enabled: false, can't be told apart from missing ones, so fields where zero
+// is valid should be pointers, which are only nil when missing.
+//
+// A field tagged `loadfile:"nested,yaml"`, or with any other format's
+// extension, holds a document of that format as a string, e.g. a YAML blob in
+// a JSON field, which is decoded into the field's type as a file of that
+// format would be. Errors are prefixed with the field's path of keys. A value
+// which isn't a string is decoded as usual. Nested fields are only found in
+// JSON and YAML files, and only the nested strings are decoded separately,
+// the rest of the file decoding as it is written.
+//
+// An empty file leaves into untouched and returns nil for every format, unless
+// the Loader was created WithErrorOnEmpty.
 func (l *Loader) Load(filename string, into interface{}) error {
-	reader, err := l.GetReader(filename)
+	_, err := l.LoadInfo(filename, into)
+	return err
+}
+
+// LoadInfo is Load, also returning the Info reported by the TypeLoader, e.g.
+// whether a stale cached copy was used
+func (l *Loader) LoadInfo(filename string, into interface{}) (info Info, err error) {
+	start := time.Now()
+	step := l.tracer.begin("load", filename)
+	defer func() {
+		l.stats.recordLoad(time.Since(start), err)
+		l.tracer.end(step, err)
+	}()
+	reader, info, err := l.GetReaderInfo(filename)
 	if err != nil {
-		return err
+		return info, err
 	}
 	if readCloser, ok := reader.(io.Closer); ok {
 		defer readCloser.Close()
 	}
-	fileDotParts := strings.Split(filename, ".")
-	fileExtension := fileDotParts[len(fileDotParts)-1]
-	switch strings.ToLower(fileExtension) {
-	case "json":
-		return json.NewDecoder(reader).Decode(into)
-	case "xml":
-		return xml.NewDecoder(reader).Decode(into)
-	case "yml", "yaml":
-		b, err := ioutil.ReadAll(reader)
+	if info.ModTime.IsZero() {
+		info.ModTime = readerModTime(reader)
+	}
+	if err := l.decode(filename, reader, into); err != nil {
+		return info, err
+	}
+	l.commit(filename)
+	return info, nil
+}
+
+// commit tells the TypeLoader of filename, when it is a Committer, that what
+// it fetched has decoded
+func (l *Loader) commit(filename string) {
+	filename, _ = l.splitFragment(filename)
+	filename, _ = splitMember(filename)
+	rg, err := l.getReaderGetter(filename)
+	if err != nil {
+		return
+	}
+	if committer, ok := rg.(Committer); ok {
+		// Failing to update a cache shouldn't fail a good load, the worst
+		// case is serving an older copy during the next outage
+		_ = committer.Commit(filename)
+	}
+}
+
+// LoadInto decodes r as Load would decode the content of filename, without
+// fetching anything. filename is only used to detect the format, from its
+// extensions, and in errors. r isn't closed.
+func (l *Loader) LoadInto(filename string, r io.Reader, into interface{}) error {
+	return l.decode(filename, r, into)
+}
+
+// LoadAndCapture is Load, also returning the raw bytes fetched, as they were
+// before decompression or decoding. They are captured while decoding, so they
+// are exactly what was decoded, unlike a second fetch which could differ.
+func (l *Loader) LoadAndCapture(filename string, into interface{}) ([]byte, error) {
+	reader, err := l.GetReadCloser(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	raw := &bytes.Buffer{}
+	var tee io.Reader = io.TeeReader(reader, raw)
+	if fr, ok := reader.(FormatReader); ok {
+		tee = formatReader{Reader: tee, ext: fr.FormatExtension()}
+	}
+	if err := l.decode(filename, tee, into); err != nil {
+		return nil, err
+	}
+	// decoders can stop before EOF, e.g. at trailing whitespace
+	if _, err := io.Copy(ioutil.Discard, tee); err != nil {
+		return nil, err
+	}
+	return raw.Bytes(), nil
+}
+
+func (l *Loader) decode(filename string, reader io.Reader, into interface{}) error {
+	ext, prepared, err := l.prepare(filename, reader)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer prepared.Close()
+	return l.decodeAs(filename, ext, prepared, into)
+}
+
+// decodeAs decodes a prepared reader with the decoder for ext
+func (l *Loader) decodeAs(filename, ext string, reader io.Reader, into interface{}) error {
+	decoder := l.getFormat(ext)
+	if fd := l.fragmentFormat(ext); fd != nil {
+		_, fragment := l.splitFragment(filename)
+		decoder = func(r io.Reader, into interface{}) error {
+			return fd(r, fragment, into)
+		}
+	}
+	format := l.docFormat(ext)
+	dotenv := l.stringCoercion && l.aliasedExtension(ext) == "env"
+	if dotenv {
+		decoder, format = decodeYAML, docYAML
+	}
+	if l.yamlV3 && format == docYAML {
+		decoder = l.decodeYAMLv3
+	}
+	if format == docYAML && hasUntaggedEmbedded(reflect.TypeOf(into)) {
+		decoder = embeddedDecoder(decoder)
+	}
+	if l.caseInsensitiveYAML && format == docYAML && !isMapTarget(into) {
+		decoder = caseFoldingDecoder(decoder, reflect.TypeOf(into))
+	}
+	if l.keyMap != nil {
+		decoder = keyMappingDecoder(format, decoder, l.keyMap, reflect.TypeOf(into))
+	}
+	if l.keyNormalizer != nil && isMapTarget(into) {
+		decoder = normalizingDecoder(format, decoder, l.keyNormalizer)
+	}
+	if hooks := l.typeHooks(); needsTypedDecoder(into, hooks) {
+		decoder = typedDecoder(format, decoder, hooks, l.decodeNested)
+	}
+	if l.sliceMerge == SliceAppend {
+		decoder = appendingDecoder(format, decoder)
+	}
+	if l.partialDecode {
+		decoder = partialDecoder(format, decoder)
+	}
+	if _, _, ok := captureTarget(into); ok {
+		decoder = capturingDecoder(format, decoder)
+	}
+	if l.strict {
+		decoder = l.strictDecoder(format, decoder)
+	}
+	if dotenv {
+		decoder = dotenvYAMLDecoder(l.getFormat(ext), decoder)
+	}
+	var decodeErr error
+	step := l.tracer.begin("decode", filename, "as ", ext)
+	if cr, ok := inMemory(reader); ok {
+		// Everything the decoder could have read is already at hand
+		if err := decoder(cr, into); err != nil {
+			decodeErr = newDecodeError(filename, cr.content, err)
+		}
+		l.stats.recordBytes(len(cr.content))
+	} else {
+		recorded := &recordingReader{r: reader}
+		if err := decoder(recorded, into); err != nil {
+			decodeErr = newDecodeError(filename, recorded.read.Bytes(), err)
+		}
+		l.stats.recordBytes(recorded.read.Len())
+	}
+	l.tracer.end(step, decodeErr)
+	// The keys which did decode are still worth resolving secrets in
+	var partial *MultiError
+	if decodeErr != nil && !errors.As(decodeErr, &partial) {
+		return decodeErr
+	}
+	if l.secretSchemes != nil {
+		step := l.tracer.begin("secrets", filename)
+		err := l.resolveSecrets(into)
+		l.tracer.end(step, err)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s: %w", redactURL(filename), err)
 		}
-		return yaml.Unmarshal(b, into)
 	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	if !l.skipRequired {
+		if err := checkRequired(format, into); err != nil {
+			return fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+	}
+	return afterLoad(filename, into)
+}
 
-	return json.NewDecoder(reader).Decode(into)
+// prepare runs everything which comes between fetching and decoding, such as
+// decompression and include resolution, returning the extension of the format
+// to decode with. An empty file returns ErrEmpty. The returned reader must be
+// closed, which releases any decompressor but doesn't close reader.
+func (l *Loader) prepare(filename string, reader io.Reader) (string, io.ReadCloser, error) {
+	name := l.formatName(filename, reader)
+	reader = readSmallFile(reader)
+	closers := multiCloser{}
+	if archiveName, member := splitMember(filename); member != "" {
+		extracted, err := l.extractMember(archiveName, member, reader)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		name, reader = member, extracted
+		closers = append(closers, extracted)
+	}
+
+	// formatName is the filename without any compression extension, used
+	// to find the format
+	formatName, decompressed, err := l.decompress(name, reader)
+	if err != nil {
+		closers.Close()
+		return "", nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	closers = append(multiCloser{decompressed}, closers...)
+	if formatName != name {
+		l.tracer.end(l.tracer.begin("decompress", filename, fileExtension(name)), nil)
+	}
+	ext := l.defaultExtension(filename, fileExtension(formatName))
+	prepared, err := l.prepareDecompressed(filename, ext, decompressed)
+	if err != nil {
+		closers.Close()
+		return "", nil, err
+	}
+	return ext, &readCloser{Reader: prepared, Closer: closers}, nil
 }
 
-func (l *Loader) getReaderGetter(filename string) TypeLoader {
-	for re, getter := range l.types {
-		if re.MatchString(filename) {
-			return getter
+func (l *Loader) prepareDecompressed(filename, ext string, reader io.Reader) (io.Reader, error) {
+	reader = l.transcode(ext, reader)
+	if cr, ok := reader.(*contentReader); ok {
+		if len(cr.content) == 0 {
+			return nil, ErrEmpty
 		}
+	} else {
+		buffered := bufio.NewReader(reader)
+		if _, err := buffered.Peek(1); err == io.EOF {
+			return nil, ErrEmpty
+		}
+		reader = buffered
 	}
-	return l.fallback
+
+	if l.utf8Validation && l.docFormat(ext) != "" {
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			err = validateUTF8(content)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if len(l.transforms) > 0 {
+		step := l.tracer.begin("transform", filename)
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			content, err = l.transform(content)
+		}
+		l.tracer.end(step, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.template && l.docFormat(ext) != "" {
+		step := l.tracer.begin("template", filename)
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			content, err = l.renderTemplate(filename, content)
+		}
+		l.tracer.end(step, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.envExpansion && l.docFormat(ext) != "" {
+		step := l.tracer.begin("expand", filename)
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			content, err = expandEnv(content, l.strictEnvExpansion)
+		}
+		l.tracer.end(step, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.jsonComments && l.docFormat(ext) == docJSON {
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			content, err = stripJSONComments(content)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.formatCheck {
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			err = l.checkFormat(ext, content)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.includes {
+		step := l.tracer.begin("includes", filename)
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			inc := &includer{loader: l, stack: []string{filename}}
+			content, err = inc.resolve(filename, ext, content)
+		}
+		l.tracer.end(step, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.maxDepth > 0 {
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			err = checkDepth(l.docFormat(ext), content, l.maxDepth)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.refs {
+		step := l.tracer.begin("refs", filename)
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			content, err = l.resolveRefs(filename, ext, content)
+		}
+		l.tracer.end(step, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.environment != "" {
+		step := l.tracer.begin("environment", filename, l.environment)
+		content, err := ioutil.ReadAll(reader)
+		if err == nil {
+			content, err = l.selectEnvironment(ext, content)
+		}
+		l.tracer.end(step, err)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	if l.xmlRoot != "" && l.docFormat(ext) == docXML {
+		var err error
+		reader, err = checkXMLRoot(reader, l.xmlRoot)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+	}
+
+	return reader, nil
 }
 
-func (l *Loader) GetReader(filename string) (io.Reader, error) {
-	rg := l.getReaderGetter(filename)
-	if rg == nil {
+// fileExtension returns the lower cased extension of filename, ignoring the
+// query string and fragment of http(s) URLs and the fragment of fragment
+// formats
+func fileExtension(filename string) string {
+	filename, _ = splitFragment(filename)
+	filename = unixRequestURL(filename)
+	if reHTTPFilename.MatchString(filename) {
+		if u, err := url.Parse(filename); err == nil {
+			filename = u.Path
+		}
+	}
+	fileDotParts := strings.Split(filename, ".")
+	return strings.ToLower(fileDotParts[len(fileDotParts)-1])
+}
+
+// formatHinter is implemented by TypeLoaders with filenames which don't carry
+// an extension, returning the extension of the format to use instead, or "".
+// isFormat says whether the Loader has a decoder for an extension.
+type formatHinter interface {
+	formatHint(filename string, isFormat func(ext string) bool) string
+}
+
+// FormatReader is implemented by a reader which knows the format of its
+// content, e.g. from a media type, when the filename doesn't say. It is
+// consulted before the filename.
+type FormatReader interface {
+	io.Reader
+	// FormatExtension returns the extension of the format to decode the
+	// content as, e.g. "yaml", or "" if it isn't known
+	FormatExtension() string
+}
+
+// formatReader adds a FormatExtension to a reader wrapping a FormatReader
+type formatReader struct {
+	io.Reader
+	ext string
+}
+
+func (fr formatReader) FormatExtension() string {
+	return fr.ext
+}
+
+// formatReadCloser is formatReader for an io.ReadCloser
+type formatReadCloser struct {
+	io.ReadCloser
+	ext string
+}
+
+func (fr formatReadCloser) FormatExtension() string {
+	return fr.ext
+}
+
+// formatName returns the name to detect the format from, which is the filename
+// itself unless the reader or its TypeLoader gives a hint
+func (l *Loader) formatName(filename string, reader io.Reader) string {
+	if fr, ok := reader.(FormatReader); ok {
+		if ext := fr.FormatExtension(); ext != "" {
+			return filename + "." + ext
+		}
+	}
+	if rg, err := l.getReaderGetter(filename); err == nil {
+		if hinter, ok := rg.(formatHinter); ok {
+			if ext := hinter.formatHint(filename, l.isFormat); ext != "" {
+				return filename + "." + ext
+			}
+		}
+	}
+	return filename
+}
+
+// trimExtension removes the final extension from filename, leaving the query
+// string and fragment of http(s) URLs in place
+func trimExtension(filename string) string {
+	if reHTTPFilename.MatchString(filename) {
+		if u, err := url.Parse(filename); err == nil {
+			u.Path = strings.TrimSuffix(u.Path, path.Ext(u.Path))
+			return u.String()
+		}
+	}
+	return strings.TrimSuffix(filename, path.Ext(filename))
+}
+
+func (l *Loader) getReaderGetter(filename string) (TypeLoader, error) {
+	if err := l.checkScheme(filename); err != nil {
+		return nil, err
+	}
+	for _, reg := range l.types {
+		if reg.re.MatchString(filename) {
+			return reg.loader, nil
+		}
+	}
+	if l.fallback == nil {
+		if len(l.types) == 0 {
+			return nil, ErrNoLoadersConfigured
+		}
 		return nil, ErrorNoReader
 	}
-	return rg.GetReader(filename)
+	if match := reURLScheme.FindStringSubmatch(filename); match != nil && !reFileURL.MatchString(filename) && !l.unknownSchemeFallback {
+		return nil, fmt.Errorf("%w %q: %s", ErrUnknownScheme, match[1], redactURL(filename))
+	}
+	return l.fallback, nil
+}
+
+func (l *Loader) GetReader(filename string) (io.Reader, error) {
+	filename, _ = l.splitFragment(filename)
+	filename, _ = splitMember(filename)
+	rg, err := l.getReaderGetter(filename)
+	if err != nil {
+		return nil, err
+	}
+	step := l.tracer.beginFetch(filename, rg)
+	r, err := l.fetch(rg, filename)
+	l.tracer.end(step, err)
+	return r, err
+}
+
+// GetReaderInfo is GetReader, also returning Info when the TypeLoader
+// implements InfoLoader
+func (l *Loader) GetReaderInfo(filename string) (io.Reader, Info, error) {
+	filename, _ = l.splitFragment(filename)
+	filename, _ = splitMember(filename)
+	rg, err := l.getReaderGetter(filename)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	step := l.tracer.beginFetch(filename, rg)
+	r, info, err := l.fetchInfo(rg, filename)
+	l.tracer.end(step, err)
+	return r, info, err
 }
 
 func (l *Loader) GetReadCloser(filename string) (io.ReadCloser, error) {
@@ -75,69 +708,286 @@ func (l *Loader) GetReadCloser(filename string) (io.ReadCloser, error) {
 	if readCloser, ok := r.(io.ReadCloser); ok {
 		return readCloser, nil
 	}
+	if fr, ok := r.(FormatReader); ok {
+		// Kept, as the NopCloser would hide it
+		return formatReadCloser{ReadCloser: ioutil.NopCloser(r), ext: fr.FormatExtension()}, nil
+	}
 	return ioutil.NopCloser(r), nil
 }
 
 // ErrorNoReader is returned when no loader regex matches
 var ErrorNoReader = errors.New("No Loader matched the given filename")
 
-var reS3Filename = regexp.MustCompile(`^s3:\/\/([^\/]+)\/(.*)$`)
+// ErrNoLoadersConfigured is returned for every filename by a Loader with no
+// TypeLoaders registered and no fallback, such as a zero Loader rather than
+// one from NewLoader. It matches ErrorNoReader with errors.Is.
+var ErrNoLoadersConfigured error = noLoadersError{}
+
+type noLoadersError struct{}
+
+func (noLoadersError) Error() string {
+	return "The Loader has no TypeLoaders registered nor a fallback, create it with NewLoader"
+}
+
+func (noLoadersError) Is(target error) bool {
+	return target == ErrorNoReader
+}
+
+// ErrNotFound is returned when the file does not exist. Errors from the S3 and
+// HTTP loaders for a missing object or a 404 match it with errors.Is, while
+// FileLoader returns the os error, which matches os.ErrNotExist.
+var ErrNotFound = errors.New("File not found")
+
+// isNotFound returns true if err means the file doesn't exist, as opposed to
+// failing to read or decode it
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, os.ErrNotExist)
+}
+
+// ErrSymlink is returned by a FileLoader without FollowSymlinks set when the
+// file is a symlink
+var ErrSymlink = errors.New("File is a symlink")
+
+// ErrUnsupportedFileType is returned by FileLoader for anything other than a
+// regular file, unless AllowNonRegular is set
+var ErrUnsupportedFileType = errors.New("Not a regular file")
+
+// ErrEmpty is returned for an empty file by a Loader created WithErrorOnEmpty
+var ErrEmpty = errors.New("File is empty")
+
+// ErrUnsupported is returned when the TypeLoader matching a filename doesn't
+// implement the capability an operation needs, e.g. Lister for LoadPrefix
+var ErrUnsupported = errors.New("Operation not supported by the matched Loader")
 
 // TypeLoader returns an io.Reader for the given filename. If it returns an
-// io.ReadCloser, Loader.Load will close it.
+// io.ReadCloser, Loader.Load will close it, including when decoding fails. A
+// reader wrapping something which needs closing must pass Close through, as
+// nothing else is closed.
 type TypeLoader interface {
 	GetReader(filename string) (io.Reader, error)
 }
 
-// S3Loader fetches a file from an AWS S3 bucket using default AWS credentials.
-// Supports 'shared config state', i.e., AWS_SDK_LOAD_CONFIG is forced to true,
-// meaning AWS_PROFILE works
-type S3Loader struct{}
+// ContextLoader is implemented by a TypeLoader which can stop fetching when a
+// context is done, used to apply a Loader's default timeout
+type ContextLoader interface {
+	TypeLoader
+	GetReaderContext(ctx context.Context, filename string) (io.Reader, error)
+}
 
-func (S3Loader) GetReader(filename string) (io.Reader, error) {
+// Info describes the source of a reader
+type Info struct {
+	// Stale is set when the content is a previously cached copy, served
+	// because the source itself could not be fetched
+	Stale bool
 
-	parts := reS3Filename.FindStringSubmatch(filename)
-	if len(parts) != 3 {
-		return nil, errors.New("Impossible bad match passed to S3Loader")
-	}
-	bucket := parts[1]
-	key := parts[2]
+	// ModTime is when the content was last modified, as far as the source
+	// says: the mtime of a local file, or Last-Modified from S3 or HTTP. It
+	// is zero when not known.
+	ModTime time.Time
+}
 
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	}))
+// InfoLoader is implemented by a TypeLoader which can describe the reader it
+// returns
+type InfoLoader interface {
+	TypeLoader
+	GetReaderInfo(filename string) (io.Reader, Info, error)
+}
 
-	s3Conn := s3.New(sess)
-	obj, err := s3Conn.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
+// InfoContextLoader is an InfoLoader which can also stop fetching when a
+// context is done, as a ContextLoader can
+type InfoContextLoader interface {
+	InfoLoader
+	GetReaderInfoContext(ctx context.Context, filename string) (io.Reader, Info, error)
+}
+
+// Committer is implemented by a TypeLoader which keeps what it fetches only
+// once it is known to be good, such as LastKnownGoodLoader. Commit is called
+// with the filename fetched once its content has decoded without error.
+type Committer interface {
+	Commit(filename string) error
+}
+
+// Lister is implemented by a TypeLoader which can enumerate the files under a
+// prefix. The returned filenames must be accepted by the same loader's
+// GetReader. FileLoader and S3Loader implement it.
+type Lister interface {
+	List(prefix string) ([]string, error)
+}
+
+// FileLoader blindly uses os.Open. RFC 8089 file:// URLs are converted to the
+// local path first. As the fallback of a Loader it isn't given other
+// scheme://... filenames, which return ErrUnknownScheme, unless the Loader was
+// created WithUnknownSchemeFallback.
+type FileLoader struct {
+	// FollowSymlinks opens a file which is a symlink as its target. It is set
+	// on the FileLoader of NewLoader. Without it a symlink is refused with
+	// ErrSymlink, so it can't redirect a load outside of the intended
+	// directory. The file is opened with O_NOFOLLOW, so only the final path
+	// element is checked, not its parent directories.
+	FollowSymlinks bool
+
+	// AllowNonRegular opens files which aren't regular files, such as FIFOs
+	// and devices. By default they return ErrUnsupportedFileType, as opening a
+	// FIFO blocks until something writes to it.
+	AllowNonRegular bool
+}
+
+func (fl FileLoader) GetReader(filename string) (io.Reader, error) {
+	if reFileURL.MatchString(filename) {
+		localPath, err := fileURLPath(filename)
+		if err != nil {
+			return nil, err
+		}
+		filename = localPath
+	}
+	flag := os.O_RDONLY
+	if !fl.FollowSymlinks {
+		flag |= oNoFollow
+		if oNoFollow == 0 && isSymlink(filename) {
+			return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+		}
+	}
+	if !fl.AllowNonRegular {
+		// Opening a FIFO otherwise blocks before it can be checked
+		flag |= oNonBlock
+	}
+	f, err := os.OpenFile(filename, flag, 0)
+	if err != nil && !fl.FollowSymlinks && isSymlink(filename) {
+		// Which error O_NOFOLLOW gives differs by OS, ELOOP or EMLINK
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return obj.Body, nil
+	if !fl.AllowNonRegular {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s is %s", ErrUnsupportedFileType, filename, fileTypeName(info.Mode()))
+		}
+	}
+	return f, nil
+}
+
+func isSymlink(filename string) bool {
+	info, err := os.Lstat(filename)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// WriteFile replaces filename with content atomically: content is written to a
+// temporary file in the same directory, synced, and renamed over filename, so
+// neither a reader nor a crash mid write ever sees a partial file. An existing
+// file keeps its permissions, a new one is created 0644. When filename is a
+// symlink its target is replaced and the link kept, with FollowSymlinks set,
+// or ErrSymlink returned without it.
+func (fl FileLoader) WriteFile(filename string, content []byte) error {
+	if reFileURL.MatchString(filename) {
+		localPath, err := fileURLPath(filename)
+		if err != nil {
+			return err
+		}
+		filename = localPath
+	}
+	perm := os.FileMode(0644)
+	info, err := os.Lstat(filename)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if !fl.FollowSymlinks {
+			return fmt.Errorf("%w: %s", ErrSymlink, filename)
+		}
+		// Renaming over the link would replace the link itself
+		if filename, err = filepath.EvalSymlinks(filename); err != nil {
+			return err
+		}
+		info, err = os.Stat(filename)
+	}
+	if err == nil {
+		if !info.Mode().IsRegular() && !fl.AllowNonRegular {
+			return fmt.Errorf("%w: %s is %s", ErrUnsupportedFileType, filename, fileTypeName(info.Mode()))
+		}
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileAtomic(filename, content, perm)
 }
 
-// FileLoader blindly uses os.Open
-type FileLoader struct{}
+func fileTypeName(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "a directory"
+	case mode&os.ModeNamedPipe != 0:
+		return "a named pipe"
+	case mode&os.ModeSocket != 0:
+		return "a socket"
+	case mode&os.ModeDevice != 0:
+		return "a device"
+	}
+	return "not a regular file"
+}
 
-func (FileLoader) GetReader(filename string) (io.Reader, error) {
-	return os.Open(filename)
+// List returns every regular file in the directory prefix and its
+// subdirectories, in lexical order. Names starting with a '.' are skipped,
+// including directories. With FollowSymlinks a symlink to a regular file is
+// listed, as a Kubernetes ConfigMap volume's files are, otherwise symlinks
+// are skipped. Symlinks to directories are never followed, which could loop.
+func (fl FileLoader) List(prefix string) ([]string, error) {
+	if reFileURL.MatchString(prefix) {
+		localPath, err := fileURLPath(prefix)
+		if err != nil {
+			return nil, err
+		}
+		prefix = localPath
+	}
+	filenames := []string{}
+	err := filepath.Walk(prefix, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != prefix && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 && fl.FollowSymlinks {
+			if target, err := os.Stat(path); err == nil {
+				info = target
+			}
+		}
+		if info.Mode().IsRegular() {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filenames, nil
 }
 
 // DefaultLoader implements all implemented types
-var DefaultLoader = &Loader{
-	types: map[*regexp.Regexp]TypeLoader{
-		reS3Filename: S3Loader{},
-	},
-	fallback: &FileLoader{},
-}
+var DefaultLoader = NewLoader()
 
 // Load a file into a struct, using the default loader
 func Load(filename string, into interface{}) error {
 	return DefaultLoader.Load(filename, into)
 }
 
+// LoadInto decodes r as the content of filename, using the default loader
+func LoadInto(filename string, r io.Reader, into interface{}) error {
+	return DefaultLoader.LoadInto(filename, r, into)
+}
+
+// LoadAndCapture loads a file into a struct, returning the raw bytes, using the
+// default loader
+func LoadAndCapture(filename string, into interface{}) ([]byte, error) {
+	return DefaultLoader.LoadAndCapture(filename, into)
+}
+
 func GetReader(filename string) (io.Reader, error) {
 	return DefaultLoader.GetReader(filename)
 }
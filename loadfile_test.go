@@ -0,0 +1,105 @@
+package loadfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFile writes content to name in a temporary directory of the test,
+// returning its path
+func writeTestFile(t testing.TB, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadEmpty(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "json", file: "app.json"},
+		{name: "yaml", file: "app.yaml"},
+		{name: "xml", file: "app.xml"},
+		{name: "toml", file: "app.toml"},
+		{name: "env", file: "app.env"},
+		{name: "no extension", file: "app"},
+		{name: "empty once decompressed", file: "app.json.gz", content: string(gzipped(t, ""))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct{ Name string }{Name: "untouched"}
+			if err := NewLoader().Load(filename, &into); err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != "untouched" {
+				t.Errorf("Name is %q, want it untouched", into.Name)
+			}
+			if err := NewLoader(WithErrorOnEmpty()).Load(filename, &into); !errors.Is(err, ErrEmpty) {
+				t.Errorf("WithErrorOnEmpty returned %v, want ErrEmpty", err)
+			}
+		})
+	}
+}
+
+// closeRecorder is a reader which records whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (cr *closeRecorder) Close() error {
+	cr.closed = true
+	return nil
+}
+
+func TestLoadInto(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filename string
+		content  string
+		want     string
+		wantErr  bool
+	}{
+		{name: "json", filename: "app.json", content: `{"name": "app"}`, want: "app"},
+		{name: "yaml", filename: "app.yaml", content: "name: app\n", want: "app"},
+		{name: "url", filename: "https://example.com/app.yaml?v=1", content: "name: app\n", want: "app"},
+		{name: "compressed", filename: "app.yaml.gz", content: string(gzipped(t, "name: app\n")), want: "app"},
+		{name: "no extension", filename: "app", content: `{"name": "app"}`, want: "app"},
+		{name: "decode error", filename: "app.yaml", content: "name: [\n", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Not a file, so anything fetched would fail
+			filename := filepath.Join(t.TempDir(), "missing", tc.filename)
+			if strings.Contains(tc.filename, "://") {
+				filename = tc.filename
+			}
+			reader := &closeRecorder{Reader: strings.NewReader(tc.content)}
+			into := struct{ Name string }{}
+			err := NewLoader().LoadInto(filename, reader, &into)
+			if reader.closed {
+				t.Error("the reader was closed")
+			}
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), filename) {
+					t.Fatalf("got %v, want an error naming %s", err, filename)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package loadfiletest provides helpers for testing code built on loadfile,
+// such as the resilience wrappers
+package loadfiletest
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ErrNoMoreResponses is returned by FakeLoader once every programmed response
+// has been used
+var ErrNoMoreResponses = errors.New("FakeLoader has no more responses")
+
+// FakeResponse is a single programmed result of FakeLoader.GetReader. When Err
+// is set it is returned, otherwise a reader over Body.
+type FakeResponse struct {
+	Body string
+	Err  error
+}
+
+// FakeLoader is a loadfile.TypeLoader which returns its Responses in order,
+// one per GetReader call, and records the calls made. The readers it returns
+// are io.ReadClosers, counted until they are closed, to catch leaks. It is
+// safe for concurrent use.
+type FakeLoader struct {
+	Responses []FakeResponse
+
+	mu        sync.Mutex
+	filenames []string
+	open      int
+}
+
+// fakeBody is a response body which tells its loader when it is closed
+type fakeBody struct {
+	io.Reader
+	loader *FakeLoader
+	once   sync.Once
+}
+
+func (fb *fakeBody) Close() error {
+	fb.once.Do(func() {
+		fb.loader.mu.Lock()
+		defer fb.loader.mu.Unlock()
+		fb.loader.open--
+	})
+	return nil
+}
+
+func (fl *FakeLoader) GetReader(filename string) (io.Reader, error) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	call := len(fl.filenames)
+	fl.filenames = append(fl.filenames, filename)
+	if call >= len(fl.Responses) {
+		return nil, ErrNoMoreResponses
+	}
+	resp := fl.Responses[call]
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	fl.open++
+	return &fakeBody{Reader: strings.NewReader(resp.Body), loader: fl}, nil
+}
+
+// Calls returns the number of GetReader calls made so far
+func (fl *FakeLoader) Calls() int {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return len(fl.filenames)
+}
+
+// Filenames returns the filename passed to each GetReader call, in order
+func (fl *FakeLoader) Filenames() []string {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return append([]string{}, fl.filenames...)
+}
+
+// AssertCalls fails the test unless exactly want GetReader calls were made
+func (fl *FakeLoader) AssertCalls(t testing.TB, want int) {
+	t.Helper()
+	if got := fl.Calls(); got != want {
+		t.Errorf("FakeLoader: expected %d calls, got %d", want, got)
+	}
+}
+
+// Open returns the number of readers returned which haven't been closed
+func (fl *FakeLoader) Open() int {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.open
+}
+
+// AssertClosed fails the test if any reader returned hasn't been closed
+func (fl *FakeLoader) AssertClosed(t testing.TB) {
+	t.Helper()
+	if open := fl.Open(); open != 0 {
+		t.Errorf("FakeLoader: %d readers were not closed", open)
+	}
+}
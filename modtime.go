@@ -0,0 +1,65 @@
+package loadfile
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// ModTimeReader is implemented by a reader which knows when its content was
+// last modified, e.g. from a Last-Modified header. A TypeLoader can return one
+// to report the time in Info.
+type ModTimeReader interface {
+	io.Reader
+	ModTime() time.Time
+}
+
+// LoadWithModTime is Load, also returning when the file was last modified, as
+// Info.ModTime does, so that a caller can act only on a config newer than one
+// it has, without fetching it twice. The time is zero for sources which don't
+// say.
+func (l *Loader) LoadWithModTime(filename string, into interface{}) (time.Time, error) {
+	info, err := l.LoadInfo(filename, into)
+	return info.ModTime, err
+}
+
+// LoadWithModTime loads a file, also returning when it was last modified,
+// using the default loader
+func LoadWithModTime(filename string, into interface{}) (time.Time, error) {
+	return DefaultLoader.LoadWithModTime(filename, into)
+}
+
+// modTimeReader is a body with the modification time its source gave
+type modTimeReader struct {
+	io.ReadCloser
+	modTime time.Time
+}
+
+func (r *modTimeReader) ModTime() time.Time {
+	return r.modTime
+}
+
+// readerModTime returns the modification time of what a TypeLoader returned,
+// through the readers the Loader wraps it in, or the zero time
+func readerModTime(r io.Reader) time.Time {
+	for {
+		switch wrapper := r.(type) {
+		case *os.File:
+			info, err := wrapper.Stat()
+			if err != nil {
+				return time.Time{}
+			}
+			return info.ModTime()
+		case ModTimeReader:
+			return wrapper.ModTime()
+		case *budgetReader:
+			r = wrapper.r
+		case *cancelReadCloser:
+			r = wrapper.Reader
+		case *multipartBody:
+			r = wrapper.ReadCloser
+		default:
+			return time.Time{}
+		}
+	}
+}
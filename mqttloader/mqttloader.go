@@ -0,0 +1,177 @@
+// Package mqttloader adds a loadfile.TypeLoader which reads the retained
+// message of an MQTT topic, for devices which are sent their config over a
+// message bus. Register it with a Loader:
+//
+//	l := loadfile.NewLoader()
+//	l.Register(mqttloader.Pattern, &mqttloader.MQTTLoader{Username: "device-42"})
+//	l.Load("mqtt://broker.internal/fleet/config.yaml", &cfg)
+//
+// This is kept out of the core package so that only those who need it depend
+// on an MQTT client.
+package mqttloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/daemonl/loadfile"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Pattern matches mqtt:// filenames
+var Pattern = regexp.MustCompile(`^mqtt:\/\/`)
+
+var reFilename = regexp.MustCompile(`^mqtt:\/\/([^\/]+)\/(.+)$`)
+
+// DefaultTimeout is how long MQTTLoader waits for the broker and the message
+// when its Timeout is zero
+const DefaultTimeout = 10 * time.Second
+
+// MQTTLoader fetches the retained message of a topic, named like
+// mqtt://broker/topic or mqtt://broker:port/topic, e.g.
+// mqtt://broker.internal/fleet/config.yaml. The port is 1883, or 8883 with
+// TLS, when not given. The format is taken from the extension of the last
+// level of the topic, or can be given by a Loader's AliasExtension.
+//
+// Each read connects, subscribes to the topic and returns the payload of its
+// retained message, then disconnects. Messages published live in the meantime
+// are ignored. When no retained message arrives within the timeout, or it is
+// empty, which is how a retained message is cleared, it returns
+// loadfile.ErrNotFound. Topics can't contain the wildcards + and #.
+type MQTTLoader struct {
+	// Username and Password authenticate to the broker when Username is set
+	Username string
+	Password string
+
+	// TLSConfig connects with TLS when set
+	TLSConfig *tls.Config
+
+	// ClientID identifies the connection, a random loadfile- ID when empty.
+	// Brokers disconnect the older of two connections with the same ID, so
+	// it shouldn't be shared with a long running client.
+	ClientID string
+
+	// QoS is the quality of service of the subscription, 0, 1 or 2
+	QoS byte
+
+	// Timeout limits connecting and then waiting for the message, together,
+	// DefaultTimeout when zero
+	Timeout time.Duration
+}
+
+func (ml *MQTTLoader) GetReader(filename string) (io.Reader, error) {
+	return ml.GetReaderContext(context.Background(), filename)
+}
+
+// GetReaderContext is GetReader, with ctx able to stop the wait early
+func (ml *MQTTLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	parts := reFilename.FindStringSubmatch(filename)
+	if len(parts) != 3 {
+		return nil, errors.New("MQTT filenames must be mqtt://broker/topic")
+	}
+	broker, topic := parts[1], parts[2]
+	if strings.ContainsAny(topic, "+#") {
+		return nil, fmt.Errorf("MQTT topic %s has a wildcard, only a single topic can be read", topic)
+	}
+
+	timeout := ml.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	opts, err := ml.clientOptions(broker, timeout)
+	if err != nil {
+		return nil, err
+	}
+	client := mqtt.NewClient(opts)
+	// Deferred before waiting, so a connect ctx gave up on is stopped too
+	connected := client.Connect()
+	defer client.Disconnect(250)
+	if err := wait(ctx, connected); err != nil {
+		return nil, fmt.Errorf("Connecting to MQTT broker %s: %w", broker, err)
+	}
+
+	messages := make(chan []byte, 1)
+	subscribed := client.Subscribe(topic, ml.QoS, func(_ mqtt.Client, msg mqtt.Message) {
+		if !msg.Retained() {
+			return
+		}
+		select {
+		case messages <- append([]byte{}, msg.Payload()...):
+		default:
+		}
+	})
+	if err := wait(ctx, subscribed); err != nil {
+		return nil, fmt.Errorf("Subscribing to MQTT topic %s: %w", topic, err)
+	}
+
+	select {
+	case payload := <-messages:
+		if len(payload) == 0 {
+			return nil, fmt.Errorf("%w: MQTT topic %s has an empty retained message", loadfile.ErrNotFound, topic)
+		}
+		return bytes.NewReader(payload), nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: no retained message on MQTT topic %s within %s", loadfile.ErrNotFound, topic, timeout)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (ml *MQTTLoader) clientOptions(broker string, timeout time.Duration) (*mqtt.ClientOptions, error) {
+	scheme, port := "tcp", "1883"
+	if ml.TLSConfig != nil {
+		scheme, port = "ssl", "8883"
+	}
+	if _, _, err := net.SplitHostPort(broker); err != nil {
+		broker = net.JoinHostPort(broker, port)
+	}
+
+	clientID := ml.ClientID
+	if clientID == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		clientID = "loadfile-" + hex.EncodeToString(id)
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(scheme + "://" + broker).
+		SetClientID(clientID).
+		SetCleanSession(true).
+		SetAutoReconnect(false).
+		SetConnectRetry(false).
+		SetConnectTimeout(timeout)
+	if ml.Username != "" {
+		opts.SetUsername(ml.Username)
+		opts.SetPassword(ml.Password)
+	}
+	if ml.TLSConfig != nil {
+		opts.SetTLSConfig(ml.TLSConfig)
+	}
+	return opts, nil
+}
+
+// wait waits for token to complete or ctx to end
+func wait(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,395 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// LoadPrefix lists every file under prefix (e.g. s3://bucket/tenants/) and
+// loads each into a new element appended to into, which must be a pointer to a
+// slice. The format of each file is detected from its own extension. The
+// TypeLoader matching prefix must implement Lister.
+func (l *Loader) LoadPrefix(prefix string, into interface{}) error {
+	l = l.withBudget()
+	rg, err := l.getReaderGetter(prefix)
+	if err != nil {
+		return err
+	}
+	lister, ok := rg.(Lister)
+	if !ok {
+		return ErrUnsupported
+	}
+
+	sliceVal := reflect.ValueOf(into)
+	if sliceVal.Kind() != reflect.Ptr || sliceVal.Elem().Kind() != reflect.Slice {
+		return errors.New("LoadPrefix requires a pointer to a slice")
+	}
+
+	filenames, err := lister.List(prefix)
+	if err != nil {
+		return err
+	}
+
+	slice := sliceVal.Elem()
+	elemType := slice.Type().Elem()
+	for _, filename := range filenames {
+		elem := reflect.New(elemType)
+		if err := l.Load(filename, elem.Interface()); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	sliceVal.Elem().Set(slice)
+	return nil
+}
+
+// LoadDocuments decodes each document of a multi-document YAML stream (joined
+// by ---), or a stream of concatenated JSON values, into a new element
+// appended to into, which must be a pointer to a slice. Empty documents are
+// skipped. An S3 glob such as s3://bucket/shards/*.yaml, read by an S3Loader
+// with Globs set, gives such a stream.
+func (l *Loader) LoadDocuments(filename string, into interface{}) error {
+	sliceVal := reflect.ValueOf(into)
+	if sliceVal.Kind() != reflect.Ptr || sliceVal.Elem().Kind() != reflect.Slice {
+		return errors.New("LoadDocuments requires a pointer to a slice")
+	}
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	documents, err := splitDocuments(l.docFormat(ext), content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+
+	slice := sliceVal.Elem()
+	elemType := slice.Type().Elem()
+	for _, document := range documents {
+		elem := reflect.New(elemType)
+		if err := l.decodeAs(filename, ext, bytes.NewReader(document), elem.Interface()); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	sliceVal.Elem().Set(slice)
+	return nil
+}
+
+// splitDocuments returns each non empty document in a YAML or JSON stream
+func splitDocuments(format string, content []byte) ([][]byte, error) {
+	documents := [][]byte{}
+	switch format {
+	case docYAML:
+		dec := yamlv3.NewDecoder(bytes.NewReader(content))
+		for {
+			node := &yamlv3.Node{}
+			err := dec.Decode(node)
+			if err == io.EOF {
+				return documents, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+				continue
+			}
+			document, err := yamlv3.Marshal(node)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, document)
+		}
+	case docJSON:
+		dec := json.NewDecoder(bytes.NewReader(content))
+		for {
+			raw := json.RawMessage{}
+			err := dec.Decode(&raw)
+			if err == io.EOF {
+				return documents, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, raw)
+		}
+	}
+	return nil, fmt.Errorf("Format %q can't be split into documents", format)
+}
+
+// LoadDocuments decodes each document in a stream into a slice, using the
+// default loader
+func LoadDocuments(filename string, into interface{}) error {
+	return DefaultLoader.LoadDocuments(filename, into)
+}
+
+// LoadDir merges every file under dir into into, with LoadLayered in lexical
+// order, so 99-overrides.yaml is loaded after 00-base.yaml. dir can be a local
+// directory (subdirectories included) or anything else the matching
+// TypeLoader can List, such as s3://bucket/configs/. Files whose extension
+// isn't a known format, such as a README, are skipped.
+func (l *Loader) LoadDir(dir string, into interface{}) error {
+	l = l.withBudget()
+	rg, err := l.getReaderGetter(dir)
+	if err != nil {
+		return err
+	}
+	lister, ok := rg.(Lister)
+	if !ok {
+		return ErrUnsupported
+	}
+	listed, err := lister.List(dir)
+	if err != nil {
+		return err
+	}
+	sort.Strings(listed)
+	filenames := []string{}
+	for _, filename := range listed {
+		formatName := filename
+		if _, ok := codecs[fileExtension(formatName)]; ok {
+			formatName = trimExtension(formatName)
+		}
+		if l.isFormat(fileExtension(formatName)) {
+			filenames = append(filenames, filename)
+		}
+	}
+	return l.LoadLayered(into, filenames...)
+}
+
+// LoadDir merges every file under dir, using the default loader
+func LoadDir(dir string, into interface{}) error {
+	return DefaultLoader.LoadDir(dir, into)
+}
+
+// LoadPrefix loads every file under prefix into a slice, using the default
+// loader
+func LoadPrefix(prefix string, into interface{}) error {
+	return DefaultLoader.LoadPrefix(prefix, into)
+}
+
+// LoadLayered loads each file in turn into the same target, so values set by
+// later files override earlier ones, e.g. a base config and then environment
+// overrides. Nested structs are merged field by field, but slices within a
+// file replace what was there before, unless the Loader was created
+// WithSliceMergeStrategy(SliceAppend). Maps have keys added or replaced.
+func (l *Loader) LoadLayered(into interface{}, filenames ...string) error {
+	l = l.withBudget()
+	layered := l.layered()
+	for i, filename := range filenames {
+		err := layered.traceLayer(filename, i, func() error {
+			return layered.Load(filename, into)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return l.checkLayered(into, filenames)
+}
+
+// traceLayer calls load for the file of a layered load which goes over n
+// earlier ones, traced as a layer step
+func (l *Loader) traceLayer(filename string, n int, load func() error) error {
+	detail := ""
+	if n > 0 {
+		detail = fmt.Sprintf("merged over %d earlier", n)
+	}
+	step := l.tracer.begin("layer", filename, detail)
+	err := load()
+	l.tracer.end(step, err)
+	return err
+}
+
+// layered returns a copy of l which leaves required fields unchecked, as they
+// can be set by any of the files loaded into one target, for checkLayered to
+// check once the last is loaded
+func (l *Loader) layered() *Loader {
+	layered := *l
+	layered.skipRequired = true
+	return &layered
+}
+
+// checkLayered checks the required fields of into, loaded from filenames,
+// naming their keys in the format of the last file
+func (l *Loader) checkLayered(into interface{}, filenames []string) error {
+	if l.skipRequired || len(filenames) == 0 {
+		return nil
+	}
+	last := filenames[len(filenames)-1]
+	if err := checkRequired(l.docFormat(fileExtension(last)), into); err != nil {
+		redacted := make([]string, len(filenames))
+		for i, filename := range filenames {
+			redacted[i] = redactURL(filename)
+		}
+		return fmt.Errorf("%s: %w", strings.Join(redacted, ", "), err)
+	}
+	return nil
+}
+
+// LoadWithExtraFromEnv loads base, then each of the comma separated files named
+// by the environment variable envVar, if it is set, on top as LoadLayered
+// does, e.g. APP_CONFIG_EXTRA=/run/secrets/override.yaml. Extra files which
+// don't exist are skipped, but one which fails to load is an error.
+func (l *Loader) LoadWithExtraFromEnv(base string, envVar string, into interface{}) error {
+	l = l.withBudget()
+	layered := l.layered()
+	err := layered.traceLayer(base, 0, func() error {
+		return layered.Load(base, into)
+	})
+	if err != nil {
+		return err
+	}
+	filenames := []string{base}
+	for _, extra := range strings.Split(os.Getenv(envVar), ",") {
+		extra = strings.TrimSpace(extra)
+		if extra == "" {
+			continue
+		}
+		err := layered.traceLayer(extra, len(filenames), func() error {
+			return layered.LoadOptional(extra, into)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", envVar, err)
+		}
+		filenames = append(filenames, extra)
+	}
+	return l.checkLayered(into, filenames)
+}
+
+// LoadWithExtraFromEnv loads base and the extra files named by envVar, using
+// the default loader
+func LoadWithExtraFromEnv(base string, envVar string, into interface{}) error {
+	return DefaultLoader.LoadWithExtraFromEnv(base, envVar, into)
+}
+
+// LoadManifest loads a manifest file, a list of filenames or URLs such as
+//
+//	["base.yaml", "s3://bucket/production.yaml"]
+//
+// in any format, and then each file it lists, in order, with LoadLayered.
+// Relative names are resolved against the manifest's location. This gives one
+// place to point at different sources without changing the code which loads
+// them.
+func (l *Loader) LoadManifest(manifestFile string, into interface{}) error {
+	l = l.withBudget()
+	entries := []string{}
+	if err := l.Load(manifestFile, &entries); err != nil {
+		return err
+	}
+	filenames := make([]string, len(entries))
+	for i, entry := range entries {
+		filename, err := ResolveRelative(manifestFile, entry)
+		if err != nil {
+			return fmt.Errorf("%s: %w", redactURL(manifestFile), err)
+		}
+		filenames[i] = filename
+	}
+	return l.LoadLayered(into, filenames...)
+}
+
+// LoadManifest loads the files listed in a manifest, using the default loader
+func LoadManifest(manifestFile string, into interface{}) error {
+	return DefaultLoader.LoadManifest(manifestFile, into)
+}
+
+// LoadLayeredWithSources is LoadLayered, also returning which file last set
+// each value, keyed by the dotted path of keys as they are written in the
+// files, e.g. "database.host". Slices are treated as a single value. This works
+// for formats which can be decoded into a generic document, JSON and YAML.
+func (l *Loader) LoadLayeredWithSources(into interface{}, filenames ...string) (map[string]string, error) {
+	l = l.withBudget()
+	layered := l.layered()
+	sources := map[string]string{}
+	for i, filename := range filenames {
+		err := layered.traceLayer(filename, i, func() error {
+			// Fetched and prepared once, for the generic document of its
+			// keys as well as for into
+			ext, content, err := layered.readPrepared(filename)
+			if err == ErrEmpty && !l.errorOnEmpty {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := layered.decodeAs(filename, ext, newContentReader(content), into); err != nil {
+				return err
+			}
+			doc, err := parseDocument(l.docFormat(ext), content)
+			if err != nil {
+				return fmt.Errorf("%s: %w", redactURL(filename), err)
+			}
+			for _, path := range documentPaths(doc, "") {
+				sources[path] = filename
+			}
+			layered.commit(filename)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := l.checkLayered(into, filenames); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// LoadLayered loads each file in turn into the same target, using the default
+// loader
+func LoadLayered(into interface{}, filenames ...string) error {
+	return DefaultLoader.LoadLayered(into, filenames...)
+}
+
+// WithHostname sets the hostname LoadForHost uses instead of os.Hostname
+func WithHostname(name string) Option {
+	return func(l *Loader) {
+		l.hostname = name
+	}
+}
+
+// LoadForHost loads baseDir/common.yaml, then merges baseDir/<hostname>.yaml
+// over it, as LoadLayered does, if that exists. The hostname is whatever
+// os.Hostname returns, which may be fully qualified, unless the Loader was
+// created WithHostname. baseDir can be a URL, such as s3://bucket/hosts.
+func (l *Loader) LoadForHost(baseDir string, into interface{}) error {
+	l = l.withBudget()
+	hostname := l.hostname
+	if hostname == "" {
+		var err error
+		if hostname, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+	base := strings.TrimSuffix(baseDir, "/") + "/"
+	filenames := []string{base + "common.yaml", base + hostname + ".yaml"}
+	layered := l.layered()
+	err := layered.traceLayer(filenames[0], 0, func() error {
+		return layered.Load(filenames[0], into)
+	})
+	if err != nil {
+		return err
+	}
+	err = layered.traceLayer(filenames[1], 1, func() error {
+		return layered.LoadOptional(filenames[1], into)
+	})
+	if err != nil {
+		return err
+	}
+	return l.checkLayered(into, filenames)
+}
+
+// LoadForHost loads the common and host specific files in baseDir, using the
+// default loader
+func LoadForHost(baseDir string, into interface{}) error {
+	return DefaultLoader.LoadForHost(baseDir, into)
+}
@@ -0,0 +1,160 @@
+package loadfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLayeredRequired(t *testing.T) {
+	type config struct {
+		Host string `loadfile:"required"`
+		Port int
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"common.yaml":   "port: 80\n",
+		"web1.yaml":     "host: web1\n",
+		"override.yaml": "host: extra\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := filepath.Join(dir, "common.yaml")
+	t.Setenv("LAYERED_EXTRA", filepath.Join(dir, "override.yaml"))
+	t.Setenv("LAYERED_NONE", "")
+
+	for _, tc := range []struct {
+		name     string
+		load     func(l *Loader, into *config) error
+		wantHost string
+		wantErr  bool
+	}{{
+		name: "layered",
+		load: func(l *Loader, into *config) error {
+			return l.LoadLayered(into, base, filepath.Join(dir, "web1.yaml"))
+		},
+		wantHost: "web1",
+	}, {
+		name: "layered missing",
+		load: func(l *Loader, into *config) error {
+			return l.LoadLayered(into, base)
+		},
+		wantErr: true,
+	}, {
+		name: "extra from env",
+		load: func(l *Loader, into *config) error {
+			return l.LoadWithExtraFromEnv(base, "LAYERED_EXTRA", into)
+		},
+		wantHost: "extra",
+	}, {
+		name: "extra from env missing",
+		load: func(l *Loader, into *config) error {
+			return l.LoadWithExtraFromEnv(base, "LAYERED_NONE", into)
+		},
+		wantErr: true,
+	}, {
+		name: "with sources",
+		load: func(l *Loader, into *config) error {
+			_, err := l.LoadLayeredWithSources(into, base, filepath.Join(dir, "web1.yaml"))
+			return err
+		},
+		wantHost: "web1",
+	}, {
+		name: "with sources missing",
+		load: func(l *Loader, into *config) error {
+			_, err := l.LoadLayeredWithSources(into, base)
+			return err
+		},
+		wantErr: true,
+	}, {
+		name: "for host",
+		load: func(l *Loader, into *config) error {
+			return NewLoader(WithHostname("web1")).LoadForHost(dir, into)
+		},
+		wantHost: "web1",
+	}, {
+		name: "for host missing",
+		load: func(l *Loader, into *config) error {
+			return NewLoader(WithHostname("web2")).LoadForHost(dir, into)
+		},
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := config{}
+			err := tc.load(NewLoader(), &got)
+			if tc.wantErr {
+				if !errors.Is(err, ErrMissingRequired) || !strings.Contains(err.Error(), ": host") {
+					t.Fatalf("got error %v, want ErrMissingRequired naming host", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Host != tc.wantHost || got.Port != 80 {
+				t.Errorf("got %+v, want host %s and port 80", got, tc.wantHost)
+			}
+		})
+	}
+}
+
+// countingLoader serves files from memory, counting the fetches of each
+type countingLoader struct {
+	files   map[string]string
+	fetches map[string]int
+}
+
+func (cl *countingLoader) GetReader(filename string) (io.Reader, error) {
+	cl.fetches[filename]++
+	return strings.NewReader(cl.files[filename]), nil
+}
+
+func TestLoadLayeredWithSources(t *testing.T) {
+	cl := &countingLoader{
+		files: map[string]string{
+			"mem://base.yaml":     "database:\n  host: localhost\n  port: 5432\nname: app\n",
+			"mem://override.json": `{"database": {"host": "db.internal"}, "tags": ["a", "b"]}`,
+		},
+		fetches: map[string]int{},
+	}
+	l := NewLoader()
+	if err := l.Register(regexp.MustCompile(`^mem:\/\/`), cl); err != nil {
+		t.Fatal(err)
+	}
+	got := struct {
+		Name     string
+		Database struct {
+			Host string
+			Port int
+		}
+		Tags []string
+	}{}
+	sources, err := l.LoadLayeredWithSources(&got, "mem://base.yaml", "mem://override.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Database.Host != "db.internal" || got.Database.Port != 5432 || got.Name != "app" || len(got.Tags) != 2 {
+		t.Errorf("got %+v", got)
+	}
+	for path, want := range map[string]string{
+		"name":          "mem://base.yaml",
+		"database.port": "mem://base.yaml",
+		"database.host": "mem://override.json",
+		"tags":          "mem://override.json",
+	} {
+		if sources[path] != want {
+			t.Errorf("%s: got %q, want %q", path, sources[path], want)
+		}
+	}
+	for filename, fetches := range cl.fetches {
+		if fetches != 1 {
+			t.Errorf("%s fetched %d times, want once", filename, fetches)
+		}
+	}
+}
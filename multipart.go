@@ -0,0 +1,140 @@
+package loadfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// ErrNotMultipart is returned by LoadMultipart for a file which isn't a
+// multipart body
+var ErrNotMultipart = errors.New("Not a multipart body")
+
+// LoadMultipart merges each part of a multipart body, such as a
+// multipart/mixed response from a config service bundling several documents,
+// into into, in order, as LoadLayered does with files. The format of each part
+// is given by its Content-Type, or failing that by the extension of its name.
+// The name is the name, or filename, parameter of its Content-Disposition, and
+// errors name the part as filename!name. Empty parts are skipped.
+//
+// The boundary is taken from the Content-Type of an HTTPLoader response, and
+// otherwise from the first line of the body, so a saved bundle can be loaded
+// from anywhere.
+func (l *Loader) LoadMultipart(filename string, into interface{}) error {
+	l = l.withBudget()
+	r, err := l.GetReadCloser(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	boundary := multipartBoundary(r)
+	body := bufio.NewReader(r)
+	if boundary == "" {
+		if boundary, err = sniffBoundary(body); err != nil {
+			return fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+	}
+
+	parts := multipart.NewReader(body, boundary)
+	for i := 1; ; i++ {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", redactURL(filename), err)
+		}
+		name := partName(part)
+		if name == "" {
+			name = fmt.Sprintf("part %d", i)
+		}
+		partFilename := filename + "!" + name
+		ext := partExtension(part, name)
+		prepared, err := l.prepareDecompressed(partFilename, ext, part)
+		if err == ErrEmpty && !l.errorOnEmpty {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := l.decodeAs(partFilename, ext, prepared, into); err != nil {
+			return err
+		}
+	}
+}
+
+// LoadMultipart merges each part of a multipart body, using the default
+// loader
+func LoadMultipart(filename string, into interface{}) error {
+	return DefaultLoader.LoadMultipart(filename, into)
+}
+
+// multipartBody is the body of a multipart HTTP response, with the boundary
+// from its Content-Type
+type multipartBody struct {
+	io.ReadCloser
+	boundary string
+}
+
+// multipartBoundary finds the boundary of an HTTPLoader response through the
+// readers the Loader wraps it in, or returns ""
+func multipartBoundary(r io.Reader) string {
+	for {
+		switch wrapper := r.(type) {
+		case *multipartBody:
+			return wrapper.boundary
+		case *budgetReader:
+			r = wrapper.r
+		case *cancelReadCloser:
+			r = wrapper.Reader
+		default:
+			return ""
+		}
+	}
+}
+
+// sniffBoundary reads the boundary from the first delimiter line, --boundary,
+// leaving it in body to be read again
+func sniffBoundary(body *bufio.Reader) (string, error) {
+	line, err := body.Peek(72)
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+	// Boundaries are at most 70 characters, after the --
+	if end := bytes.IndexAny(line, "\r\n"); end >= 0 {
+		line = line[:end]
+	}
+	if !bytes.HasPrefix(line, []byte("--")) || len(line) == 2 {
+		return "", ErrNotMultipart
+	}
+	return strings.TrimRight(string(line[2:]), " \t"), nil
+}
+
+// partName returns the name, or filename, in the Content-Disposition of part
+func partName(part *multipart.Part) string {
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	if name := params["name"]; name != "" {
+		return name
+	}
+	return params["filename"]
+}
+
+// partExtension returns the extension of the format of part, from its
+// Content-Type, or from its name
+func partExtension(part *multipart.Part, name string) string {
+	if contentType := part.Header.Get("Content-Type"); contentType != "" {
+		if format, err := ParseFormat(contentType); err == nil {
+			return string(format)
+		}
+	}
+	return fileExtension(name)
+}
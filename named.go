@@ -0,0 +1,54 @@
+package loadfile
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var reNamedFilename = regexp.MustCompile(`^mem:\/\/`)
+
+// RegisterNamed makes factory the source of mem://name, for sources which
+// aren't files or on the network, such as computed config, test fixtures or
+// decrypted blobs. factory is called afresh for every load, and a returned
+// reader which is an io.Closer is closed once read. The format is taken from
+// the extension of name, e.g. mem://fixture.yaml, as for a file.
+//
+// Registering a name again replaces its factory. The first call registers the
+// mem:// scheme, as Register does, but once it has RegisterNamed is safe to
+// call concurrently with loads.
+func (l *Loader) RegisterNamed(name string, factory func() (io.Reader, error)) error {
+	if err := l.checkFrozen(); err != nil {
+		return err
+	}
+	if l.named == nil {
+		named := &namedLoader{factories: map[string]func() (io.Reader, error){}}
+		if err := l.Register(reNamedFilename, named); err != nil {
+			return err
+		}
+		l.named = named
+	}
+	l.named.mu.Lock()
+	l.named.factories[name] = factory
+	l.named.mu.Unlock()
+	return nil
+}
+
+// namedLoader reads mem://name from the factory registered for name
+type namedLoader struct {
+	mu        sync.RWMutex
+	factories map[string]func() (io.Reader, error)
+}
+
+func (nl *namedLoader) GetReader(filename string) (io.Reader, error) {
+	name := strings.TrimPrefix(filename, "mem://")
+	nl.mu.RLock()
+	factory, ok := nl.factories[name]
+	nl.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: nothing registered as %s", ErrNotFound, filename)
+	}
+	return factory()
+}
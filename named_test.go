@@ -0,0 +1,67 @@
+package loadfile
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRegisterNamed(t *testing.T) {
+	calls := 0
+	factory := func() (io.Reader, error) {
+		calls++
+		return strings.NewReader("name: fixture\n"), nil
+	}
+	for _, tc := range []struct {
+		name     string
+		filename string
+		want     string
+		wantErr  error
+	}{{
+		name:     "registered",
+		filename: "mem://fixture.yaml",
+		want:     "fixture",
+	}, {
+		name:     "not registered",
+		filename: "mem://other.yaml",
+		wantErr:  ErrNotFound,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader()
+			if err := l.RegisterNamed("fixture.yaml", factory); err != nil {
+				t.Fatal(err)
+			}
+			into := struct{ Name string }{}
+			err := l.Load(tc.filename, &into)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Name != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+}
+
+func TestRegisterNamedFrozen(t *testing.T) {
+	l := NewLoader()
+	l.Freeze()
+	if err := l.RegisterNamed("fixture.yaml", func() (io.Reader, error) {
+		return strings.NewReader("{}"), nil
+	}); err == nil {
+		t.Error("registered on a frozen Loader")
+	}
+	if l.named != nil {
+		t.Error("named sources set up on a frozen Loader")
+	}
+}
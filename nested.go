@@ -0,0 +1,78 @@
+package loadfile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var nestedTypes sync.Map // reflect.Type -> bool
+
+// nestedFormat returns the extension of the format of a field tagged
+// loadfile:"nested,yaml", and whether it is tagged nested at all
+func nestedFormat(field reflect.StructField) (string, bool) {
+	options := strings.Split(field.Tag.Get("loadfile"), ",")
+	for i, option := range options {
+		if option != "nested" {
+			continue
+		}
+		if i+1 < len(options) {
+			return strings.ToLower(options[i+1]), true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// hasNested returns true if t has a nested field anywhere within it
+func hasNested(t reflect.Type) bool {
+	if found, ok := nestedTypes.Load(t); ok {
+		return found.(bool)
+	}
+	found := findNested(t, map[reflect.Type]bool{})
+	nestedTypes.Store(t, found)
+	return found
+}
+
+func findNested(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findNested(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if _, ok := nestedFormat(t.Field(i)); ok || findNested(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// needsTypedDecoder returns true if into needs typedDecoder, for hooks or
+// nested fields
+func needsTypedDecoder(into interface{}, hooks []typeHook) bool {
+	if needsHooks(into, hooks) {
+		return true
+	}
+	t := reflect.TypeOf(into)
+	return t != nil && t.Kind() == reflect.Ptr && hasNested(t.Elem())
+}
+
+// decodeNested decodes the string value of a field tagged loadfile:"nested,ext"
+// as a file of that format would be decoded, nested fields and type hooks
+// included
+func (l *Loader) decodeNested(ext, content string, into interface{}) error {
+	if !l.isFormat(ext) {
+		return fmt.Errorf("Unknown nested format %q", ext)
+	}
+	decoder := l.getFormat(ext)
+	if hooks := l.typeHooks(); needsTypedDecoder(into, hooks) {
+		decoder = typedDecoder(l.docFormat(ext), decoder, hooks, l.decodeNested)
+	}
+	return decoder(strings.NewReader(content), into)
+}
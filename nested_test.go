@@ -0,0 +1,85 @@
+package loadfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNestedFields(t *testing.T) {
+	type tool struct {
+		Port int    `json:"port" yaml:"port"`
+		Mode string `json:"mode" yaml:"mode"`
+	}
+	type config struct {
+		Tool    tool   `json:"tool" yaml:"tool" loadfile:"nested,json"`
+		Sidecar *tool  `json:"sidecar" yaml:"sidecar" loadfile:"nested,yaml"`
+		Version string `json:"version" yaml:"version"`
+		Flag    string `json:"flag" yaml:"flag"`
+	}
+
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    config
+		wantErr string
+	}{{
+		name:    "json in yaml",
+		file:    "config.yaml",
+		content: "tool: '{\"port\": 8080, \"mode\": \"on\"}'\nversion: 1.10\nflag: yes\n",
+		want:    config{Tool: tool{Port: 8080, Mode: "on"}, Version: "1.10", Flag: "yes"},
+	}, {
+		name:    "yaml in yaml block",
+		file:    "config.yaml",
+		content: "sidecar: |\n  port: 9090\n  mode: off\nversion: 1.10\n",
+		want:    config{Sidecar: &tool{Port: 9090, Mode: "off"}, Version: "1.10"},
+	}, {
+		name:    "yaml in json",
+		file:    "config.json",
+		content: `{"sidecar": "port: 9090\nmode: yes", "version": "1.10"}`,
+		want:    config{Sidecar: &tool{Port: 9090, Mode: "yes"}, Version: "1.10"},
+	}, {
+		name:    "not a string decodes as usual",
+		file:    "config.yaml",
+		content: "tool:\n  port: 1\n",
+		want:    config{Tool: tool{Port: 1}},
+	}, {
+		name:    "invalid nested content",
+		file:    "config.yaml",
+		content: "tool: '{\"port\": '\n",
+		wantErr: "tool: ",
+	}, {
+		name:    "nested type error",
+		file:    "config.json",
+		content: `{"sidecar": "port: many"}`,
+		wantErr: "sidecar: ",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := config{}
+			err := NewLoader().Load(writeTestFile(t, tc.file, tc.content), &got)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNestedNeedsFormat(t *testing.T) {
+	type config struct {
+		Tool struct{ Port int } `yaml:"tool" loadfile:"nested"`
+	}
+	err := NewLoader().Load(writeTestFile(t, "config.yaml", "tool: 'port: 1'\n"), &config{})
+	if err == nil || !strings.Contains(err.Error(), `needs a format`) {
+		t.Fatalf("got error %v, want one saying the format is needed", err)
+	}
+}
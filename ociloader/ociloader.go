@@ -0,0 +1,144 @@
+// Package ociloader adds a loadfile.TypeLoader which reads a file distributed
+// as an OCI artifact, using oras-go. Register it with a Loader:
+//
+//	l := loadfile.NewLoader()
+//	l.Register(ociloader.Pattern, &ociloader.OCILoader{})
+//	l.Load("oci://registry.example.com/configs/app:v1.2.3", &cfg)
+//
+// It is kept out of the core package because of the size of the oras-go
+// dependency.
+package ociloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/daemonl/loadfile"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// Pattern matches oci:// filenames
+var Pattern = regexp.MustCompile(`^oci:\/\/`)
+
+// OCILoader fetches the single layer of an OCI artifact. Filenames have the
+// form
+//
+//	oci://registry/repository:tag
+//	oci://registry/repository@sha256:...
+//
+// The format is taken from the layer's org.opencontainers.image.title
+// annotation (set by oras push) when it has an extension, otherwise from its
+// media type, e.g. application/yaml or application/vnd.acme.config+json.
+type OCILoader struct {
+	// Client makes the registry requests. When nil, credentials come from
+	// the Docker config ($DOCKER_CONFIG/config.json or ~/.docker/config.json)
+	// and its credential helpers, as docker login stores them.
+	Client remote.Client
+
+	// PlainHTTP uses http rather than https, for local registries
+	PlainHTTP bool
+}
+
+func (ol *OCILoader) client() (remote.Client, error) {
+	if ol.Client != nil {
+		return ol.Client, nil
+	}
+	store, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      auth.NewCache(),
+		Credential: credentials.Credential(store),
+	}, nil
+}
+
+func (ol *OCILoader) GetReader(filename string) (io.Reader, error) {
+	ref, err := registry.ParseReference(strings.TrimPrefix(filename, "oci://"))
+	if err != nil {
+		return nil, err
+	}
+	if ref.Reference == "" {
+		return nil, fmt.Errorf("OCI filename %s has no tag or digest", filename)
+	}
+	repo, err := remote.NewRepository(ref.Registry + "/" + ref.Repository)
+	if err != nil {
+		return nil, err
+	}
+	repo.PlainHTTP = ol.PlainHTTP
+	repo.Client, err = ol.client()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	_, manifestContent, err := oras.FetchBytes(ctx, repo, ref.Reference, oras.DefaultFetchBytesOptions)
+	if errors.Is(err, errdef.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", loadfile.ErrNotFound, filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(manifestContent, &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Layers) != 1 {
+		return nil, fmt.Errorf("OCI artifact %s has %d layers, expected 1", filename, len(manifest.Layers))
+	}
+	layer := manifest.Layers[0]
+
+	blob, err := repo.Fetch(ctx, layer)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+	// ReadAll checks the size and digest against the manifest
+	layerContent, err := content.ReadAll(blob, layer)
+	if err != nil {
+		return nil, err
+	}
+	return &layerReader{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(layerContent)),
+		ext:        layerFormat(layer),
+	}, nil
+}
+
+// layerReader is a loadfile.FormatReader giving the format of the layer
+type layerReader struct {
+	io.ReadCloser
+	ext string
+}
+
+func (lr *layerReader) FormatExtension() string {
+	return lr.ext
+}
+
+// layerFormat returns the extension of the layer's title, or the format of its
+// media type, or "" if neither is known
+func layerFormat(layer ocispec.Descriptor) string {
+	if ext := path.Ext(layer.Annotations[ocispec.AnnotationTitle]); ext != "" {
+		return strings.ToLower(ext[1:])
+	}
+	if format, err := loadfile.ParseFormat(layer.MediaType); err == nil {
+		return format.String()
+	}
+	return ""
+}
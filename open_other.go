@@ -0,0 +1,10 @@
+//go:build !unix
+
+package loadfile
+
+// oNoFollow and oNonBlock are zero where there is no O_NOFOLLOW or
+// O_NONBLOCK, leaving FileLoader to Lstat the path before opening it
+const (
+	oNoFollow = 0
+	oNonBlock = 0
+)
@@ -0,0 +1,13 @@
+//go:build unix
+
+package loadfile
+
+import "syscall"
+
+// oNoFollow makes opening a symlink fail, so it is checked by the open itself
+// rather than by an Lstat the path could change after. oNonBlock opens a FIFO
+// without waiting for a writer, so what was opened can be checked by fstat.
+const (
+	oNoFollow = syscall.O_NOFOLLOW
+	oNonBlock = syscall.O_NONBLOCK
+)
@@ -0,0 +1,94 @@
+package loadfile
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// LoadOptional is Load for a file which may not exist: if it doesn't, into is
+// left untouched and nil is returned. Any other error fetching the file, and
+// any error decoding it (including a missing include), is returned as usual.
+func (l *Loader) LoadOptional(filename string, into interface{}) error {
+	_, err := l.loadExisting(filename, into)
+	return err
+}
+
+// LoadWithEmbeddedDefault loads filename, or when it doesn't exist the file
+// defaultName from defaultFS, typically an embed.FS holding the default config
+// shipped in the binary. As with LoadOptional only the file itself being
+// missing falls back to the default; an external file which fails to decode
+// is an error. The default is decoded as a file named defaultName would be,
+// but includes in it are fetched through the Loader, not from defaultFS.
+func (l *Loader) LoadWithEmbeddedDefault(filename string, defaultFS fs.FS, defaultName string, into interface{}) error {
+	found, err := l.loadExisting(filename, into)
+	if found || err != nil {
+		return err
+	}
+	file, err := defaultFS.Open(defaultName)
+	if err != nil {
+		return fmt.Errorf("Opening embedded default %s: %w", defaultName, err)
+	}
+	defer file.Close()
+	return l.decode(defaultName, file, into)
+}
+
+// LoadFirst loads the first of candidates which exists, returning which one
+// it was. Candidates which don't exist are skipped, but one which exists and
+// fails to load is an error. If none exist the returned error matches
+// ErrNotFound.
+func (l *Loader) LoadFirst(into interface{}, candidates ...string) (string, error) {
+	for _, filename := range candidates {
+		found, err := l.loadExisting(filename, into)
+		if err != nil {
+			return filename, err
+		}
+		if found {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("%w: none of %s", ErrNotFound, strings.Join(redactURLs(candidates), ", "))
+}
+
+// loadExisting loads filename into into, returning false without an error if
+// the file doesn't exist. Only the file itself being missing counts, not
+// anything it refers to.
+func (l *Loader) loadExisting(filename string, into interface{}) (bool, error) {
+	reader, _, err := l.GetReaderInfo(filename)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	if readCloser, ok := reader.(io.Closer); ok {
+		defer readCloser.Close()
+	}
+	return true, l.decode(filename, reader, into)
+}
+
+// LoadOptional loads a file which may not exist, using the default loader
+func LoadOptional(filename string, into interface{}) error {
+	return DefaultLoader.LoadOptional(filename, into)
+}
+
+// LoadFirst loads the first of candidates which exists, using the default
+// loader
+func LoadFirst(into interface{}, candidates ...string) (string, error) {
+	return DefaultLoader.LoadFirst(into, candidates...)
+}
+
+func redactURLs(filenames []string) []string {
+	redacted := make([]string, len(filenames))
+	for i, filename := range filenames {
+		redacted[i] = redactURL(filename)
+	}
+	return redacted
+}
+
+// LoadWithEmbeddedDefault loads a file, falling back to a default in
+// defaultFS when it doesn't exist, using the default loader
+func LoadWithEmbeddedDefault(filename string, defaultFS fs.FS, defaultName string, into interface{}) error {
+	return DefaultLoader.LoadWithEmbeddedDefault(filename, defaultFS, defaultName, into)
+}
@@ -0,0 +1,12 @@
+package loadfile
+
+// Option configures a Loader created by NewLoader
+type Option func(*Loader)
+
+// WithErrorOnEmpty makes Load return ErrEmpty when the file has no content,
+// rather than leaving the target untouched and returning nil
+func WithErrorOnEmpty() Option {
+	return func(l *Loader) {
+		l.errorOnEmpty = true
+	}
+}
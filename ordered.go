@@ -0,0 +1,230 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// OrderedMap is a decoded object which keeps its keys in the order they were
+// written. Values are nil, bool, string, int64, float64, []interface{} or
+// *OrderedMap, so order is kept at every level.
+type OrderedMap struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+// NewOrderedMap returns an empty OrderedMap
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{Values: map[string]interface{}{}}
+}
+
+// Get returns the value for key, and whether it was present
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	v, ok := m.Values[key]
+	return v, ok
+}
+
+// Set sets the value for key, adding it after the existing keys if new
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = value
+}
+
+// MarshalJSON encodes the map as a JSON object with the keys in order
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for idx, key := range m.Keys {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		valueJSON, err := json.Marshal(m.Values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(valueJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// LoadOrdered fetches a JSON or YAML file and decodes it keeping the order of
+// keys in every object, e.g. for diffing or re-emitting without reordering.
+// The top level of the file must be an object.
+func (l *Loader) LoadOrdered(filename string) (*OrderedMap, error) {
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty {
+		return NewOrderedMap(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	switch l.docFormat(ext) {
+	case docJSON:
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		doc, err = decodeOrderedJSON(dec)
+	case docYAML:
+		root := &yamlv3.Node{}
+		if err = yamlv3.Unmarshal(content, root); err == nil {
+			doc, err = decodeOrderedNode(root)
+		}
+	default:
+		return nil, fmt.Errorf("%s: LoadOrdered supports JSON and YAML only", redactURL(filename))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+
+	m, ok := doc.(*OrderedMap)
+	if !ok {
+		return nil, fmt.Errorf("%s: top level is not an object", redactURL(filename))
+	}
+	return m, nil
+}
+
+// LoadOrdered decodes a file keeping key order, using the default loader
+func LoadOrdered(filename string) (*OrderedMap, error) {
+	return DefaultLoader.LoadOrdered(filename)
+}
+
+func decodeOrderedJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return normalizeDocument(tok), nil
+	}
+
+	switch delim {
+	case '{':
+		m := NewOrderedMap()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return nil, fmt.Errorf("Unexpected object key %v", keyTok)
+			}
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return m, nil
+
+	case '[':
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("Unexpected %v", delim)
+}
+
+func decodeOrderedNode(node *yamlv3.Node) (interface{}, error) {
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return decodeOrderedNode(node.Content[0])
+
+	case yamlv3.AliasNode:
+		return decodeOrderedNode(node.Alias)
+
+	case yamlv3.MappingNode:
+		m := NewOrderedMap()
+		merges := []*yamlv3.Node{}
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			keyNode, valueNode := node.Content[idx], node.Content[idx+1]
+			if keyNode.Tag == "!!merge" {
+				merges = append(merges, valueNode)
+				continue
+			}
+			value, err := decodeOrderedNode(valueNode)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(keyNode.Value, value)
+		}
+		// Merged keys come after the mapping's own, which take precedence
+		for _, merge := range merges {
+			if err := mergeOrderedNode(m, merge); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case yamlv3.SequenceNode:
+		list := make([]interface{}, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := decodeOrderedNode(child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalizeDocument(value), nil
+}
+
+// mergeOrderedNode applies a YAML << merge, adding keys which m doesn't
+// already have
+func mergeOrderedNode(m *OrderedMap, merge *yamlv3.Node) error {
+	sources := []*yamlv3.Node{merge}
+	if merge.Kind == yamlv3.SequenceNode {
+		sources = merge.Content
+	}
+	for _, source := range sources {
+		value, err := decodeOrderedNode(source)
+		if err != nil {
+			return err
+		}
+		merged, ok := value.(*OrderedMap)
+		if !ok {
+			return fmt.Errorf("YAML merge of a non mapping")
+		}
+		for _, key := range merged.Keys {
+			if _, exists := m.Values[key]; !exists {
+				m.Set(key, merged.Values[key])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// WithPartialDecode decodes each top level key of a JSON or YAML file into
+// the target on its own, so that one malformed section of a large aggregated
+// file doesn't stop the rest loading. The keys which decode are set, the
+// secrets in them resolved, and the keys which don't are returned together as
+// a *MultiError, within a *DecodeError, with AfterLoad not called. A file
+// which can't be parsed at all, or isn't a mapping, still fails as a whole,
+// as do the checks of WithStrict. Other formats are decoded as usual.
+func WithPartialDecode() Option {
+	return func(l *Loader) {
+		l.partialDecode = true
+	}
+}
+
+// MultiError is returned WithPartialDecode when some top level keys of a file
+// couldn't be decoded, with the error for each
+type MultiError struct {
+	Errors map[string]error
+}
+
+func (e *MultiError) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, key := range e.keys() {
+		lines = append(lines, fmt.Sprintf("%s: %s", key, e.Errors[key]))
+	}
+	noun := "keys"
+	if len(e.Errors) == 1 {
+		noun = "key"
+	}
+	return fmt.Sprintf("%d %s failed to decode:\n\t%s", len(e.Errors), noun, strings.Join(lines, "\n\t"))
+}
+
+// Unwrap returns the error of each key, in key order
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, key := range e.keys() {
+		errs = append(errs, e.Errors[key])
+	}
+	return errs
+}
+
+func (e *MultiError) keys() []string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// partialDecoder wraps decoder to decode a document one top level key at a
+// time, each as a document of its own
+func partialDecoder(format string, decoder DecoderFunc) DecoderFunc {
+	if format != docJSON && format != docYAML {
+		return decoder
+	}
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		keys, sections, err := splitTopLevel(format, content)
+		if err != nil {
+			return err
+		}
+		if keys == nil {
+			return decoder(bytes.NewReader(content), into)
+		}
+		failed := map[string]error{}
+		for i, key := range keys {
+			if err := decoder(bytes.NewReader(sections[i]), into); err != nil {
+				failed[key] = err
+			}
+		}
+		if len(failed) > 0 {
+			return &MultiError{Errors: failed}
+		}
+		return nil
+	}
+}
+
+// splitTopLevel returns each top level key of content with a document holding
+// only that key, or nil keys when content isn't a mapping
+func splitTopLevel(format string, content []byte) ([]string, [][]byte, error) {
+	if format == docJSON {
+		if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+			return nil, nil, nil
+		}
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, nil, err
+		}
+		keys := make([]string, 0, len(doc))
+		for key := range doc {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sections := make([][]byte, len(keys))
+		for i, key := range keys {
+			section, err := json.Marshal(map[string]json.RawMessage{key: doc[key]})
+			if err != nil {
+				return nil, nil, err
+			}
+			sections[i] = section
+		}
+		return keys, sections, nil
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.MappingNode {
+		return nil, nil, nil
+	}
+	mapping := doc.Content[0]
+	// A section can refer to an anchor in another, which won't be in its
+	// document
+	inlineAliases(mapping)
+	var keys []string
+	var sections [][]byte
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		section, err := yamlv3.Marshal(&yamlv3.Node{
+			Kind:    yamlv3.MappingNode,
+			Content: mapping.Content[i : i+2],
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, mapping.Content[i].Value)
+		sections = append(sections, section)
+	}
+	return keys, sections, nil
+}
+
+// inlineAliases replaces each alias under node with the node it refers to
+func inlineAliases(node *yamlv3.Node) {
+	for i, child := range node.Content {
+		if child.Kind == yamlv3.AliasNode && child.Alias != nil {
+			node.Content[i] = child.Alias
+		}
+		inlineAliases(node.Content[i])
+	}
+}
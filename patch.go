@@ -0,0 +1,539 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// LoadPatched loads base as a generic document, applies each of patchFiles to
+// it in order, and decodes the result into into. A patch which is an array is
+// an RFC 6902 JSON Patch, a list of operations such as
+//
+//	[{"op": "replace", "path": "/database/port", "value": 5433},
+//	 {"op": "remove", "path": "/features/1"}]
+//
+// and a patch which is an object an RFC 7386 JSON Merge Patch, merged into the
+// document with null removing a key. Patches can be JSON or YAML, the base
+// JSON or YAML. A failing operation, including a failed test, is an error
+// naming it and its patch file, and nothing is decoded. JSON numbers and YAML
+// scalars are kept as they were written, so values the patches don't touch
+// decode as they would from Load.
+func (l *Loader) LoadPatched(base string, patchFiles []string, into interface{}) error {
+	l = l.withBudget()
+	ext, content, err := l.readPrepared(base)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		content, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if content != nil {
+		if doc, err = parsePatchDocument(l.docFormat(ext), content); err != nil {
+			return fmt.Errorf("%s: %w", redactURL(base), err)
+		}
+	}
+
+	for _, patchFile := range patchFiles {
+		patchExt, patchContent, err := l.readPrepared(patchFile)
+		if err == ErrEmpty && !l.errorOnEmpty {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		patch, err := parsePatchDocument(l.docFormat(patchExt), patchContent)
+		if err != nil {
+			return fmt.Errorf("%s: %w", redactURL(patchFile), err)
+		}
+		if operations, ok := patch.([]interface{}); ok {
+			doc, err = applyJSONPatch(doc, operations)
+		} else {
+			doc = applyMergePatch(doc, patch)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", redactURL(patchFile), err)
+		}
+	}
+
+	patched, err := encodePatchDocument(l.docFormat(ext), doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(base), err)
+	}
+	return l.decodeAs(base, ext, bytes.NewReader(patched), into)
+}
+
+// LoadPatched loads a file with JSON Patches or Merge Patches applied, using
+// the default loader
+func LoadPatched(base string, patchFiles []string, into interface{}) error {
+	return DefaultLoader.LoadPatched(base, patchFiles, into)
+}
+
+// yamlScalar is a YAML scalar in a patched document, kept as its node so that
+// it is written back as it was
+type yamlScalar struct {
+	node *yamlv3.Node
+}
+
+func (s yamlScalar) String() string {
+	return s.node.Value
+}
+
+// value returns the scalar as yaml.v3 decodes it
+func (s yamlScalar) value() interface{} {
+	var v interface{}
+	if err := s.node.Decode(&v); err != nil {
+		return s.node.Value
+	}
+	return v
+}
+
+// MarshalJSON writes the scalar as yaml.v3 decodes it, for a YAML patch to a
+// JSON file
+func (s yamlScalar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.value())
+}
+
+// parsePatchDocument parses a base or patch into generic values, with JSON
+// numbers as json.Number and YAML scalars as yamlScalar
+func parsePatchDocument(format string, content []byte) (interface{}, error) {
+	switch format {
+	case docJSON:
+		return decodeJSONNumbers(content)
+	case docYAML:
+		root, err := parseYAMLNode(content)
+		if err != nil || root == nil {
+			return nil, err
+		}
+		if hasAlias(root) {
+			if root, err = expandedCopy(root); err != nil {
+				return nil, err
+			}
+		}
+		inlineNestedMerges(root)
+		return yamlNodeDocument(root), nil
+	}
+	return parseDocument(format, content)
+}
+
+func yamlNodeDocument(node *yamlv3.Node) interface{} {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = yamlNodeDocument(node.Content[i+1])
+		}
+		return m
+	case yamlv3.SequenceNode:
+		s := make([]interface{}, len(node.Content))
+		for i, child := range node.Content {
+			s[i] = yamlNodeDocument(child)
+		}
+		return s
+	}
+	if isNullNode(node) {
+		return nil
+	}
+	return yamlScalar{node: node}
+}
+
+// encodePatchDocument is the inverse of parsePatchDocument
+func encodePatchDocument(format string, doc interface{}) ([]byte, error) {
+	switch format {
+	case docJSON:
+		return json.Marshal(doc)
+	case docYAML:
+		node, err := documentYAMLNode(doc)
+		if err != nil {
+			return nil, err
+		}
+		return yamlv3.Marshal(node)
+	}
+	return nil, fmt.Errorf("Format %q can't be encoded generically", format)
+}
+
+// documentYAMLNode returns a node for a patched document, from a YAML file or
+// a JSON patch
+func documentYAMLNode(doc interface{}) (*yamlv3.Node, error) {
+	switch val := doc.(type) {
+	case nil:
+		return nullNode(), nil
+	case yamlScalar:
+		return copyNode(val.node), nil
+	case json.Number:
+		return &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: val.String()}, nil
+	case string:
+		return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: val}, nil
+	case map[string]interface{}:
+		node := &yamlv3.Node{Kind: yamlv3.MappingNode}
+		for _, key := range sortedKeys(val) {
+			child, err := documentYAMLNode(val[key])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yamlv3.Node{Kind: yamlv3.SequenceNode}
+		for _, item := range val {
+			child, err := documentYAMLNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	}
+	node := &yamlv3.Node{}
+	if err := node.Encode(doc); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// patchString returns an op, path or from of an operation as a string
+func patchString(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case yamlScalar:
+		if val.node.ShortTag() == "!!str" {
+			return val.node.Value, true
+		}
+	}
+	return "", false
+}
+
+// applyMergePatch merges patch into target as RFC 7386 describes
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = map[string]interface{}{}
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+		} else {
+			targetMap[key] = applyMergePatch(targetMap[key], value)
+		}
+	}
+	return targetMap
+}
+
+// applyJSONPatch applies each RFC 6902 operation to doc in turn
+func applyJSONPatch(doc interface{}, operations []interface{}) (interface{}, error) {
+	for i, raw := range operations {
+		operation, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Operation %d is not an object", i)
+		}
+		op, _ := patchString(operation["op"])
+		path, _ := patchString(operation["path"])
+		patched, err := applyOperation(doc, op, path, operation)
+		if err != nil {
+			return nil, fmt.Errorf("Operation %d (%s %s): %w", i, op, path, err)
+		}
+		doc = patched
+	}
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, op, path string, operation map[string]interface{}) (interface{}, error) {
+	if _, ok := patchString(operation["path"]); !ok {
+		return nil, errors.New("Missing path")
+	}
+	value, hasValue := operation["value"]
+	from, hasFrom := patchString(operation["from"])
+	switch op {
+	case "add", "replace", "test":
+		if !hasValue {
+			return nil, errors.New("Missing value")
+		}
+	case "move", "copy":
+		if !hasFrom {
+			return nil, errors.New("Missing from")
+		}
+	}
+
+	switch op {
+	case "add":
+		return patchAt(doc, path, func(container interface{}, token string) (interface{}, error) {
+			return addChild(container, token, value)
+		})
+	case "remove":
+		return patchAt(doc, path, removeChild)
+	case "replace":
+		return patchAt(doc, path, func(container interface{}, token string) (interface{}, error) {
+			if _, err := getChild(container, token); err != nil {
+				return nil, err
+			}
+			return setChild(container, token, value)
+		})
+	case "move":
+		if path == from || strings.HasPrefix(path, from+"/") {
+			if path == from {
+				return doc, nil
+			}
+			return nil, errors.New("Can't move a value into itself")
+		}
+		moved, err := jsonPointer(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = patchAt(doc, from, removeChild); err != nil {
+			return nil, err
+		}
+		return patchAt(doc, path, func(container interface{}, token string) (interface{}, error) {
+			return addChild(container, token, moved)
+		})
+	case "copy":
+		copied, err := jsonPointer(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return patchAt(doc, path, func(container interface{}, token string) (interface{}, error) {
+			return addChild(container, token, copyDocument(copied))
+		})
+	case "test":
+		actual, err := jsonPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalDocuments(actual, value) {
+			return nil, fmt.Errorf("Test failed, the value is %v", actual)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("Unknown op %q", op)
+}
+
+// patchAt calls fn with the container of the value path points to and the
+// last token of path, returning doc with the container fn returns in place.
+// The whole document, path "", is replaced by fn's value with an empty token
+// and no container.
+func patchAt(doc interface{}, path string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if path == "" {
+		return fn(nil, "")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("Invalid JSON pointer %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	var walk func(container interface{}, tokens []string) (interface{}, error)
+	walk = func(container interface{}, tokens []string) (interface{}, error) {
+		if len(tokens) == 1 {
+			return fn(container, tokens[0])
+		}
+		child, err := getChild(container, tokens[0])
+		if err != nil {
+			return nil, err
+		}
+		child, err = walk(child, tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		return setChild(container, tokens[0], child)
+	}
+	return walk(doc, tokens)
+}
+
+// arrayIndex parses token as an index into array, which may be one past the
+// end when allowEnd is set
+func arrayIndex(array []interface{}, token string, allowEnd bool) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > len(array) || (idx == len(array) && !allowEnd) || strconv.Itoa(idx) != token {
+		return 0, fmt.Errorf("No index %q", token)
+	}
+	return idx, nil
+}
+
+func getChild(container interface{}, token string) (interface{}, error) {
+	switch val := container.(type) {
+	case map[string]interface{}:
+		child, ok := val[token]
+		if !ok {
+			return nil, fmt.Errorf("No %q", token)
+		}
+		return child, nil
+	case []interface{}:
+		idx, err := arrayIndex(val, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return val[idx], nil
+	case nil:
+		if token == "" {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not in an object or array", token)
+}
+
+// setChild replaces an existing child of container
+func setChild(container interface{}, token string, value interface{}) (interface{}, error) {
+	switch val := container.(type) {
+	case map[string]interface{}:
+		val[token] = value
+		return val, nil
+	case []interface{}:
+		idx, err := arrayIndex(val, token, false)
+		if err != nil {
+			return nil, err
+		}
+		val[idx] = value
+		return val, nil
+	case nil:
+		if token == "" {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not in an object or array", token)
+}
+
+// addChild sets a key of an object, or inserts into an array, where - is
+// the end
+func addChild(container interface{}, token string, value interface{}) (interface{}, error) {
+	array, ok := container.([]interface{})
+	if !ok {
+		if container == nil && token != "" {
+			return nil, fmt.Errorf("%q is not in an object or array", token)
+		}
+		return setChild(container, token, value)
+	}
+	if token == "-" {
+		return append(array, value), nil
+	}
+	idx, err := arrayIndex(array, token, true)
+	if err != nil {
+		return nil, err
+	}
+	array = append(array, nil)
+	copy(array[idx+1:], array[idx:])
+	array[idx] = value
+	return array, nil
+}
+
+func removeChild(container interface{}, token string) (interface{}, error) {
+	switch val := container.(type) {
+	case map[string]interface{}:
+		if _, ok := val[token]; !ok {
+			return nil, fmt.Errorf("No %q", token)
+		}
+		delete(val, token)
+		return val, nil
+	case []interface{}:
+		idx, err := arrayIndex(val, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return append(val[:idx], val[idx+1:]...), nil
+	case nil:
+		if token == "" {
+			return nil, errors.New("Can't remove the whole document")
+		}
+	}
+	return nil, fmt.Errorf("%q is not in an object or array", token)
+}
+
+// equalDocuments compares patched documents, with numbers equal by value
+// whether they are integers or not
+func equalDocuments(a, b interface{}) bool {
+	switch aVal := a.(type) {
+	case map[string]interface{}:
+		bVal, ok := b.(map[string]interface{})
+		if !ok || len(aVal) != len(bVal) {
+			return false
+		}
+		for key, child := range aVal {
+			if other, ok := bVal[key]; !ok || !equalDocuments(child, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bVal, ok := b.([]interface{})
+		if !ok || len(aVal) != len(bVal) {
+			return false
+		}
+		for i := range aVal {
+			if !equalDocuments(aVal[i], bVal[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	// A YAML scalar is equal to a string of its text, as a string field
+	// would decode it, so that 01234 tests equal to "01234"
+	aScalar, aIsScalar := a.(yamlScalar)
+	bScalar, bIsScalar := b.(yamlScalar)
+	if aIsScalar && bIsScalar && aScalar.node.Value == bScalar.node.Value {
+		return true
+	}
+	if bString, ok := b.(string); ok && aIsScalar {
+		return aScalar.node.Value == bString
+	}
+	if aString, ok := a.(string); ok && bIsScalar {
+		return bScalar.node.Value == aString
+	}
+	if aIsScalar {
+		a = aScalar.value()
+	}
+	if bIsScalar {
+		b = bScalar.value()
+	}
+	if aNum, ok := exactNumber(a); ok {
+		bNum, ok := exactNumber(b)
+		return ok && aNum.Cmp(bNum) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// exactNumber returns a number of a patched document without rounding it, so
+// that integers beyond a float64's precision compare exactly
+func exactNumber(v interface{}) (*big.Rat, bool) {
+	switch val := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(val.String())
+	case int:
+		return new(big.Rat).SetInt64(int64(val)), true
+	case int64:
+		return new(big.Rat).SetInt64(val), true
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(val)), true
+	case float64:
+		if rat := new(big.Rat); rat.SetFloat64(val) != nil {
+			return rat, true
+		}
+	}
+	return nil, false
+}
+
+func documentNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case float64:
+		return val, true
+	}
+	return 0, false
+}
@@ -0,0 +1,98 @@
+package loadfile
+
+import (
+	"strings"
+	"testing"
+)
+
+type patchedConfig struct {
+	Version  string
+	Flag     string
+	Zip      string
+	Big      uint64
+	Port     int
+	Features []string
+}
+
+func TestLoadPatched(t *testing.T) {
+	const big = 18446744073709551615
+	for _, tc := range []struct {
+		name    string
+		base    string
+		content string
+		patches map[string]string
+		want    patchedConfig
+		wantErr string
+	}{{
+		name:    "yaml merge patch",
+		base:    "app.yaml",
+		content: "version: 1.10\nflag: yes\nzip: 01234\nbig: 18446744073709551615\nport: 80\n",
+		patches: map[string]string{"patch.yaml": "port: 443\n"},
+		want:    patchedConfig{Version: "1.10", Flag: "yes", Zip: "01234", Big: big, Port: 443},
+	}, {
+		name:    "yaml json patch",
+		base:    "app.yaml",
+		content: "version: 1.10\nzip: 01234\nfeatures: [a, b]\n",
+		patches: map[string]string{"patch.yaml": "- {op: test, path: /zip, value: \"01234\"}\n- {op: add, path: /features/-, value: on}\n- {op: add, path: /flag, value: 'no'}\n"},
+		want:    patchedConfig{Version: "1.10", Zip: "01234", Flag: "no", Features: []string{"a", "b", "on"}},
+	}, {
+		name:    "yaml base with a json patch",
+		base:    "app.yaml",
+		content: "version: 1.10\nport: 80\n",
+		patches: map[string]string{"patch.json": `[{"op": "add", "path": "/big", "value": 18446744073709551615}, {"op": "add", "path": "/flag", "value": "yes"}]`},
+		want:    patchedConfig{Version: "1.10", Flag: "yes", Big: big, Port: 80},
+	}, {
+		name:    "json merge patch",
+		base:    "app.json",
+		content: `{"version": "1.10", "big": 18446744073709551615, "port": 80, "zip": "01234"}`,
+		patches: map[string]string{"patch.json": `{"port": 443, "zip": null}`},
+		want:    patchedConfig{Version: "1.10", Big: big, Port: 443},
+	}, {
+		name:    "json patch",
+		base:    "app.json",
+		content: `{"big": 18446744073709551615, "port": 80}`,
+		patches: map[string]string{"patch.json": `[{"op": "test", "path": "/big", "value": 18446744073709551615}, {"op": "replace", "path": "/port", "value": 443}]`},
+		want:    patchedConfig{Big: big, Port: 443},
+	}, {
+		name:    "json test of a big number",
+		base:    "app.json",
+		content: `{"big": 18446744073709551615}`,
+		patches: map[string]string{"patch.json": `[{"op": "test", "path": "/big", "value": 18446744073709551614}]`},
+		wantErr: "Test failed",
+	}, {
+		name:    "json base with a yaml patch",
+		base:    "app.json",
+		content: `{"big": 18446744073709551615, "port": 80}`,
+		patches: map[string]string{"patch.yaml": "port: 443\nversion: '1.10'\n"},
+		want:    patchedConfig{Version: "1.10", Big: big, Port: 443},
+	}, {
+		name:    "failed operation",
+		base:    "app.yaml",
+		content: "port: 80\n",
+		patches: map[string]string{"patch.yaml": "- {op: remove, path: /missing}\n"},
+		wantErr: `Operation 0 (remove /missing)`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			base := writeTestFile(t, tc.base, tc.content)
+			patches := []string{}
+			for name, content := range tc.patches {
+				patches = append(patches, writeTestFile(t, name, content))
+			}
+			got := patchedConfig{}
+			err := NewLoader().LoadPatched(base, patches, &got)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want an error with %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != tc.want.Version || got.Flag != tc.want.Flag || got.Zip != tc.want.Zip ||
+				got.Big != tc.want.Big || got.Port != tc.want.Port || strings.Join(got.Features, ",") != strings.Join(tc.want.Features, ",") {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
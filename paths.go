@@ -0,0 +1,86 @@
+package loadfile
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var reURLScheme = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):\/\/`)
+
+var reFileURL = regexp.MustCompile(`^(?i)file:\/\/`)
+
+// fileURLPath converts a file:///abs/path or file://localhost/abs/path URL to
+// a local path, decoding any percent encoding
+func fileURLPath(fileURL string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return "", fmt.Errorf("File URL %s is on a remote host", fileURL)
+	}
+	localPath := u.Path
+	// file:///C:/dir/file on Windows
+	if runtime.GOOS == "windows" && len(localPath) > 2 && localPath[0] == '/' && localPath[2] == ':' {
+		localPath = localPath[1:]
+	}
+	return filepath.FromSlash(localPath), nil
+}
+
+// ResolveRelative resolves ref against the location of the file base, as
+// includes, refs and manifest entries are written relative to the file they
+// are in. Local paths are joined as paths of the OS, http and https URLs
+// resolved as links are, and anything else such as s3://bucket/key joined
+// within the key, where .. can't climb out of the bucket. References which
+// already have a scheme, and absolute local paths, are returned unchanged.
+func ResolveRelative(base, ref string) (string, error) {
+	if reURLScheme.MatchString(ref) {
+		return ref, nil
+	}
+
+	match := reURLScheme.FindStringSubmatch(base)
+	if match == nil {
+		if filepath.IsAbs(ref) {
+			return ref, nil
+		}
+		return filepath.Join(filepath.Dir(base), ref), nil
+	}
+
+	switch strings.ToLower(match[1]) {
+	case "http", "https":
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return baseURL.ResolveReference(refURL).String(), nil
+	}
+
+	// Everything else is treated like s3://bucket/key, joining within the
+	// key. Joining onto the leading / stops .. from climbing out of the
+	// bucket.
+	rest := base[len(match[0]):]
+	host, key := rest, ""
+	if slash := strings.Index(rest, "/"); slash >= 0 {
+		host, key = rest[:slash], rest[slash+1:]
+	}
+	// An access point ARN has a / of its own
+	if parts := reS3Filename.FindStringSubmatch(base); len(parts) == 3 {
+		host, key = parts[1], parts[2]
+	}
+	var joined string
+	if strings.HasPrefix(ref, "/") {
+		joined = path.Clean(ref)
+	} else {
+		joined = path.Join(path.Dir("/"+key), ref)
+	}
+	return match[0] + host + joined, nil
+}
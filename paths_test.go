@@ -0,0 +1,52 @@
+package loadfile
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveRelative(t *testing.T) {
+	local := filepath.Join("configs", "app", "main.yaml")
+	for _, tc := range []struct {
+		name string
+		base string
+		ref  string
+		want string
+	}{
+		{name: "local sibling", base: local, ref: "db.yaml", want: filepath.Join("configs", "app", "db.yaml")},
+		{name: "local subdirectory", base: local, ref: "env/prod.yaml", want: filepath.Join("configs", "app", "env", "prod.yaml")},
+		{name: "local parent", base: local, ref: "../shared.yaml", want: filepath.Join("configs", "shared.yaml")},
+		{name: "local absolute", base: local, ref: filepath.Join(string(filepath.Separator)+"etc", "app.yaml"), want: filepath.Join(string(filepath.Separator)+"etc", "app.yaml")},
+		{name: "https sibling", base: "https://host/configs/main.yaml", ref: "db.yaml", want: "https://host/configs/db.yaml"},
+		{name: "https parent", base: "https://host/configs/app/main.yaml?v=1", ref: "../shared.yaml", want: "https://host/configs/shared.yaml"},
+		{name: "https root", base: "https://host/configs/main.yaml", ref: "/db.yaml", want: "https://host/db.yaml"},
+		{name: "https above the root", base: "https://host/main.yaml", ref: "../../db.yaml", want: "https://host/db.yaml"},
+		{name: "http query", base: "http://host/main.yaml", ref: "db.yaml?v=2", want: "http://host/db.yaml?v=2"},
+		{name: "s3 sibling", base: "s3://bucket/configs/main.yaml", ref: "db.yaml", want: "s3://bucket/configs/db.yaml"},
+		{name: "s3 parent", base: "s3://bucket/configs/app/main.yaml", ref: "../shared/db.yaml", want: "s3://bucket/configs/shared/db.yaml"},
+		{name: "s3 dot segments", base: "s3://bucket/configs/main.yaml", ref: "./env/../db.yaml", want: "s3://bucket/configs/db.yaml"},
+		{name: "s3 above the bucket", base: "s3://bucket/configs/main.yaml", ref: "../../../other/db.yaml", want: "s3://bucket/other/db.yaml"},
+		{name: "s3 absolute key", base: "s3://bucket/configs/main.yaml", ref: "/db.yaml", want: "s3://bucket/db.yaml"},
+		{name: "s3 top level key", base: "s3://bucket/main.yaml", ref: "db.yaml", want: "s3://bucket/db.yaml"},
+		{
+			name: "s3 above the access point",
+			base: "s3://arn:aws:s3:us-east-1:123456789012:accesspoint/configs/app/main.yaml",
+			ref:  "../../db.yaml",
+			want: "s3://arn:aws:s3:us-east-1:123456789012:accesspoint/configs/db.yaml",
+		},
+		{name: "gs sibling", base: "gs://bucket/configs/main.yaml", ref: "db.yaml", want: "gs://bucket/configs/db.yaml"},
+		{name: "gs above the bucket", base: "gs://bucket/main.yaml", ref: "../db.yaml", want: "gs://bucket/db.yaml"},
+		{name: "ref with a scheme", base: "s3://bucket/main.yaml", ref: "https://host/db.yaml", want: "https://host/db.yaml"},
+		{name: "ref with another scheme", base: "https://host/main.yaml", ref: "s3://bucket/db.yaml", want: "s3://bucket/db.yaml"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveRelative(tc.base, tc.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package loadfile
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Keys records the keys a file set, by the dotted path of keys as they are
+// written in the file, e.g. "database.port", so an explicit null can be told
+// apart from a missing key. Decoding alone can't: both leave a pointer field
+// nil. Slices are treated as a single value.
+type Keys map[string]bool
+
+// WasSet returns true if the file had the key, whatever its value, including
+// null
+func (k Keys) WasSet(path string) bool {
+	_, ok := k[path]
+	return ok
+}
+
+// IsNull returns true if the file set the key to an explicit null
+func (k Keys) IsNull(path string) bool {
+	return k[path]
+}
+
+// LoadKeys is Load, also returning the keys the file set. This works for
+// formats which can be decoded into a generic document, JSON and YAML.
+//
+// Without it, a json.RawMessage field is nil when the key is missing and
+// "null" when it is null, as is a yaml.Node field's Tag ("!!null") when
+// decoding WithYAMLv3.
+func (l *Loader) LoadKeys(filename string, into interface{}) (Keys, error) {
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return Keys{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(l.docFormat(ext), content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	if err := l.decodeAs(filename, ext, bytes.NewReader(content), into); err != nil {
+		return nil, err
+	}
+	keys := Keys{}
+	collectKeys(keys, doc, "")
+	return keys, nil
+}
+
+func collectKeys(keys Keys, doc interface{}, prefix string) {
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key, child := range m {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		keys[path] = child == nil
+		collectKeys(keys, child, path)
+	}
+}
+
+// LoadKeys loads a file and the keys it set, using the default loader
+func LoadKeys(filename string, into interface{}) (Keys, error) {
+	return DefaultLoader.LoadKeys(filename, into)
+}
@@ -0,0 +1,92 @@
+package loadfile
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLoadKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		set     []string
+		null    []string
+		notSet  []string
+	}{{
+		name:    "json",
+		file:    "app.json",
+		content: `{"name": null, "database": {"port": 5432, "host": null}}`,
+		set:     []string{"name", "database", "database.port", "database.host"},
+		null:    []string{"name", "database.host"},
+		notSet:  []string{"other", "database.user"},
+	}, {
+		name:    "yaml",
+		file:    "app.yaml",
+		content: "name: null\ndatabase:\n  port: 5432\n  host: ~\n  user:\n",
+		set:     []string{"name", "database", "database.port", "database.host", "database.user"},
+		null:    []string{"name", "database.host", "database.user"},
+		notSet:  []string{"other"},
+	}, {
+		name:    "empty",
+		file:    "app.yaml",
+		content: "",
+		notSet:  []string{"name"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct {
+				Name     *string
+				Database struct {
+					Port *int
+					Host *string
+				}
+			}{}
+			keys, err := NewLoader().LoadKeys(filename, &into)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range tc.set {
+				if !keys.WasSet(path) {
+					t.Errorf("WasSet(%q) is false", path)
+				}
+			}
+			for _, path := range tc.null {
+				if !keys.IsNull(path) {
+					t.Errorf("IsNull(%q) is false", path)
+				}
+			}
+			for _, path := range tc.notSet {
+				if keys.WasSet(path) || keys.IsNull(path) {
+					t.Errorf("%q is set", path)
+				}
+			}
+			if keys.WasSet("database.port") && (into.Database.Port == nil || *into.Database.Port != 5432) {
+				t.Errorf("Database.Port is %v, want 5432", into.Database.Port)
+			}
+		})
+	}
+}
+
+func TestLoadRawMessageNull(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "null", content: `{"name": null}`, want: "null"},
+		{name: "missing", content: `{}`, want: ""},
+		{name: "value", content: `{"name": "app"}`, want: `"app"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, "app.json", tc.content)
+			into := struct{ Name json.RawMessage }{}
+			if err := NewLoader().Load(filename, &into); err != nil {
+				t.Fatal(err)
+			}
+			if string(into.Name) != tc.want {
+				t.Errorf("Name is %q, want %q", into.Name, tc.want)
+			}
+		})
+	}
+}
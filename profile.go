@@ -0,0 +1,130 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ErrProfileNotFound is returned by LoadProfile when the file has no section
+// for the profile
+var ErrProfileNotFound = errors.New("Profile not found")
+
+// errNotProfiles is returned for a file whose top level isn't a mapping
+var errNotProfiles = errors.New("Top level is not a mapping of profiles")
+
+// profileCommonKey is the section merged under every profile
+const profileCommonKey = "common"
+
+// LoadProfile loads a JSON or YAML file with a top level section per profile,
+// e.g. production: and staging:, decoding only the named profile's section
+// into into. A common: section, when present, is deep merged underneath the
+// profile, so the profile only needs the values which differ. A missing
+// profile returns ErrProfileNotFound. YAML is selected on yaml.v3 nodes, and
+// JSON with its numbers as written, so values decode as they would from Load.
+func (l *Loader) LoadProfile(filename, profile string, into interface{}) error {
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty {
+		return fmt.Errorf("%w: %s in empty file %s", ErrProfileNotFound, profile, redactURL(filename))
+	}
+	if err != nil {
+		return err
+	}
+	var selected []byte
+	switch format := l.docFormat(ext); format {
+	case docJSON:
+		selected, err = selectJSONProfile(content, profile)
+	case docYAML:
+		selected, err = selectYAMLProfile(content, profile)
+	default:
+		err = fmt.Errorf("Format %q can't be decoded generically", format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	if selected == nil {
+		return fmt.Errorf("%w: %s in %s", ErrProfileNotFound, profile, redactURL(filename))
+	}
+	return l.decodeAs(filename, ext, bytes.NewReader(selected), into)
+}
+
+// selectJSONProfile returns the profile's section of a JSON file, merged
+// over common, or nil when there is no such profile
+func selectJSONProfile(content []byte, profile string) ([]byte, error) {
+	doc, err := decodeJSONNumbers(content)
+	if err != nil {
+		return nil, err
+	}
+	sections, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errNotProfiles
+	}
+	selected, ok := sections[profile]
+	if !ok {
+		return nil, nil
+	}
+	if common, ok := sections[profileCommonKey].(map[string]interface{}); ok {
+		if selectedMap, ok := selected.(map[string]interface{}); ok {
+			selected = deepMerge(deepMerge(nil, common), selectedMap)
+		}
+	}
+	return json.Marshal(selected)
+}
+
+// selectYAMLProfile returns the profile's section of a YAML file, merged
+// over common, or nil when there is no such profile
+func selectYAMLProfile(content []byte, profile string) ([]byte, error) {
+	root, err := parseYAMLNode(content)
+	if err != nil {
+		return nil, err
+	}
+	if root != nil && hasAlias(root) {
+		// A section can refer to anchors elsewhere in the file, which won't
+		// be written with it
+		if root, err = expandedCopy(root); err != nil {
+			return nil, err
+		}
+	}
+	if root == nil || root.Kind != yamlv3.MappingNode {
+		return nil, errNotProfiles
+	}
+	if hasMergeKey(root) {
+		inlineMerges(root)
+	}
+	idx := mappingIndex(root, profile)
+	if idx < 0 {
+		return nil, nil
+	}
+	selected := root.Content[idx]
+	if common := mappingIndex(root, profileCommonKey); common >= 0 {
+		if root.Content[common].Kind == yamlv3.MappingNode && selected.Kind == yamlv3.MappingNode {
+			merged := copyNode(root.Content[common])
+			inlineNestedMerges(merged)
+			selected = copyNode(selected)
+			inlineNestedMerges(selected)
+			mergeNodes(merged, selected)
+			selected = merged
+		}
+	}
+	return yamlv3.Marshal(selected)
+}
+
+// inlineNestedMerges inlines the << keys of node and every mapping within
+// it, so each key can be merged on its own
+func inlineNestedMerges(node *yamlv3.Node) {
+	if node.Kind == yamlv3.MappingNode && hasMergeKey(node) {
+		inlineMerges(node)
+	}
+	for _, child := range node.Content {
+		inlineNestedMerges(child)
+	}
+}
+
+// LoadProfile decodes the named profile's section of a file, using the default
+// loader
+func LoadProfile(filename, profile string, into interface{}) error {
+	return DefaultLoader.LoadProfile(filename, profile, into)
+}
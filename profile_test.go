@@ -0,0 +1,91 @@
+package loadfile
+
+import (
+	"errors"
+	"testing"
+)
+
+type profileConfig struct {
+	Version string
+	Flag    string
+	Zip     string
+	Big     uint64
+	Host    string
+	Port    int
+}
+
+func TestLoadProfile(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		profile string
+		want    profileConfig
+		wantErr error
+	}{{
+		name:    "yaml scalars as written",
+		file:    "app.yaml",
+		content: "production:\n  version: 1.10\n  flag: yes\n  zip: 01234\n  big: 18446744073709551615\n",
+		profile: "production",
+		want:    profileConfig{Version: "1.10", Flag: "yes", Zip: "01234", Big: 18446744073709551615},
+	}, {
+		name:    "yaml merged over common",
+		file:    "app.yaml",
+		content: "common:\n  version: 1.10\n  zip: 01234\n  host: localhost\n  port: 80\nproduction:\n  host: prod\n  flag: on\n",
+		profile: "production",
+		want:    profileConfig{Version: "1.10", Flag: "on", Zip: "01234", Host: "prod", Port: 80},
+	}, {
+		name:    "yaml anchors",
+		file:    "app.yaml",
+		content: "defaults: &defaults\n  version: 1.10\n  port: 80\nproduction:\n  <<: *defaults\n  port: 443\n",
+		profile: "production",
+		want:    profileConfig{Version: "1.10", Port: 443},
+	}, {
+		name:    "yaml anchors under common",
+		file:    "app.yaml",
+		content: "base: &base\n  zip: 01234\n  port: 80\ncommon:\n  <<: *base\n  host: localhost\nproduction:\n  port: 443\n",
+		profile: "production",
+		want:    profileConfig{Zip: "01234", Host: "localhost", Port: 443},
+	}, {
+		name:    "json numbers as written",
+		file:    "app.json",
+		content: `{"common": {"version": "1.10", "big": 18446744073709551615}, "production": {"port": 443}}`,
+		profile: "production",
+		want:    profileConfig{Version: "1.10", Big: 18446744073709551615, Port: 443},
+	}, {
+		name:    "missing profile",
+		file:    "app.yaml",
+		content: "production:\n  port: 443\n",
+		profile: "staging",
+		wantErr: ErrProfileNotFound,
+	}, {
+		name:    "empty file",
+		file:    "app.json",
+		profile: "production",
+		wantErr: ErrProfileNotFound,
+	}, {
+		name:    "not a mapping",
+		file:    "app.yaml",
+		content: "- production\n",
+		profile: "production",
+		wantErr: errNotProfiles,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			got := profileConfig{}
+			err := NewLoader().LoadProfile(filename, tc.profile, &got)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
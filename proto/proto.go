@@ -0,0 +1,82 @@
+// Package proto adds protocol buffer formats to loadfile. It is imported for
+// its side effects:
+//
+//	import _ "github.com/daemonl/loadfile/proto"
+//
+// Files ending in .pb or .bin are decoded as binary protobuf. As there is no
+// reflective fallback, the target must implement proto.Message.
+//
+// Files ending in .textpb or .prototxt are decoded as protobuf text format,
+// also into a proto.Message.
+//
+// Files ending in .protojson are decoded with the protobuf JSON mapping, which
+// accepts the lowerCamelCase field names and the string forms of well known
+// types such as google.protobuf.Duration ("30s") and Timestamp, which
+// encoding/json gets wrong.
+//
+// This lives outside of the core package to keep the
+// google.golang.org/protobuf dependency out of builds which don't need it.
+package proto
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/daemonl/loadfile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func init() {
+	loadfile.RegisterFormat("pb", decodeBinary)
+	loadfile.RegisterFormat("bin", decodeBinary)
+	loadfile.RegisterFormat("textpb", decodeText)
+	loadfile.RegisterFormat("prototxt", decodeText)
+	loadfile.RegisterFormat("protojson", decodeJSON)
+}
+
+func asMessage(into interface{}) (pb.Message, error) {
+	msg, ok := into.(pb.Message)
+	if !ok {
+		return nil, fmt.Errorf("Cannot decode protobuf into %T, it is not a proto.Message", into)
+	}
+	return msg, nil
+}
+
+func decodeBinary(r io.Reader, into interface{}) error {
+	msg, err := asMessage(into)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return pb.Unmarshal(b, msg)
+}
+
+func decodeText(r io.Reader, into interface{}) error {
+	msg, err := asMessage(into)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return prototext.Unmarshal(b, msg)
+}
+
+func decodeJSON(r io.Reader, into interface{}) error {
+	msg, err := asMessage(into)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(b, msg)
+}
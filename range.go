@@ -0,0 +1,95 @@
+package loadfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// RangeLoader is implemented by a TypeLoader which can read part of a file
+// without fetching the rest
+type RangeLoader interface {
+	GetRange(filename string, offset, length int64) (io.ReadCloser, error)
+}
+
+// GetRange returns up to length bytes of the file starting at offset, or
+// everything after offset when length is negative. The content is returned as
+// stored, without decompression. The TypeLoader matching filename must
+// implement RangeLoader, as FileLoader and S3Loader do, otherwise
+// ErrUnsupported is returned.
+func (l *Loader) GetRange(filename string, offset, length int64) (io.ReadCloser, error) {
+	rg, err := l.getReaderGetter(filename)
+	if err != nil {
+		return nil, err
+	}
+	rangeLoader, ok := rg.(RangeLoader)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("Invalid range offset %d", offset)
+	}
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	return rangeLoader.GetRange(filename, offset, length)
+}
+
+// GetRange reads part of a file, using the default loader
+func GetRange(filename string, offset, length int64) (io.ReadCloser, error) {
+	return DefaultLoader.GetRange(filename, offset, length)
+}
+
+// GetRange seeks to offset in the file
+func (fl FileLoader) GetRange(filename string, offset, length int64) (io.ReadCloser, error) {
+	r, err := fl.GetReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := r.(*os.File)
+	if !ok {
+		if closer, ok := r.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, ErrUnsupported
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return &readCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+// GetRange makes a ranged GetObject request
+func (sl S3Loader) GetRange(filename string, offset, length int64) (io.ReadCloser, error) {
+	parts := reS3Filename.FindStringSubmatch(filename)
+	if len(parts) != 3 {
+		return nil, errors.New("Impossible bad match passed to S3Loader")
+	}
+	if sl.isGlob(parts[2]) {
+		return nil, ErrUnsupported
+	}
+	s3Conn, err := sl.getClient()
+	if err != nil {
+		return nil, err
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+	return sl.getObjectInput(context.Background(), s3Conn, &s3.GetObjectInput{
+		Bucket: aws.String(parts[1]),
+		Key:    aws.String(parts[2]),
+		Range:  aws.String(byteRange),
+	})
+}
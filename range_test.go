@@ -0,0 +1,51 @@
+package loadfile
+
+import (
+	"io"
+	"testing"
+)
+
+func TestGetRange(t *testing.T) {
+	filename := writeTestFile(t, "data.txt", "0123456789")
+	for _, tc := range []struct {
+		name   string
+		offset int64
+		length int64
+		want   string
+	}{{
+		name:   "middle",
+		offset: 2,
+		length: 3,
+		want:   "234",
+	}, {
+		name:   "to the end",
+		offset: 7,
+		length: -1,
+		want:   "789",
+	}, {
+		name:   "zero length",
+		offset: 4,
+		length: 0,
+		want:   "",
+	}, {
+		name:   "past the end",
+		offset: 8,
+		length: 10,
+		want:   "89",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewLoader().GetRange(filename, tc.offset, tc.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("read %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// RawSection is a top level value of a file, both decoded and as written
+type RawSection struct {
+	// Value is the decoded value, as for an interface{} field, with objects
+	// as map[string]interface{}
+	Value interface{}
+
+	// Raw is the value's bytes exactly as they are in the file, without the
+	// key, e.g. to pass on to whatever owns the section unchanged
+	Raw []byte
+}
+
+// LoadRawSections returns each top level value of a JSON or YAML object by
+// its key, decoded and with its original bytes, including formatting and
+// comments. The bytes are those decoded, after decompression and any
+// transforms or templates.
+//
+// A JSON section is the value as json.RawMessage holds it. A YAML section
+// runs from its value to the next top level key, leaving out blank lines and
+// unindented comments before that key. A nested block collection starts at
+// the beginning of its first line, so its indentation is kept and it still
+// decodes on its own, e.g. "  host: db\n  port: 5432", while a value on the
+// key's line starts where it does, e.g. "5432 # the default". A key with no
+// value has an empty section, whatever comments follow it. The top level
+// of a YAML file must be a block mapping, not a flow mapping like {a: 1}, as
+// its values can't be told apart by line.
+func (l *Loader) LoadRawSections(filename string) (map[string]RawSection, error) {
+	sections := map[string]RawSection{}
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch l.docFormat(ext) {
+	case docJSON:
+		err = rawJSONSections(content, sections)
+	case docYAML:
+		err = rawYAMLSections(content, sections)
+	default:
+		return nil, fmt.Errorf("%s: format %q can't be split into sections", redactURL(filename), ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	return sections, nil
+}
+
+// LoadRawSections returns the top level sections of a file, decoded and as
+// written, using the default loader
+func LoadRawSections(filename string) (map[string]RawSection, error) {
+	return DefaultLoader.LoadRawSections(filename)
+}
+
+func rawJSONSections(content []byte, sections map[string]RawSection) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return err
+	}
+	for key, section := range raw {
+		value, err := parseDocument(docJSON, section)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		sections[key] = RawSection{Value: value, Raw: section}
+	}
+	return nil
+}
+
+func rawYAMLSections(content []byte, sections map[string]RawSection) error {
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(content, doc); err != nil {
+		return err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yamlv3.MappingNode {
+		return errors.New("Top level is not an object")
+	}
+	root := doc.Content[0]
+	if root.Style&yamlv3.FlowStyle != 0 {
+		return errors.New("Top level is a flow mapping, which can't be split into raw sections")
+	}
+
+	lines := lineOffsets(content)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+
+		var start int
+		if (value.Kind == yamlv3.MappingNode || value.Kind == yamlv3.SequenceNode) &&
+			value.Style&yamlv3.FlowStyle == 0 && value.Line > key.Line {
+			start = lines[value.Line-1]
+		} else {
+			start = columnOffset(content, lines[value.Line-1], value.Column)
+		}
+		end := len(content)
+		if i+2 < len(root.Content) {
+			end = lines[root.Content[i+2].Line-1]
+		} else {
+			end = documentEnd(content, lines, value.Line)
+		}
+		end = trimSectionEnd(content, start, end)
+		if isNullNode(value) && value.Value == "" {
+			// Nothing was written, what follows the key is comments
+			end = start
+		}
+
+		var decoded interface{}
+		if err := value.Decode(&decoded); err != nil {
+			return fmt.Errorf("%s: %w", key.Value, err)
+		}
+		sections[key.Value] = RawSection{
+			Value: normalizeDocument(decoded),
+			Raw:   append([]byte{}, content[start:end]...),
+		}
+	}
+	return nil
+}
+
+// lineOffsets returns the offset of the start of each line of content
+func lineOffsets(content []byte) []int {
+	offsets := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// columnOffset returns the offset of the 1 based column, counted in
+// characters as yaml.v3 counts them, of the line starting at lineStart
+func columnOffset(content []byte, lineStart, column int) int {
+	offset := lineStart
+	for n := 1; n < column && offset < len(content); n++ {
+		_, size := utf8.DecodeRune(content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// documentEnd returns the offset of the --- or ... line ending the document
+// after the line it is given, or the end of content
+func documentEnd(content []byte, lines []int, after int) int {
+	for line := after; line < len(lines); line++ {
+		text := content[lines[line]:]
+		if bytes.HasPrefix(text, []byte("---")) || bytes.HasPrefix(text, []byte("...")) {
+			return lines[line]
+		}
+	}
+	return len(content)
+}
+
+// trimSectionEnd moves end, at the start of a line, back over the blank lines
+// and unindented comments which belong to what follows, then over trailing
+// whitespace, but not before start
+func trimSectionEnd(content []byte, start, end int) int {
+	for end > start {
+		lineStart := bytes.LastIndexByte(content[start:end-1], '\n') + 1 + start
+		line := bytes.TrimRight(content[lineStart:end], "\r\n")
+		if lineStart == start || (len(bytes.TrimSpace(line)) > 0 && line[0] != '#') {
+			break
+		}
+		end = lineStart
+	}
+	return start + len(bytes.TrimRight(content[start:end], " \t\r\n"))
+}
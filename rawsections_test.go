@@ -0,0 +1,80 @@
+package loadfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadRawSections(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+	}{{
+		name:    "values on the key's line",
+		file:    "app.yaml",
+		content: "port: 5432 # the default\nname: app\n",
+		want:    map[string]string{"port": "5432 # the default", "name": "app"},
+	}, {
+		name:    "nested block keeps its indentation",
+		file:    "app.yaml",
+		content: "db:\n  host: db\n  port: 5432\nname: app\n",
+		want:    map[string]string{"db": "  host: db\n  port: 5432", "name": "app"},
+	}, {
+		name:    "blank lines and unindented comments belong to the next key",
+		file:    "app.yaml",
+		content: "a: 1\n\n# about b\nb:\n  c: 2\n  # inner\n\n",
+		want:    map[string]string{"a": "1", "b": "  c: 2\n  # inner"},
+	}, {
+		name:    "block scalar keeps its blank lines",
+		file:    "app.yaml",
+		content: "a: |\n  text\n\n  more\n\nb: 1\n",
+		want:    map[string]string{"a": "|\n  text\n\n  more", "b": "1"},
+	}, {
+		name:    "sequence",
+		file:    "app.yaml",
+		content: "a:\n- 1\n- 2\n# b\nb: 1\n",
+		want:    map[string]string{"a": "- 1\n- 2", "b": "1"},
+	}, {
+		name:    "ends at the end of the document",
+		file:    "app.yaml",
+		content: "a: 1\n---\nb: 2\n",
+		want:    map[string]string{"a": "1"},
+	}, {
+		name:    "no value followed by an indented comment",
+		file:    "app.yaml",
+		content: "a:\n  # x\nb: 1\n",
+		want:    map[string]string{"a": "", "b": "1"},
+	}, {
+		name:    "no value with a comment",
+		file:    "app.yaml",
+		content: "a: # note\nb: 1\n",
+		want:    map[string]string{"a": "", "b": "1"},
+	}, {
+		name:    "explicit null",
+		file:    "app.yaml",
+		content: "a: null # c\n",
+		want:    map[string]string{"a": "null # c"},
+	}, {
+		name:    "json",
+		file:    "app.json",
+		content: `{"a": {"b":  1}, "c": [1, 2]}`,
+		want:    map[string]string{"a": `{"b":  1}`, "c": "[1, 2]"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			sections, err := NewLoader().LoadRawSections(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for key, section := range sections {
+				got[key] = string(section.Raw)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
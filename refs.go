@@ -0,0 +1,304 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ErrRefCycle is returned when a $ref refers to itself, directly or through
+// other references
+var ErrRefCycle = errors.New("Reference cycle detected")
+
+// WithRefs enables JSON Reference resolution in JSON and YAML files. An object
+// with a "$ref" string is replaced by the value the reference points to, and
+// its other keys are ignored:
+//
+//	{"database": {"$ref": "common.json#/definitions/db"}}
+//	{"replica": {"$ref": "#/database"}}
+//
+// The part before the # is a file, resolved against the referring file's
+// location and fetched through the same Loader, the part after it a JSON
+// Pointer (RFC 6901) into that file, the whole file when empty. Without a file
+// the pointer is into the referring document. References within referenced
+// values are resolved too, cycles returning ErrRefCycle. Includes, when also
+// enabled, are resolved first.
+func WithRefs() Option {
+	return func(l *Loader) {
+		l.refs = true
+	}
+}
+
+// refResolver resolves the references for a single top level load
+type refResolver struct {
+	loader *Loader
+	// documents caches every parsed document by filename
+	documents map[string]*yamlv3.Node
+	stack     []string
+	// resolved counts the references resolved
+	resolved int
+}
+
+// resolveRefs returns content with every $ref replaced, in the same format as
+// it was given. Content without a $ref, and formats other than JSON and YAML,
+// are unchanged. YAML is resolved as yaml.v3 nodes, and JSON with its numbers
+// as written, so that everything but the references is kept as it was.
+func (l *Loader) resolveRefs(filename, ext string, content []byte) ([]byte, error) {
+	format := l.docFormat(ext)
+	if (format != docJSON && format != docYAML) || !bytes.Contains(content, []byte("$ref")) {
+		return content, nil
+	}
+	// JSON is YAML, so referenced values are looked up as nodes either way
+	doc, err := parseYAMLNode(content)
+	if err != nil {
+		return nil, err
+	}
+	rr := &refResolver{
+		loader:    l,
+		documents: map[string]*yamlv3.Node{filename: doc},
+	}
+
+	if format == docJSON {
+		var generic interface{}
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&generic); err != nil {
+			return nil, err
+		}
+		generic, err = rr.walkGeneric(filename, generic)
+		if err != nil {
+			return nil, err
+		}
+		if rr.resolved == 0 {
+			return content, nil
+		}
+		return json.Marshal(generic)
+	}
+
+	root := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(content, root); err != nil {
+		return nil, err
+	}
+	if err := rr.walkNode(filename, root); err != nil {
+		return nil, err
+	}
+	if rr.resolved == 0 {
+		return content, nil
+	}
+	return yamlv3.Marshal(root)
+}
+
+// walkGeneric resolves the references in a generic JSON document
+func (rr *refResolver) walkGeneric(filename string, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := val["$ref"].(string); ok {
+			resolved, err := rr.resolve(filename, ref)
+			if err != nil {
+				return nil, err
+			}
+			return nodeDocument(resolved)
+		}
+		for key, child := range val {
+			resolved, err := rr.walkGeneric(filename, child)
+			if err != nil {
+				return nil, err
+			}
+			val[key] = resolved
+		}
+	case []interface{}:
+		for i, child := range val {
+			resolved, err := rr.walkGeneric(filename, child)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = resolved
+		}
+	}
+	return v, nil
+}
+
+// walkNode replaces each mapping under node with a $ref by the node it
+// refers to. Aliases aren't followed, as they refer to nodes walked where
+// they are anchored.
+func (rr *refResolver) walkNode(filename string, node *yamlv3.Node) error {
+	if node.Kind == yamlv3.MappingNode {
+		if i := mappingIndex(node, "$ref"); i >= 0 && node.Content[i].Kind == yamlv3.ScalarNode && node.Content[i].ShortTag() == "!!str" {
+			resolved, err := rr.resolve(filename, node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			resolved.Anchor = node.Anchor
+			*node = *resolved
+			return nil
+		}
+	}
+	for _, child := range node.Content {
+		if err := rr.walkNode(filename, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns a copy of the node ref points to from the file filename,
+// with its own references resolved
+func (rr *refResolver) resolve(filename, ref string) (*yamlv3.Node, error) {
+	target, pointer := ref, ""
+	if idx := strings.Index(ref, "#"); idx >= 0 {
+		target, pointer = ref[:idx], ref[idx+1:]
+	}
+	if target != "" {
+		resolved, err := ResolveRelative(filename, target)
+		if err != nil {
+			return nil, fmt.Errorf("$ref %s: %w", ref, err)
+		}
+		target = resolved
+	} else {
+		target = filename
+	}
+
+	key := target + "#" + pointer
+	for _, parent := range rr.stack {
+		if parent == key {
+			return nil, fmt.Errorf("%w: %s -> %s", ErrRefCycle, strings.Join(rr.stack, " -> "), key)
+		}
+	}
+	if len(rr.stack) > maxIncludeDepth {
+		return nil, fmt.Errorf("%w: more than %d levels resolving %s", ErrRefCycle, maxIncludeDepth, key)
+	}
+	rr.stack = append(rr.stack, key)
+	defer func() { rr.stack = rr.stack[:len(rr.stack)-1] }()
+
+	doc, err := rr.document(target)
+	if err != nil {
+		return nil, fmt.Errorf("$ref %s: %w", ref, err)
+	}
+	value, err := nodePointer(doc, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("$ref %s: %w", ref, err)
+	}
+	// Copied with aliases expanded, as their anchors may not be written
+	// before the copy, or at all when in another file
+	copied, err := expandedCopy(value)
+	if err != nil {
+		return nil, fmt.Errorf("$ref %s: %w", ref, err)
+	}
+	rr.resolved++
+	if err := rr.walkNode(target, copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// document returns the parsed content of filename, fetching it the first time
+func (rr *refResolver) document(filename string) (*yamlv3.Node, error) {
+	if doc, ok := rr.documents[filename]; ok {
+		return doc, nil
+	}
+	inc := &includer{loader: rr.loader}
+	_, content, err := inc.fetchContent(filename)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so either parses as a node
+	doc, err := parseYAMLNode(content)
+	if err != nil {
+		return nil, err
+	}
+	rr.documents[filename] = doc
+	return doc, nil
+}
+
+// nodePointer returns the node pointer refers to within doc
+func nodePointer(doc *yamlv3.Node, pointer string) (*yamlv3.Node, error) {
+	if doc == nil {
+		doc = nullNode()
+	}
+	if pointer == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("Invalid JSON pointer %q", pointer)
+	}
+	value := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		for value.Kind == yamlv3.AliasNode && value.Alias != nil {
+			value = value.Alias
+		}
+		switch value.Kind {
+		case yamlv3.MappingNode:
+			i := mappingIndex(value, token)
+			if i < 0 {
+				return nil, fmt.Errorf("%w: no %q in JSON pointer %q", ErrNotFound, token, pointer)
+			}
+			value = value.Content[i]
+		case yamlv3.SequenceNode:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(value.Content) {
+				return nil, fmt.Errorf("%w: no index %q in JSON pointer %q", ErrNotFound, token, pointer)
+			}
+			value = value.Content[idx]
+		default:
+			return nil, fmt.Errorf("%w: %q is not an object or array in JSON pointer %q", ErrNotFound, token, pointer)
+		}
+	}
+	return value, nil
+}
+
+// jsonPointer returns the value pointer refers to within doc
+func jsonPointer(doc interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("Invalid JSON pointer %q", pointer)
+	}
+	value := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch val := value.(type) {
+		case map[string]interface{}:
+			child, ok := val[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: no %q in JSON pointer %q", ErrNotFound, token, pointer)
+			}
+			value = child
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(val) {
+				return nil, fmt.Errorf("%w: no index %q in JSON pointer %q", ErrNotFound, token, pointer)
+			}
+			value = val[idx]
+		default:
+			return nil, fmt.Errorf("%w: %q is not an object or array in JSON pointer %q", ErrNotFound, token, pointer)
+		}
+	}
+	return value, nil
+}
+
+// copyDocument returns a deep copy of a normalized document, so a referenced
+// value can be used in more than one place
+func copyDocument(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(val))
+		for key, child := range val {
+			copied[key] = copyDocument(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(val))
+		for i, child := range val {
+			copied[i] = copyDocument(child)
+		}
+		return copied
+	}
+	return v
+}
@@ -0,0 +1,77 @@
+package loadfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveRefs(t *testing.T) {
+	type db struct {
+		Host    string `json:"host" yaml:"host"`
+		Version string `json:"version" yaml:"version"`
+	}
+	type config struct {
+		Database db     `json:"database" yaml:"database"`
+		Replica  db     `json:"replica" yaml:"replica"`
+		Flag     string `json:"flag" yaml:"flag"`
+		Big      uint64 `json:"big" yaml:"big"`
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "common.json"), []byte(`{"definitions": {"db": {"host": "db", "version": "1.10"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    config
+	}{{
+		name:    "no refs",
+		file:    "none.yaml",
+		content: "flag: yes\ndatabase: {version: 1.10}\n",
+		want:    config{Flag: "yes", Database: db{Version: "1.10"}},
+	}, {
+		name:    "yaml refs keep siblings",
+		file:    "refs.yaml",
+		content: "flag: yes\ndatabase:\n  $ref: common.json#/definitions/db\nreplica:\n  $ref: '#/database'\n",
+		want:    config{Flag: "yes", Database: db{Host: "db", Version: "1.10"}, Replica: db{Host: "db", Version: "1.10"}},
+	}, {
+		name:    "ref to an aliased value",
+		file:    "alias.yaml",
+		content: "base: &b {host: local, version: 1.10}\ndatabase: *b\nreplica: {$ref: '#/database'}\n",
+		want:    config{Database: db{Host: "local", Version: "1.10"}, Replica: db{Host: "local", Version: "1.10"}},
+	}, {
+		name:    "json refs keep numbers",
+		file:    "refs.json",
+		content: `{"big": 18446744073709551615, "database": {"$ref": "common.json#/definitions/db"}}`,
+		want:    config{Big: 18446744073709551615, Database: db{Host: "db", Version: "1.10"}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tc.file)
+			if err := os.WriteFile(filename, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got := config{}
+			if err := NewLoader(WithRefs()).Load(filename, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveRefsUnchanged(t *testing.T) {
+	content := []byte("# kept\nflag: yes # also kept\n")
+	got, err := NewLoader(WithRefs()).resolveRefs("config.yaml", "yaml", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("got %q, want the content unchanged", got)
+	}
+}
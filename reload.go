@@ -0,0 +1,191 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ErrImmutableFieldChanged is returned by Reload when a field tagged
+// reloadable:"false" would change
+var ErrImmutableFieldChanged = errors.New("Field can't be changed by a reload")
+
+// Reload loads filename onto a copy of into's current value, a pointer to a
+// struct, and stores the result in into only if no field tagged
+// `reloadable:"false"`, such as a listen address, has a different value, at
+// any depth of nested structs. Otherwise into is left unchanged and
+// ErrImmutableFieldChanged names the field, e.g. Server.Listen.
+//
+// As the file is decoded onto the current value, as Load does, parts of it
+// which the file doesn't set keep their current values.
+func (l *Loader) Reload(filename string, into interface{}) error {
+	current := reflect.ValueOf(into)
+	if current.Kind() != reflect.Ptr || current.IsNil() || current.Elem().Kind() != reflect.Struct {
+		return errors.New("Reload requires a pointer to a struct")
+	}
+	next := reflect.New(current.Elem().Type())
+	next.Elem().Set(deepCopy(current.Elem()))
+	if err := l.Load(filename, next.Interface()); err != nil {
+		return err
+	}
+	if field, changed := immutableChange(current.Elem(), next.Elem(), ""); changed {
+		return fmt.Errorf("%s: %w: %s", redactURL(filename), ErrImmutableFieldChanged, field)
+	}
+	current.Elem().Set(next.Elem())
+	return nil
+}
+
+// Reload loads filename onto into unless an immutable field changes, using
+// the default loader
+func Reload(filename string, into interface{}) error {
+	return DefaultLoader.Reload(filename, into)
+}
+
+// immutableChange returns the path of the first reloadable:"false" field
+// which differs between before and after, including those within elements of
+// slices and maps, and any within a pointer which is set or cleared
+func immutableChange(before, after reflect.Value, prefix string) (string, bool) {
+	switch before.Kind() {
+	case reflect.Ptr:
+		if before.IsNil() || after.IsNil() {
+			return prefix, before.IsNil() != after.IsNil() && hasImmutable(before.Type())
+		}
+		return immutableChange(before.Elem(), after.Elem(), prefix)
+
+	case reflect.Slice, reflect.Array:
+		if !hasImmutable(before.Type().Elem()) {
+			return "", false
+		}
+		if before.Len() != after.Len() {
+			return prefix, true
+		}
+		for i := 0; i < before.Len(); i++ {
+			if path, changed := immutableChange(before.Index(i), after.Index(i), joinPath(prefix, strconv.Itoa(i))); changed {
+				return path, true
+			}
+		}
+
+	case reflect.Map:
+		if !hasImmutable(before.Type().Elem()) {
+			return "", false
+		}
+		keys := append(before.MapKeys(), after.MapKeys()...)
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			path := joinPath(prefix, fmt.Sprint(key.Interface()))
+			beforeElem, afterElem := before.MapIndex(key), after.MapIndex(key)
+			if !beforeElem.IsValid() || !afterElem.IsValid() {
+				return path, true
+			}
+			if path, changed := immutableChange(beforeElem, afterElem, path); changed {
+				return path, true
+			}
+		}
+
+	case reflect.Struct:
+		for i := 0; i < before.NumField(); i++ {
+			field := before.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			path := joinPath(prefix, field.Name)
+			if field.Tag.Get("reloadable") == "false" {
+				if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+					return path, true
+				}
+				continue
+			}
+			if path, changed := immutableChange(before.Field(i), after.Field(i), path); changed {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+var immutableTypes sync.Map // reflect.Type -> bool
+
+// hasImmutable returns true if t has a reloadable:"false" field anywhere
+// within it
+func hasImmutable(t reflect.Type) bool {
+	if found, ok := immutableTypes.Load(t); ok {
+		return found.(bool)
+	}
+	found := findImmutable(t, map[reflect.Type]bool{})
+	immutableTypes.Store(t, found)
+	return found
+}
+
+func findImmutable(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findImmutable(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if field.Tag.Get("reloadable") == "false" || findImmutable(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deepCopy copies v so that decoding into the copy, which can add to maps and
+// write through pointers, leaves v as it was
+func deepCopy(v reflect.Value) reflect.Value {
+	copied := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			elem := reflect.New(v.Type().Elem())
+			elem.Elem().Set(deepCopy(v.Elem()))
+			copied.Set(elem)
+		}
+	case reflect.Struct:
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			copied.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+			}
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			copied.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				copied.Index(i).Set(deepCopy(v.Index(i)))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			copied.Set(deepCopy(v.Elem()))
+		}
+	default:
+		copied.Set(v)
+	}
+	return copied
+}
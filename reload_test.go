@@ -0,0 +1,96 @@
+package loadfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type reloadServer struct {
+	Name   string
+	Listen string `reloadable:"false"`
+}
+
+type reloadConfig struct {
+	Level   string
+	Listen  string `reloadable:"false"`
+	Admin   *reloadServer
+	Servers []reloadServer
+	ByName  map[string]reloadServer
+}
+
+func TestReload(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		before    string
+		after     string
+		wantField string
+	}{{
+		name:   "mutable field",
+		before: `{"Level": "info", "Listen": ":80"}`,
+		after:  `{"Level": "debug", "Listen": ":80"}`,
+	}, {
+		name:      "immutable field",
+		before:    `{"Listen": ":80"}`,
+		after:     `{"Listen": ":81"}`,
+		wantField: "Listen",
+	}, {
+		name:      "pointer set",
+		before:    `{}`,
+		after:     `{"Admin": {"Listen": ":9000"}}`,
+		wantField: "Admin",
+	}, {
+		name:      "within a pointer",
+		before:    `{"Admin": {"Listen": ":9000"}}`,
+		after:     `{"Admin": {"Listen": ":9001"}}`,
+		wantField: "Admin.Listen",
+	}, {
+		name:      "slice element",
+		before:    `{"Servers": [{"Name": "a", "Listen": ":80"}]}`,
+		after:     `{"Servers": [{"Name": "a", "Listen": ":81"}]}`,
+		wantField: "Servers.0.Listen",
+	}, {
+		name:   "mutable field of a slice element",
+		before: `{"Servers": [{"Name": "a", "Listen": ":80"}]}`,
+		after:  `{"Servers": [{"Name": "b", "Listen": ":80"}]}`,
+	}, {
+		name:      "slice element added",
+		before:    `{"Servers": [{"Listen": ":80"}]}`,
+		after:     `{"Servers": [{"Listen": ":80"}, {"Listen": ":81"}]}`,
+		wantField: "Servers",
+	}, {
+		name:      "map element",
+		before:    `{"ByName": {"a": {"Listen": ":80"}}}`,
+		after:     `{"ByName": {"a": {"Listen": ":81"}}}`,
+		wantField: "ByName.a.Listen",
+	}, {
+		name:      "map element added",
+		before:    `{"ByName": {"a": {"Listen": ":80"}}}`,
+		after:     `{"ByName": {"b": {"Listen": ":81"}}}`,
+		wantField: "ByName.b",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &reloadConfig{}
+			if err := Load(writeTestFile(t, "before.json", tc.before), config); err != nil {
+				t.Fatal(err)
+			}
+			loaded := *config
+			err := Reload(writeTestFile(t, "after.json", tc.after), config)
+			if tc.wantField == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrImmutableFieldChanged) {
+				t.Fatalf("got error %v, want ErrImmutableFieldChanged", err)
+			}
+			if want := ErrImmutableFieldChanged.Error() + ": " + tc.wantField; !strings.HasSuffix(err.Error(), want) {
+				t.Errorf("got %q, want it to end %q", err, want)
+			}
+			if config.Listen != loaded.Listen || config.Admin != loaded.Admin {
+				t.Errorf("config changed despite the error")
+			}
+		})
+	}
+}
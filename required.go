@@ -0,0 +1,119 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrMissingRequired is returned when a field tagged loadfile:"required" is
+// still its zero value after decoding, naming each such field
+var ErrMissingRequired = errors.New("Missing required field")
+
+var requiredTypes sync.Map // reflect.Type -> bool
+
+// isRequired returns true for a field tagged loadfile:"required", alone or
+// among other options
+func isRequired(field reflect.StructField) bool {
+	for _, option := range strings.Split(field.Tag.Get("loadfile"), ",") {
+		if option == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRequired returns true if t has a required field anywhere within it
+func hasRequired(t reflect.Type) bool {
+	if found, ok := requiredTypes.Load(t); ok {
+		return found.(bool)
+	}
+	found := findRequired(t, map[reflect.Type]bool{})
+	requiredTypes.Store(t, found)
+	return found
+}
+
+func findRequired(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findRequired(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if isRequired(t.Field(i)) || findRequired(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkRequired returns ErrMissingRequired listing the path of every required
+// field of into left zero, named by its keys in format
+func checkRequired(format string, into interface{}) error {
+	v := reflect.ValueOf(into)
+	if !v.IsValid() || !hasRequired(v.Type()) {
+		return nil
+	}
+	missing := []string{}
+	findMissing(format, v, "", &missing)
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrMissingRequired, strings.Join(missing, ", "))
+}
+
+func findMissing(format string, v reflect.Value, path string, missing *[]string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			findMissing(format, v.Elem(), path, missing)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			findMissing(format, v.Index(i), joinPath(path, fmt.Sprint(i)), missing)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, key := range keys {
+			findMissing(format, v.MapIndex(key), joinPath(path, fmt.Sprint(key)), missing)
+		}
+	case reflect.Struct:
+		if !hasRequired(v.Type()) {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			fieldPath := joinPath(path, decodedKeyName(format, field))
+			if isRequired(field) && v.Field(i).IsZero() {
+				*missing = append(*missing, fieldPath)
+				continue
+			}
+			// The fields of an embedded struct are decoded as the
+			// enclosing struct's own
+			if field.Anonymous {
+				fieldPath = path
+			}
+			findMissing(format, v.Field(i), fieldPath, missing)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,118 @@
+package loadfile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// RetryLoader wraps another TypeLoader, retrying failed calls with exponential
+// backoff. When the error is (or wraps) a RetryAfterError, such as a 429 or
+// 503 from HTTPLoader, the server's requested delay is used instead of the
+// backoff. Errors which another attempt can't fix aren't retried: ErrNotFound,
+// schemes which aren't allowed or known, and HTTP or S3 client errors such as
+// 401 and 403, other than 408 Request Timeout and 429 Too Many Requests.
+type RetryLoader struct {
+	Inner TypeLoader
+
+	// Attempts is the total number of calls made before giving up, 3 if zero
+	Attempts int
+
+	// Backoff is the delay before the first retry, doubling each time after.
+	// 100ms if zero.
+	Backoff time.Duration
+
+	// MaxDelay caps any single delay, including those requested by
+	// Retry-After. Unlimited if zero.
+	MaxDelay time.Duration
+}
+
+func (rl *RetryLoader) GetReader(filename string) (io.Reader, error) {
+	return rl.GetReaderContext(context.Background(), filename)
+}
+
+// GetReaderContext is GetReader, passing ctx to Inner when it is a
+// ContextLoader, and giving up without waiting out the backoff once ctx is
+// done
+func (rl *RetryLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	attempts := rl.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	backoff := rl.Backoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := backoff
+			var retryAfter *RetryAfterError
+			if errors.As(err, &retryAfter) {
+				delay = retryAfter.Delay
+			} else {
+				backoff *= 2
+			}
+			if rl.MaxDelay > 0 && delay > rl.MaxDelay {
+				delay = rl.MaxDelay
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, err
+			}
+		}
+
+		var r io.Reader
+		if contextLoader, ok := rl.Inner.(ContextLoader); ok {
+			r, err = contextLoader.GetReaderContext(ctx, filename)
+		} else {
+			r, err = rl.Inner.GetReader(filename)
+		}
+		if err == nil {
+			return r, nil
+		}
+		if !isRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// isRetryable returns false for errors which would be the same on every
+// attempt
+func isRetryable(err error) bool {
+	if isNotFound(err) ||
+		errors.Is(err, ErrSchemeNotAllowed) ||
+		errors.Is(err, ErrUnknownScheme) ||
+		errors.Is(err, ErrorNoReader) ||
+		errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return retryableStatus(httpErr.StatusCode)
+	}
+	var requestFailure awserr.RequestFailure
+	if errors.As(err, &requestFailure) {
+		return retryableStatus(requestFailure.StatusCode())
+	}
+	return true
+}
+
+// retryableStatus returns true for server errors and the client errors which
+// mean try again later
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return code < 400 || code >= 500
+}
@@ -0,0 +1,108 @@
+package loadfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingLoader fails with each of errs in turn, then serves an empty object
+type failingLoader struct {
+	errs  []error
+	calls int
+}
+
+func (fl *failingLoader) GetReader(filename string) (io.Reader, error) {
+	fl.calls++
+	if fl.calls <= len(fl.errs) {
+		return nil, fl.errs[fl.calls-1]
+	}
+	return strings.NewReader("{}"), nil
+}
+
+func TestRetryLoader(t *testing.T) {
+	serverError := &HTTPError{URL: "https://config", StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}
+	for _, tc := range []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{{
+		name:      "succeeds first time",
+		wantCalls: 1,
+	}, {
+		name:      "server errors",
+		errs:      []error{serverError, serverError},
+		wantCalls: 3,
+	}, {
+		name:      "too many requests",
+		errs:      []error{&RetryAfterError{Delay: time.Millisecond, Err: &HTTPError{StatusCode: http.StatusTooManyRequests}}},
+		wantCalls: 2,
+	}, {
+		name:      "out of attempts",
+		errs:      []error{serverError, serverError, serverError},
+		wantCalls: 3,
+		wantErr:   true,
+	}, {
+		name:      "not found",
+		errs:      []error{fmt.Errorf("%w: config", ErrNotFound)},
+		wantCalls: 1,
+		wantErr:   true,
+	}, {
+		name:      "forbidden",
+		errs:      []error{&HTTPError{StatusCode: http.StatusForbidden, Status: "403 Forbidden"}},
+		wantCalls: 1,
+		wantErr:   true,
+	}, {
+		name:      "unauthorized",
+		errs:      []error{&HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}},
+		wantCalls: 1,
+		wantErr:   true,
+	}, {
+		name:      "scheme not allowed",
+		errs:      []error{fmt.Errorf("%w: ftp", ErrSchemeNotAllowed)},
+		wantCalls: 1,
+		wantErr:   true,
+	}, {
+		name:      "unknown scheme",
+		errs:      []error{fmt.Errorf("%w: ftp", ErrUnknownScheme)},
+		wantCalls: 1,
+		wantErr:   true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &failingLoader{errs: tc.errs}
+			rl := &RetryLoader{Inner: inner, Backoff: time.Millisecond}
+			_, err := rl.GetReader("config.json")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if inner.calls != tc.wantCalls {
+				t.Errorf("got %d calls, want %d", inner.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryLoaderCancel(t *testing.T) {
+	serverError := &HTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}
+	inner := &failingLoader{errs: []error{serverError, serverError, serverError}}
+	rl := &RetryLoader{Inner: inner, Backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := rl.GetReaderContext(ctx, "config.json")
+	if !errors.Is(err, serverError) {
+		t.Fatalf("got %v, want the last error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s, want the cancelled context to stop the backoff", elapsed)
+	}
+	if inner.calls != 1 {
+		t.Errorf("got %d calls, want 1", inner.calls)
+	}
+}
@@ -0,0 +1,529 @@
+package loadfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3ARN matches an access point or Object Lambda access point ARN, which S3
+// accepts in place of a bucket name
+const s3ARN = `arn:[a-z-]+:s3(?:-object-lambda)?:[a-z0-9-]*:[0-9]*:accesspoint[\/:][^\/]+`
+
+// reS3Filename matches s3://bucket/key, where bucket may be an access point
+// ARN
+var reS3Filename = regexp.MustCompile(`^s3:\/\/(` + s3ARN + `|[^\/]+)\/(.*)$`)
+
+// S3Loader fetches a file from an AWS S3 bucket using default AWS credentials.
+// Supports 'shared config state', i.e., AWS_SDK_LOAD_CONFIG is forced to true,
+// meaning AWS_PROFILE works
+//
+// The bucket may be an access point or Object Lambda access point ARN, e.g.
+// s3://arn:aws:s3-object-lambda:us-east-1:123456789012:accesspoint/ol/app.yaml
+//
+// The session and client are built on first use and reused for every later
+// call, so credentials (including assumed role credentials) are cached and
+// refreshed by the SDK rather than rebuilt per file. An S3Loader may be
+// registered as a value or a pointer, and copies of it made once registered
+// share the client and CacheByETag's cache; one used without registering
+// builds its client for each call.
+type S3Loader struct {
+	// Credentials replaces the default credentials, see StaticCredentials,
+	// EnvCredentials, AssumeRoleCredentials and WebIdentityCredentials. The
+	// shared config's credentials are used when nil.
+	Credentials CredentialSource
+
+	// RoleARN, when set and Credentials isn't, is assumed with
+	// AssumeRoleCredentials, or WebIdentityCredentials with WebIdentity
+	RoleARN string
+
+	// ExternalID is passed to AssumeRole when RoleARN is set
+	ExternalID string
+
+	// WebIdentity, when set and Credentials isn't, uses
+	// WebIdentityCredentials, with RoleARN if set, rather than relying on the
+	// default chain to find the EKS (IRSA) token, which it can skip when the
+	// shared config names other credentials
+	WebIdentity bool
+
+	// SSECustomerKey is the key for objects encrypted with a customer
+	// provided key (SSE-C), unencoded, the SDK adds its MD5. Ignored when
+	// empty.
+	SSECustomerKey string
+
+	// SSECustomerAlgorithm is used with SSECustomerKey, AES256 when empty
+	SSECustomerAlgorithm string
+
+	// RequesterPays acknowledges that the requester is charged for requests,
+	// which requester pays buckets refuse with 403 Access Denied otherwise
+	RequesterPays bool
+
+	// CacheByETag keeps the body of every object fetched, and serves it again
+	// while a HeadObject request shows the object's ETag is unchanged, only
+	// getting the object again once it has changed. This suits objects which
+	// are loaded often but rarely change, as a HeadObject is cheaper than
+	// downloading the body, and unlike a TTL a change is seen straight away.
+	// A body beyond the Loader's WithReadAllLimit is not read or kept.
+	CacheByETag bool
+
+	// OnCacheResult, when set with CacheByETag, is called for every object
+	// fetched with whether the cached body was used, e.g. to count hits
+	OnCacheResult func(filename string, hit bool)
+
+	// Globs reads keys containing *, ? or [ as globs, see GetReaderContext.
+	// Otherwise they are read as they are, as S3 allows them in keys.
+	Globs bool
+
+	// MaxGlobObjects limits how many objects a glob may match,
+	// DefaultMaxGlobObjects when zero
+	MaxGlobObjects int
+
+	state *s3State
+}
+
+// s3State is what the copies of a registered S3Loader share
+type s3State struct {
+	mu     sync.Mutex
+	client *s3.S3
+
+	cacheMu sync.Mutex
+	cache   map[string]s3CachedObject
+}
+
+// withState returns the S3Loader with its own state, unless it has one
+func (sl S3Loader) withState() S3Loader {
+	if sl.state == nil {
+		sl.state = &s3State{}
+	}
+	return sl
+}
+
+// registeredLoader gives an S3Loader being registered, as a value or a
+// pointer, the state its copies share
+func registeredLoader(loader TypeLoader) TypeLoader {
+	switch sl := loader.(type) {
+	case S3Loader:
+		return sl.withState()
+	case *S3Loader:
+		if sl != nil && sl.state == nil {
+			sl.state = &s3State{}
+		}
+	}
+	return loader
+}
+
+// shared returns the state of a registered S3Loader, or new state for one
+// which isn't
+func (sl S3Loader) shared() *s3State {
+	return sl.withState().state
+}
+
+// DefaultMaxGlobObjects is how many objects a glob may match when an
+// S3Loader's MaxGlobObjects is zero
+const DefaultMaxGlobObjects = 1000
+
+// s3CachedObject is a body kept by CacheByETag
+type s3CachedObject struct {
+	etag string
+	body []byte
+}
+
+func (sl S3Loader) getClient() (*s3.S3, error) {
+	state := sl.shared()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.client != nil {
+		return state.client, nil
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	source := sl.Credentials
+	if source == nil && sl.WebIdentity {
+		source = WebIdentityCredentials{RoleARN: sl.RoleARN}
+	} else if source == nil && sl.RoleARN != "" {
+		source = AssumeRoleCredentials{RoleARN: sl.RoleARN, ExternalID: sl.ExternalID}
+	}
+	// Access point ARNs name their own region, which may not be the session's
+	configs := []*aws.Config{aws.NewConfig().WithS3UseARNRegion(true)}
+	if source != nil {
+		config, err := source.AWSConfig(sess)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	state.client = s3.New(sess, configs...)
+	return state.client, nil
+}
+
+func (sl S3Loader) GetReader(filename string) (io.Reader, error) {
+	return sl.GetReaderContext(context.Background(), filename)
+}
+
+// GetReaderContext is GetReader, with ctx covering the request and reading the
+// body
+//
+// With Globs set, a key containing glob characters (*, ? or [) reads every
+// matching object, in lexical key order, joined by "\n---\n" into a single
+// multi-document YAML stream for LoadDocuments, e.g. s3://bucket/shards/*.yaml.
+// As with path.Match, * doesn't match a '/'. Objects named with a compression
+// extension, such as .gz, are decompressed as they are joined, and a glob
+// matching more than MaxGlobObjects objects is an error. Objects are fetched
+// one at a time as the stream is read, so WithReadAllLimit and the byte budget
+// stop the join as they would any one file.
+func (sl S3Loader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+
+	parts := reS3Filename.FindStringSubmatch(filename)
+	if len(parts) != 3 {
+		return nil, errors.New("Impossible bad match passed to S3Loader")
+	}
+	bucket := parts[1]
+	key := parts[2]
+
+	s3Conn, err := sl.getClient()
+	if err != nil {
+		return nil, err
+	}
+	if sl.isGlob(key) {
+		return sl.getGlob(ctx, s3Conn, bucket, key)
+	}
+	return sl.getObject(ctx, s3Conn, bucket, key)
+}
+
+func (sl S3Loader) getObject(ctx context.Context, s3Conn *s3.S3, bucket, key string) (io.ReadCloser, error) {
+	if sl.CacheByETag {
+		return sl.getCachedObject(ctx, s3Conn, bucket, key)
+	}
+	obj, err := sl.getObjectOutput(ctx, s3Conn, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &modTimeReader{ReadCloser: obj.Body, modTime: aws.TimeValue(obj.LastModified)}, nil
+}
+
+// getCachedObject serves the cached body of the object while its ETag is
+// unchanged, for CacheByETag
+func (sl S3Loader) getCachedObject(ctx context.Context, s3Conn *s3.S3, bucket, key string) (io.ReadCloser, error) {
+	filename := "s3://" + bucket + "/" + key
+	state := sl.shared()
+	state.cacheMu.Lock()
+	cached, ok := state.cache[filename]
+	state.cacheMu.Unlock()
+
+	if ok {
+		head := &s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		}
+		if sl.RequesterPays {
+			head.RequestPayer = aws.String(s3.RequestPayerRequester)
+		}
+		if sl.SSECustomerKey != "" {
+			head.SSECustomerAlgorithm = aws.String(sl.sseCustomerAlgorithm())
+			head.SSECustomerKey = aws.String(sl.SSECustomerKey)
+		}
+		out, err := s3Conn.HeadObjectWithContext(ctx, head)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			state.cacheMu.Lock()
+			delete(state.cache, filename)
+			state.cacheMu.Unlock()
+			return nil, s3NotFoundError{aerr}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if aws.StringValue(out.ETag) == cached.etag {
+			sl.cacheResult(filename, true)
+			return &modTimeReader{
+				ReadCloser: ioutil.NopCloser(bytes.NewReader(cached.body)),
+				modTime:    aws.TimeValue(out.LastModified),
+			}, nil
+		}
+	}
+
+	obj, err := sl.getObjectOutput(ctx, s3Conn, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	body, err := ioutil.ReadAll(limitRead(ctx, obj.Body))
+	if err != nil {
+		return nil, err
+	}
+	sl.cacheResult(filename, false)
+	if etag := aws.StringValue(obj.ETag); etag != "" {
+		state.cacheMu.Lock()
+		if state.cache == nil {
+			state.cache = map[string]s3CachedObject{}
+		}
+		state.cache[filename] = s3CachedObject{etag: etag, body: body}
+		state.cacheMu.Unlock()
+	}
+	return &modTimeReader{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(body)),
+		modTime:    aws.TimeValue(obj.LastModified),
+	}, nil
+}
+
+func (sl S3Loader) cacheResult(filename string, hit bool) {
+	if sl.OnCacheResult != nil {
+		sl.OnCacheResult(filename, hit)
+	}
+}
+
+func (sl S3Loader) sseCustomerAlgorithm() string {
+	if sl.SSECustomerAlgorithm == "" {
+		return s3.ServerSideEncryptionAes256
+	}
+	return sl.SSECustomerAlgorithm
+}
+
+// getObjectInput makes a GetObject request as getObjectOutput does, returning
+// only the body, for GetRange
+func (sl S3Loader) getObjectInput(ctx context.Context, s3Conn *s3.S3, input *s3.GetObjectInput) (io.ReadCloser, error) {
+	obj, err := sl.getObjectOutput(ctx, s3Conn, input)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
+
+// getObjectOutput makes a GetObject request, adding any SSE-C key and
+// RequesterPays, with a missing key returned as not found
+func (sl S3Loader) getObjectOutput(ctx context.Context, s3Conn *s3.S3, input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	if sl.RequesterPays {
+		input.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	if sl.SSECustomerKey != "" {
+		input.SSECustomerAlgorithm = aws.String(sl.sseCustomerAlgorithm())
+		input.SSECustomerKey = aws.String(sl.SSECustomerKey)
+	}
+	obj, err := s3Conn.GetObjectWithContext(ctx, input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, s3NotFoundError{aerr}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// isGlob returns true if key is to be read as a glob
+func (sl S3Loader) isGlob(key string) bool {
+	return sl.Globs && strings.ContainsAny(key, "*?[")
+}
+
+// getGlob concatenates the objects matching pattern
+func (sl S3Loader) getGlob(ctx context.Context, s3Conn *s3.S3, bucket, pattern string) (io.Reader, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	prefix := pattern[:strings.IndexAny(pattern, "*?[")]
+	keys, err := sl.listKeys(ctx, s3Conn, bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	matched := []string{}
+	for _, key := range keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			matched = append(matched, key)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: no objects match s3://%s/%s", ErrNotFound, bucket, pattern)
+	}
+	maxObjects := sl.MaxGlobObjects
+	if maxObjects <= 0 {
+		maxObjects = DefaultMaxGlobObjects
+	}
+	if len(matched) > maxObjects {
+		return nil, fmt.Errorf("s3://%s/%s matches %d objects, more than the %d allowed", bucket, pattern, len(matched), maxObjects)
+	}
+	sort.Strings(matched)
+
+	objects := make(globObjects, len(matched))
+	readers := []io.Reader{}
+	for i, key := range matched {
+		if i > 0 {
+			readers = append(readers, strings.NewReader("\n---\n"))
+		}
+		key := key
+		objects[i] = &lazyObject{open: func() (io.ReadCloser, error) {
+			return sl.openObject(ctx, s3Conn, bucket, key)
+		}}
+		readers = append(readers, objects[i])
+	}
+	return &readCloser{Reader: io.MultiReader(readers...), Closer: objects}, nil
+}
+
+// openObject gets the object, decompressed by its extension, for a glob
+func (sl S3Loader) openObject(ctx context.Context, s3Conn *s3.S3, bucket, key string) (io.ReadCloser, error) {
+	body, err := sl.getObject(ctx, s3Conn, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	wrap, ok := codecs[fileExtension(key)]
+	if !ok {
+		return body, nil
+	}
+	reader, err := wrap(body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("s3://%s/%s: %w", bucket, key, err)
+	}
+	return decompressedBody{Reader: reader, body: body}, nil
+}
+
+// decompressedBody closes its decompressor, if that has a Close, then the
+// object body it reads
+type decompressedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (d decompressedBody) Close() error {
+	if closer, ok := d.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return d.body.Close()
+}
+
+// lazyObject gets an object matching a glob when it is first read, and
+// closes it once it has been read to the end, so that only one is open at a
+// time and the Loader's read limit stops the join as it would one object
+type lazyObject struct {
+	open func() (io.ReadCloser, error)
+	body io.ReadCloser
+	done bool
+}
+
+func (o *lazyObject) Read(p []byte) (int, error) {
+	if o.done {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		body, err := o.open()
+		if err != nil {
+			return 0, err
+		}
+		o.body = body
+	}
+	n, err := o.body.Read(p)
+	if err == io.EOF {
+		o.done = true
+		o.body.Close()
+	}
+	return n, err
+}
+
+// globObjects closes whichever objects of a glob are still open
+type globObjects []*lazyObject
+
+func (objects globObjects) Close() error {
+	for _, o := range objects {
+		if o.body != nil && !o.done {
+			o.done = true
+			o.body.Close()
+		}
+	}
+	return nil
+}
+
+// formatHint decodes globs as YAML, as that is what they are joined into
+func (sl S3Loader) formatHint(filename string, isFormat func(ext string) bool) string {
+	parts := reS3Filename.FindStringSubmatch(filename)
+	if len(parts) == 3 && sl.isGlob(parts[2]) {
+		return "yaml"
+	}
+	return ""
+}
+
+// List returns every object under the given s3://bucket/prefix, following
+// pagination. Keys ending in '/' (folder placeholders) are skipped.
+func (sl S3Loader) List(prefix string) ([]string, error) {
+	parts := reS3Filename.FindStringSubmatch(prefix)
+	if len(parts) != 3 {
+		return nil, errors.New("Impossible bad match passed to S3Loader")
+	}
+	bucket := parts[1]
+
+	s3Conn, err := sl.getClient()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := sl.listKeys(context.Background(), s3Conn, bucket, parts[2])
+	if err != nil {
+		return nil, err
+	}
+	filenames := make([]string, len(keys))
+	for i, key := range keys {
+		filenames[i] = "s3://" + bucket + "/" + key
+	}
+	return filenames, nil
+}
+
+func (sl S3Loader) listKeys(ctx context.Context, s3Conn *s3.S3, bucket, prefix string) ([]string, error) {
+	keys := []string{}
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if sl.RequesterPays {
+		input.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	err := s3Conn.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// s3NotFoundError is still an awserr.Error, and also matches ErrNotFound
+type s3NotFoundError struct {
+	aerr awserr.Error
+}
+
+func (e s3NotFoundError) Error() string   { return e.aerr.Error() }
+func (e s3NotFoundError) Code() string    { return e.aerr.Code() }
+func (e s3NotFoundError) Message() string { return e.aerr.Message() }
+func (e s3NotFoundError) OrigErr() error  { return e.aerr.OrigErr() }
+func (e s3NotFoundError) Unwrap() error   { return e.aerr }
+
+func (e s3NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
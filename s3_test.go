@@ -0,0 +1,221 @@
+package loadfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// fakeS3 serves ListObjectsV2, HeadObject and GetObject for the objects of
+// one bucket
+func fakeS3(t *testing.T, objects map[string][]byte) *s3.S3 {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		if r.URL.Path == "/bucket" || r.URL.Path == "/bucket/" {
+			type content struct{ Key string }
+			result := struct {
+				XMLName  xml.Name `xml:"ListBucketResult"`
+				Contents []content
+			}{}
+			keys := []string{}
+			for key := range objects {
+				if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				result.Contents = append(result.Contents, content{Key: key})
+			}
+			xml.NewEncoder(w).Encode(result)
+			return
+		}
+		body, ok := objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(srv.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	return s3.New(sess)
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	zw.Write([]byte(content))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestS3Globs(t *testing.T) {
+	objects := map[string][]byte{
+		"shards/b.yaml":     []byte("name: b\n"),
+		"shards/a.yaml":     []byte("name: a\n"),
+		"shards/c.yaml.gz":  gzipped(t, "name: c\n"),
+		"app[prod].yaml":    []byte("name: prod\n"),
+		"shards/sub/d.yaml": []byte("name: d\n"),
+	}
+	for _, tc := range []struct {
+		name     string
+		loader   *S3Loader
+		filename string
+		want     []string
+		wantErr  string
+	}{{
+		name:     "glob",
+		loader:   &S3Loader{Globs: true},
+		filename: "s3://bucket/shards/*.yaml",
+		want:     []string{"a", "b"},
+	}, {
+		name:     "compressed objects",
+		loader:   &S3Loader{Globs: true},
+		filename: "s3://bucket/shards/*.yaml*",
+		want:     []string{"a", "b", "c"},
+	}, {
+		name:     "brackets without globs",
+		loader:   &S3Loader{},
+		filename: "s3://bucket/app[prod].yaml",
+		want:     []string{"prod"},
+	}, {
+		name:     "too many objects",
+		loader:   &S3Loader{Globs: true, MaxGlobObjects: 1},
+		filename: "s3://bucket/shards/*.yaml",
+		wantErr:  "more than the 1 allowed",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.loader.state = &s3State{client: fakeS3(t, objects)}
+			l := NewLoader()
+			if err := l.Register(reS3Filename, tc.loader); err != nil {
+				t.Fatal(err)
+			}
+			docs := []struct{ Name string }{}
+			err := l.LoadDocuments(tc.filename, &docs)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, doc := range docs {
+				got = append(got, doc.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestS3LoaderValue(t *testing.T) {
+	hits := []bool{}
+	l := NewLoader()
+	if err := l.Register(reS3Filename, S3Loader{
+		CacheByETag:   true,
+		OnCacheResult: func(filename string, hit bool) { hits = append(hits, hit) },
+		state: &s3State{client: fakeS3(t, map[string][]byte{
+			"app.yaml": []byte("name: app\n"),
+		})},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		into := struct{ Name string }{}
+		if err := l.Load("s3://bucket/app.yaml", &into); err != nil {
+			t.Fatal(err)
+		}
+		if into.Name != "app" {
+			t.Errorf("Name is %q", into.Name)
+		}
+	}
+	if len(hits) != 2 || hits[0] || !hits[1] {
+		t.Errorf("cache results are %v, want [false true]", hits)
+	}
+}
+
+func TestRegisteredS3LoaderState(t *testing.T) {
+	for _, loader := range []TypeLoader{S3Loader{}, &S3Loader{}} {
+		registered := registeredLoader(loader)
+		var state *s3State
+		switch sl := registered.(type) {
+		case S3Loader:
+			state = sl.state
+		case *S3Loader:
+			state = sl.state
+		}
+		if state == nil {
+			t.Errorf("%T registered without state", loader)
+		}
+	}
+}
+
+func TestS3ReadLimit(t *testing.T) {
+	large := strings.Repeat("x", 1024)
+	objects := map[string][]byte{
+		"shards/a.yaml":    []byte("name: a\n"),
+		"shards/b.yaml.gz": gzipped(t, "name: "+large+"\n"),
+		"large.yaml":       []byte("name: " + large + "\n"),
+	}
+	for _, tc := range []struct {
+		name     string
+		loader   *S3Loader
+		filename string
+	}{{
+		name:     "glob",
+		loader:   &S3Loader{Globs: true},
+		filename: "s3://bucket/shards/*",
+	}, {
+		name:     "cache by etag",
+		loader:   &S3Loader{CacheByETag: true},
+		filename: "s3://bucket/large.yaml",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			hits := []bool{}
+			tc.loader.OnCacheResult = func(filename string, hit bool) { hits = append(hits, hit) }
+			tc.loader.state = &s3State{client: fakeS3(t, objects)}
+			l := NewLoader(WithReadAllLimit(512))
+			if err := l.Register(reS3Filename, tc.loader); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				docs := []struct{ Name string }{}
+				if err := l.LoadDocuments(tc.filename, &docs); !errors.Is(err, ErrTooLarge) {
+					t.Fatalf("got error %v, want ErrTooLarge", err)
+				}
+			}
+			for _, hit := range hits {
+				if hit {
+					t.Errorf("a body beyond the read limit was cached")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// WithPreserveComments makes Save edit an existing YAML file in place rather
+// than replacing it, keeping its comments, key order and scalar styles.
+// Changed values are written over the original nodes, keys which are new are
+// appended and keys no longer present are removed.
+func WithPreserveComments() Option {
+	return func(l *Loader) {
+		l.preserveComments = true
+	}
+}
+
+// Save encodes from to the local file filename, as JSON or YAML by extension
+// (JSON unless .yaml or .yml), replacing it atomically with
+// FileLoader.WriteFile. Only local paths can be saved, anything else returns
+// ErrUnsupported.
+func (l *Loader) Save(filename string, from interface{}) error {
+	filename, writer, err := l.saveTarget(filename)
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	switch l.docFormat(fileExtension(filename)) {
+	case docJSON:
+		b, err = json.MarshalIndent(from, "", "  ")
+		b = append(b, '\n')
+	case docYAML:
+		b, err = l.encodeYAML(filename, from)
+	default:
+		return fmt.Errorf("%w: saving %s files", ErrUnsupported, fileExtension(filename))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return writer.WriteFile(filename, b)
+}
+
+// saveTarget returns the local path filename names and the writer to save it
+// with, or ErrUnsupported when it can't be saved
+func (l *Loader) saveTarget(filename string) (string, fileWriter, error) {
+	if err := l.checkScheme(filename); err != nil {
+		return "", nil, err
+	}
+	if reFileURL.MatchString(filename) {
+		localPath, err := fileURLPath(filename)
+		if err != nil {
+			return "", nil, err
+		}
+		filename = localPath
+	} else if reURLScheme.MatchString(filename) {
+		return "", nil, fmt.Errorf("%w: saving %s", ErrUnsupported, redactURL(filename))
+	}
+
+	writer, ok := l.fallback.(fileWriter)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: saving %s", ErrUnsupported, filename)
+	}
+	return filename, writer, nil
+}
+
+// fileWriter is implemented by the fallback TypeLoader when it can be saved to
+type fileWriter interface {
+	WriteFile(filename string, content []byte) error
+}
+
+// Save encodes from to filename using the DefaultLoader
+func Save(filename string, from interface{}) error {
+	return DefaultLoader.Save(filename, from)
+}
+
+// encodeYAML encodes from, merged onto the existing file's nodes when
+// preserving comments
+func (l *Loader) encodeYAML(filename string, from interface{}) ([]byte, error) {
+	node := &yamlv3.Node{}
+	if err := node.Encode(from); err != nil {
+		return nil, err
+	}
+
+	if l.preserveComments {
+		existing, err := ioutil.ReadFile(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(existing)) > 0 {
+			doc := &yamlv3.Node{}
+			if err := yamlv3.Unmarshal(existing, doc); err != nil {
+				return nil, err
+			}
+			if doc.Kind == yamlv3.DocumentNode && len(doc.Content) == 1 {
+				mergeNode(doc.Content[0], node)
+				node = doc
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	enc := yamlv3.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeNode updates dst, from the original file, to hold the values of src,
+// freshly encoded, keeping dst's comments and, where the value is unchanged,
+// its style
+func mergeNode(dst, src *yamlv3.Node) {
+	if dst.Kind != src.Kind || dst.Kind == yamlv3.AliasNode {
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+		return
+	}
+
+	switch dst.Kind {
+	case yamlv3.ScalarNode:
+		if dst.Value != src.Value || dst.ShortTag() != src.ShortTag() {
+			dst.Value = src.Value
+			dst.Tag = src.Tag
+			dst.Style = src.Style
+		}
+
+	case yamlv3.SequenceNode:
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				mergeNode(dst.Content[i], item)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		dst.Content = dst.Content[:len(src.Content)]
+
+	case yamlv3.MappingNode:
+		existing := map[string]*yamlv3.Node{}
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			existing[dst.Content[i].Value] = dst.Content[i+1]
+		}
+		wanted := map[string]bool{}
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key := src.Content[i].Value
+			wanted[key] = true
+			if value, ok := existing[key]; ok {
+				mergeNode(value, src.Content[i+1])
+			} else {
+				dst.Content = append(dst.Content, src.Content[i], src.Content[i+1])
+			}
+		}
+		kept := dst.Content[:0]
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			if wanted[dst.Content[i].Value] {
+				kept = append(kept, dst.Content[i], dst.Content[i+1])
+			}
+		}
+		dst.Content = kept
+	}
+}
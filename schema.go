@@ -0,0 +1,135 @@
+package loadfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// SchemaError is returned by LoadWithSchema when the file doesn't validate
+// against the schema, listing every violation
+type SchemaError struct {
+	Filename   string
+	Violations []SchemaViolation
+}
+
+// SchemaViolation is one reason a file failed schema validation
+type SchemaViolation struct {
+	// Path is the JSON pointer of the value in the file, e.g.
+	// /database/port, "" for the whole document
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		path := v.Path
+		if path == "" {
+			path = "/"
+		}
+		lines[i] = fmt.Sprintf("%s: %s", path, v.Message)
+	}
+	return fmt.Sprintf("%s: doesn't match the schema:\n\t%s", redactURL(e.Filename), strings.Join(lines, "\n\t"))
+}
+
+// LoadWithSchema validates filename against the JSON Schema in schemaFilename
+// before decoding it into into as Load would, which only happens when it is
+// valid. Otherwise a *SchemaError lists the violations. The file must be JSON,
+// YAML or TOML, as it is validated as a generic document. The schema, JSON or
+// YAML, and any other schemas it refers to by a relative $ref are loaded
+// through this Loader, so they can be in S3 or anywhere else a file can be.
+//
+// An empty file is validated as null, so a schema requiring an object, or
+// any properties, rejects it. When it is valid into is left untouched.
+func (l *Loader) LoadWithSchema(filename, schemaFilename string, into interface{}) error {
+	schema, err := l.compileSchema(schemaFilename)
+	if err != nil {
+		return err
+	}
+	ext, content, err := l.readPrepared(filename)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return validateSchema(schema, filename, nil)
+	}
+	if err != nil {
+		return err
+	}
+	doc, err := parseDocument(l.docFormat(ext), content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	if err := validateSchema(schema, filename, doc); err != nil {
+		return err
+	}
+	return l.decodeAs(filename, ext, bytes.NewReader(content), into)
+}
+
+// validateSchema validates the document of filename, returning a *SchemaError
+// listing any violations
+func validateSchema(schema *jsonschema.Schema, filename string, doc interface{}) error {
+	err := schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	return &SchemaError{Filename: filename, Violations: schemaViolations(validationErr)}
+}
+
+// LoadWithSchema validates a file against a JSON Schema before loading it,
+// using the default loader
+func LoadWithSchema(filename, schemaFilename string, into interface{}) error {
+	return DefaultLoader.LoadWithSchema(filename, schemaFilename, into)
+}
+
+// schemaLoader loads schemas for the compiler through a Loader
+type schemaLoader struct {
+	loader *Loader
+}
+
+func (sl schemaLoader) Load(url string) (any, error) {
+	ext, content, err := sl.loader.readPrepared(url)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(sl.loader.docFormat(ext), content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", redactURL(url), err)
+	}
+	return doc, nil
+}
+
+func (l *Loader) compileSchema(schemaFilename string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(schemaLoader{loader: l})
+	schema, err := compiler.Compile(schemaFilename)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid schema %s: %w", redactURL(schemaFilename), err)
+	}
+	return schema, nil
+}
+
+// schemaViolations flattens err into the leaf errors, which name the actual
+// problems rather than the schema keywords they were found under
+func schemaViolations(err *jsonschema.ValidationError) []SchemaViolation {
+	violations := []SchemaViolation{}
+	var walk func(unit jsonschema.OutputUnit)
+	walk = func(unit jsonschema.OutputUnit) {
+		if len(unit.Errors) == 0 && unit.Error != nil {
+			violations = append(violations, SchemaViolation{
+				Path:    unit.InstanceLocation,
+				Message: unit.Error.String(),
+			})
+		}
+		for _, child := range unit.Errors {
+			walk(child)
+		}
+	}
+	walk(*err.DetailedOutput())
+	return violations
+}
@@ -0,0 +1,68 @@
+package loadfile
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadWithSchema(t *testing.T) {
+	strict := writeTestFile(t, "strict.json", `{"type": "object", "required": ["name"], "properties": {"port": {"type": "integer"}}}`)
+	permissive := writeTestFile(t, "any.json", `{}`)
+	for _, tc := range []struct {
+		name           string
+		content        string
+		schema         string
+		want           string
+		wantViolations []string
+	}{{
+		name:    "valid",
+		content: "name: app\nport: 80\n",
+		schema:  strict,
+		want:    "app",
+	}, {
+		name:           "violations",
+		content:        "port: eighty\n",
+		schema:         strict,
+		wantViolations: []string{"/", "/port"},
+	}, {
+		name:           "empty against a schema requiring an object",
+		content:        "",
+		schema:         strict,
+		wantViolations: []string{"/"},
+	}, {
+		name:    "empty against a schema allowing anything",
+		content: "",
+		schema:  permissive,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, "config.yaml", tc.content)
+			into := struct {
+				Name string
+				Port int
+			}{}
+			err := NewLoader().LoadWithSchema(filename, tc.schema, &into)
+			if tc.wantViolations == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if into.Name != tc.want {
+					t.Errorf("Name is %q, want %q", into.Name, tc.want)
+				}
+				return
+			}
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("got %v, want a SchemaError", err)
+			}
+			paths := map[string]bool{}
+			for _, v := range schemaErr.Violations {
+				paths[v.Path] = true
+			}
+			for _, path := range tc.wantViolations {
+				if !paths[path] && !(path == "/" && paths[""]) {
+					t.Errorf("no violation at %s in %v", path, schemaErr.Violations)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrSchemeNotAllowed is returned for filenames rejected by AllowSchemes or
+// AllowLocal, and for local paths included by remote files
+var ErrSchemeNotAllowed = errors.New("Scheme not allowed")
+
+// ErrUnknownScheme is returned for a scheme://... filename which no
+// registered TypeLoader matches, e.g. a mistyped s33://bucket/key, rather than
+// it being opened as a local path
+var ErrUnknownScheme = errors.New("Unknown scheme")
+
+// WithUnknownSchemeFallback passes scheme://... filenames no TypeLoader
+// matches to the fallback FileLoader, which opens them as local paths, rather
+// than returning ErrUnknownScheme
+func WithUnknownSchemeFallback() Option {
+	return func(l *Loader) {
+		l.unknownSchemeFallback = true
+	}
+}
+
+// AllowSchemes restricts the Loader to URLs with the given schemes, e.g. "s3"
+// or "https". Any other scheme://... filename is rejected with
+// ErrSchemeNotAllowed, even when a TypeLoader is registered for it. Calling it
+// with no schemes rejects every URL. Local paths are controlled separately, by
+// AllowLocal.
+//
+// This is a guard for filenames which are partly user influenced, where an
+// unexpected s3:// or http:// source would be a security problem.
+func (l *Loader) AllowSchemes(schemes ...string) error {
+	if err := l.checkFrozen(); err != nil {
+		return err
+	}
+	l.allowedSchemes = map[string]bool{}
+	for _, scheme := range schemes {
+		l.allowedSchemes[strings.ToLower(scheme)] = true
+	}
+	return nil
+}
+
+// AllowLocal sets whether local paths, i.e. filenames without a scheme or
+// file:// URLs, are allowed. They are unless AllowLocal(false) is called.
+func (l *Loader) AllowLocal(allow bool) error {
+	if err := l.checkFrozen(); err != nil {
+		return err
+	}
+	l.denyLocal = !allow
+	return nil
+}
+
+// isLocalPath returns true for filenames without a scheme and file:// URLs
+func isLocalPath(filename string) bool {
+	return !reURLScheme.MatchString(filename) || reFileURL.MatchString(filename)
+}
+
+func (l *Loader) checkScheme(filename string) error {
+	if isLocalPath(filename) {
+		if l.denyLocal {
+			return fmt.Errorf("%w: local path %s", ErrSchemeNotAllowed, filename)
+		}
+		return nil
+	}
+	match := reURLScheme.FindStringSubmatch(filename)
+	if l.allowedSchemes != nil && !l.allowedSchemes[strings.ToLower(match[1])] {
+		return fmt.Errorf("%w: %s", ErrSchemeNotAllowed, match[1])
+	}
+	return nil
+}
+
+// RegisterScheme is Register for the common case of a URL scheme, matching
+// filenames starting scheme: (case insensitive), e.g. RegisterScheme("vault",
+// loader) for vault://host/path. The loader can split the filename with
+// ParseURL.
+func (l *Loader) RegisterScheme(scheme string, loader TypeLoader) error {
+	return l.Register(regexp.MustCompile(`^(?i)`+regexp.QuoteMeta(scheme)+`:`), loader)
+}
+
+// ParsedURL is a filename split by ParseURL
+type ParsedURL struct {
+	Scheme string
+	// Host is the part between // and the next /, "" without a //
+	Host string
+	// Path is everything after the host and its /, or after the : without a //
+	Path string
+}
+
+// ParseURL splits a scheme://host/path filename, e.g. s3://bucket/key gives
+// Host "bucket" and Path "key". Unlike url.Parse nothing is unescaped and no
+// query string is split off, the path is exactly as given.
+func ParseURL(filename string) (ParsedURL, error) {
+	match := reURLScheme.FindStringSubmatch(filename)
+	if match == nil {
+		idx := strings.Index(filename, ":")
+		if idx < 1 {
+			return ParsedURL{}, fmt.Errorf("No scheme in %s", redactURL(filename))
+		}
+		return ParsedURL{Scheme: filename[:idx], Path: filename[idx+1:]}, nil
+	}
+	rest := strings.TrimPrefix(filename, match[0])
+	host, path := rest, ""
+	if idx := strings.Index(rest, "/"); idx >= 0 {
+		host, path = rest[:idx], rest[idx+1:]
+	}
+	return ParsedURL{Scheme: match[1], Host: host, Path: path}, nil
+}
@@ -0,0 +1,151 @@
+package loadfile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// WithSecretResolution replaces string values in the decoded target which are
+// URIs of the given schemes, e.g. password: "vault://secret/db#password",
+// with the content fetched from them, through the Loader's own TypeLoaders. A
+// #fragment selects a top level key of a JSON or YAML secret, whose value
+// must be a scalar and is used exactly as written, otherwise the whole
+// content is used, less a trailing newline.
+//
+// Only the schemes given are resolved, e.g. WithSecretResolution("vault",
+// "ssm"), and with none nothing is: a scheme such as s3, or one for local
+// files, would let a value read anything the Loader can, so each must be
+// chosen. file:// URLs are never resolved. Fetched values are not themselves
+// resolved, so a secret can't refer on to another. As anyone who can write
+// the config can point a field at any secret of those schemes the Loader can
+// read, this is off by default.
+func WithSecretResolution(schemes ...string) Option {
+	return func(l *Loader) {
+		l.secretSchemes = map[string]bool{}
+		for _, scheme := range schemes {
+			l.secretSchemes[strings.ToLower(scheme)] = true
+		}
+	}
+}
+
+// isSecretRef returns true if value is a URI WithSecretResolution resolves
+func (l *Loader) isSecretRef(value string) bool {
+	match := reURLScheme.FindStringSubmatch(value)
+	if match == nil || reFileURL.MatchString(value) {
+		return false
+	}
+	return l.secretSchemes[strings.ToLower(match[1])]
+}
+
+// resolveSecret fetches the value a secret URI refers to
+func (l *Loader) resolveSecret(ref string) (string, error) {
+	name, key := ref, ""
+	if idx := strings.LastIndex(ref, "#"); idx >= 0 {
+		name, key = ref[:idx], ref[idx+1:]
+	}
+	content, err := l.readAll(name)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	// JSON is also YAML, so this parses either. The value is taken as it was
+	// written, so a secret of 01234 isn't read as the number 668.
+	root, err := parseYAMLNode(content)
+	if err != nil {
+		return "", err
+	}
+	if root == nil || root.Kind != yamlv3.MappingNode {
+		return "", fmt.Errorf("Secret is not an object, can't select %q", key)
+	}
+	if hasMergeKey(root) {
+		root = copyNode(root)
+		inlineMerges(root)
+	}
+	idx := mappingIndex(root, key)
+	if idx < 0 {
+		return "", fmt.Errorf("Secret has no key %q", key)
+	}
+	value := root.Content[idx]
+	if value.Kind == yamlv3.AliasNode && value.Alias != nil {
+		value = value.Alias
+	}
+	if value.Kind != yamlv3.ScalarNode {
+		return "", fmt.Errorf("Secret key %q is not a single value", key)
+	}
+	return value.Value, nil
+}
+
+// resolveSecrets walks into, replacing secret references
+func (l *Loader) resolveSecrets(into interface{}) error {
+	return l.resolveSecretsIn(reflect.ValueOf(into), map[uintptr]bool{})
+}
+
+func (l *Loader) resolveSecretsIn(v reflect.Value, seen map[uintptr]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return nil
+		}
+		seen[v.Pointer()] = true
+		return l.resolveSecretsIn(v.Elem(), seen)
+
+	case reflect.String:
+		if !v.CanSet() || !l.isSecretRef(v.String()) {
+			return nil
+		}
+		value, err := l.resolveSecret(v.String())
+		if err != nil {
+			return fmt.Errorf("Resolving secret %s: %w", redactURL(v.String()), err)
+		}
+		v.SetString(value)
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := l.resolveSecretsIn(v.Field(i), seen); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := l.resolveSecretsIn(v.Index(i), seen); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			// values in an interface can't be set, so work on a copy
+			elem := reflect.New(v.Elem().Type()).Elem()
+			elem.Set(v.Elem())
+			if err := l.resolveSecretsIn(elem, seen); err != nil {
+				return err
+			}
+			if v.CanSet() {
+				v.Set(elem)
+			}
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := l.resolveSecretsIn(elem, seen); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
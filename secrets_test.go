@@ -0,0 +1,113 @@
+package loadfile
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type secretLoader struct{}
+
+func (secretLoader) GetReader(filename string) (io.Reader, error) {
+	return strings.NewReader("hunter2\n"), nil
+}
+
+func TestSecretSchemes(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		schemes []string
+		value   string
+		want    string
+	}{{
+		name:    "listed scheme",
+		schemes: []string{"vault"},
+		value:   "vault://secret/db",
+		want:    "hunter2",
+	}, {
+		name:    "scheme listed in another case",
+		schemes: []string{"VAULT"},
+		value:   "vault://secret/db",
+		want:    "hunter2",
+	}, {
+		name:    "unlisted scheme",
+		schemes: []string{"vault"},
+		value:   "s3://bucket/key",
+		want:    "s3://bucket/key",
+	}, {
+		name:  "no schemes",
+		value: "vault://secret/db",
+		want:  "vault://secret/db",
+	}, {
+		name:    "file urls",
+		schemes: []string{"file"},
+		value:   "file:///etc/passwd",
+		want:    "file:///etc/passwd",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader(WithSecretResolution(tc.schemes...))
+			if err := l.Register(regexp.MustCompile(`^(vault|s3):\/\/`), secretLoader{}); err != nil {
+				t.Fatal(err)
+			}
+			got := struct{ Password string }{}
+			if err := l.Load(writeTestFile(t, "config.json", `{"Password": "`+tc.value+`"}`), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Password != tc.want {
+				t.Errorf("got %q, want %q", got.Password, tc.want)
+			}
+		})
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	secrets := memLoader{
+		"vault://secret/db.yaml": "password: 01234\ntoken: 1.10\nid: 12345678901234567890\nflag: yes\nquoted: \"a\\\"b\"\n" +
+			"block: |\n  line\nempty:\nlist: [a, b]\nnested:\n  key: value\n",
+		"vault://secret/db.json": `{"password": "01234", "id": 12345678901234567890, "ratio": 1.10, "list": [1]}`,
+		"vault://secret/list":    "- a\n",
+	}
+	for _, tc := range []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "leading zero", ref: "vault://secret/db.yaml#password", want: "01234"},
+		{name: "trailing zero", ref: "vault://secret/db.yaml#token", want: "1.10"},
+		{name: "big number", ref: "vault://secret/db.yaml#id", want: "12345678901234567890"},
+		{name: "yaml 1.1 bool", ref: "vault://secret/db.yaml#flag", want: "yes"},
+		{name: "quoted", ref: "vault://secret/db.yaml#quoted", want: `a"b`},
+		{name: "block", ref: "vault://secret/db.yaml#block", want: "line\n"},
+		{name: "empty", ref: "vault://secret/db.yaml#empty", want: ""},
+		{name: "json string", ref: "vault://secret/db.json#password", want: "01234"},
+		{name: "json big number", ref: "vault://secret/db.json#id", want: "12345678901234567890"},
+		{name: "json number", ref: "vault://secret/db.json#ratio", want: "1.10"},
+		{name: "sequence", ref: "vault://secret/db.yaml#list", wantErr: `Secret key "list" is not a single value`},
+		{name: "mapping", ref: "vault://secret/db.yaml#nested", wantErr: `Secret key "nested" is not a single value`},
+		{name: "json array", ref: "vault://secret/db.json#list", wantErr: `Secret key "list" is not a single value`},
+		{name: "missing key", ref: "vault://secret/db.yaml#other", wantErr: `Secret has no key "other"`},
+		{name: "not an object", ref: "vault://secret/list#key", wantErr: "Secret is not an object"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader(WithSecretResolution("vault"))
+			if err := l.Register(regexp.MustCompile(`^vault:\/\/`), secrets); err != nil {
+				t.Fatal(err)
+			}
+			got := struct{ Password string }{}
+			err := l.Load(writeTestFile(t, "config.json", `{"Password": "`+tc.ref+`"}`), &got)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want an error with %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Password != tc.want {
+				t.Errorf("got %q, want %q", got.Password, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package loadfile
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// SectionTracker reloads a file and reports which of its sections changed,
+// for large files where consumers only care about specific subtrees. Each
+// section of the decoded document is hashed, and only the key paths whose hash
+// differs from the previous reload are reported. There is no file watching in
+// this package, so the caller decides when to reload, e.g. on a timer or a
+// file system notification.
+type SectionTracker struct {
+	loader   *Loader
+	filename string
+	depth    int
+
+	mu     sync.Mutex
+	hashes map[string][sha256.Size]byte
+}
+
+// NewSectionTracker returns a SectionTracker for filename. depth is how many
+// levels of keys make a section: 1 hashes each top level key, 2 each key under
+// those, as "server.tls", and so on. Values above depth which aren't maps are
+// sections of their own.
+func (l *Loader) NewSectionTracker(filename string, depth int) *SectionTracker {
+	if depth < 1 {
+		depth = 1
+	}
+	return &SectionTracker{loader: l, filename: filename, depth: depth}
+}
+
+// NewSectionTracker returns a SectionTracker for filename, using the default
+// loader
+func NewSectionTracker(filename string, depth int) *SectionTracker {
+	return DefaultLoader.NewSectionTracker(filename, depth)
+}
+
+// Reload loads the file into a generic map and calls fn with the sorted key
+// paths of the sections which were added, removed or changed since the last
+// successful Reload, and the whole document. The first Reload reports every
+// section. fn isn't called when nothing changed. When fn returns an error it
+// is returned, and the sections are reported again by the next Reload.
+func (t *SectionTracker) Reload(fn func(changed []string, doc map[string]interface{}) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	doc := map[string]interface{}{}
+	if err := t.loader.Load(t.filename, &doc); err != nil {
+		return err
+	}
+	hashes := map[string][sha256.Size]byte{}
+	hashSections(hashes, reflect.ValueOf(doc), "", t.depth)
+
+	changed := []string{}
+	for path, sum := range hashes {
+		if previous, ok := t.hashes[path]; !ok || previous != sum {
+			changed = append(changed, path)
+		}
+	}
+	for path := range t.hashes {
+		if _, ok := hashes[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	if err := fn(changed, doc); err != nil {
+		return err
+	}
+	t.hashes = hashes
+	return nil
+}
+
+// hashSections adds the hash of each section of v, a map, under prefix
+func hashSections(hashes map[string][sha256.Size]byte, v reflect.Value, prefix string, depth int) {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	iter := v.MapRange()
+	for iter.Next() {
+		path := fmt.Sprint(iter.Key().Interface())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		value := iter.Value()
+		for value.Kind() == reflect.Interface && !value.IsNil() {
+			value = value.Elem()
+		}
+		if depth > 1 && value.Kind() == reflect.Map {
+			hashSections(hashes, value, path, depth-1)
+			continue
+		}
+		h := sha256.New()
+		hashValue(h, value)
+		var sum [sha256.Size]byte
+		copy(sum[:], h.Sum(nil))
+		hashes[path] = sum
+	}
+}
+
+// hashValue writes v to h in a canonical form, with map keys sorted, as both
+// map[string]interface{} and the map[interface{}]interface{} of YAML occur
+func hashValue(h hash.Hash, v reflect.Value) {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := map[string]reflect.Value{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, key)
+			values[key] = iter.Value()
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(h, "{%d", len(keys))
+		for _, key := range keys {
+			fmt.Fprintf(h, "%q:", key)
+			hashValue(h, values[key])
+		}
+		h.Write([]byte("}"))
+	case reflect.Slice, reflect.Array:
+		fmt.Fprintf(h, "[%d", v.Len())
+		for i := 0; i < v.Len(); i++ {
+			h.Write([]byte(","))
+			hashValue(h, v.Index(i))
+		}
+		h.Write([]byte("]"))
+	case reflect.Invalid:
+		h.Write([]byte("null"))
+	default:
+		fmt.Fprintf(h, "%T(%#v)", v.Interface(), v.Interface())
+	}
+}
@@ -0,0 +1,101 @@
+package loadfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// ErrSignatureInvalid is returned by LoadSigned when a file's signature is
+// missing, malformed or doesn't verify
+var ErrSignatureInvalid = errors.New("Invalid signature")
+
+// ErrUnsignedFetches is returned by LoadSigned when the Loader has includes,
+// refs, secret resolution or templates enabled, as what they would bring in
+// isn't covered by the signature
+var ErrUnsignedFetches = errors.New("Refusing to load a signed file with includes, refs, secrets or templates enabled")
+
+// WithSignatureLocation sets where LoadSigned finds the signature of a file,
+// e.g. a signatures/ directory beside it, rather than filename.sig
+func WithSignatureLocation(location func(filename string) string) Option {
+	return func(l *Loader) {
+		l.signatureLocation = location
+	}
+}
+
+// signatureFilename adds .sig to the path of filename, leaving the query
+// string of http(s) URLs in place
+func signatureFilename(filename string) string {
+	if reHTTPFilename.MatchString(filename) {
+		if u, err := url.Parse(filename); err == nil {
+			u.Path += ".sig"
+			return u.String()
+		}
+	}
+	return filename + ".sig"
+}
+
+// LoadSigned fetches filename and its detached Ed25519 signature, by default
+// filename.sig, both through the Loader, and decodes the file as Load does
+// only once the signature of its raw content, as fetched, verifies with
+// pubKey. The signature is the 64 signature bytes, or those base64 encoded.
+// Otherwise it returns ErrSignatureInvalid, wrapping the error fetching the
+// signature when it can't be, and nothing is decoded.
+//
+// Everything decoded is covered by the signature: a Loader with includes,
+// refs, secret resolution or templates enabled, which would decode content
+// from unsigned files or elsewhere, returns ErrUnsignedFetches without
+// fetching anything.
+func (l *Loader) LoadSigned(filename string, pubKey ed25519.PublicKey, into interface{}) error {
+	if l.includes || l.refs || l.secretSchemes != nil || l.template {
+		return fmt.Errorf("%s: %w", redactURL(filename), ErrUnsignedFetches)
+	}
+	r, err := l.GetReadCloser(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	content, err := l.readFetched(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	ext := ""
+	if fr, ok := r.(FormatReader); ok {
+		ext = fr.FormatExtension()
+	}
+
+	sigFilename := signatureFilename(filename)
+	if l.signatureLocation != nil {
+		sigFilename = l.signatureLocation(filename)
+	}
+	sig, err := l.readAll(sigFilename)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", redactURL(filename), ErrSignatureInvalid, err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return fmt.Errorf("%s: %w: %s isn't an Ed25519 signature", redactURL(filename), ErrSignatureInvalid, redactURL(sigFilename))
+		}
+		sig = decoded
+	}
+	if len(pubKey) != ed25519.PublicKeySize || !ed25519.Verify(pubKey, content, sig) {
+		return fmt.Errorf("%s: %w", redactURL(filename), ErrSignatureInvalid)
+	}
+
+	var verified io.Reader = bytes.NewReader(content)
+	if ext != "" {
+		verified = formatReader{Reader: verified, ext: ext}
+	}
+	return l.decode(filename, verified, into)
+}
+
+// LoadSigned loads a file once its detached signature verifies, using the
+// default loader
+func LoadSigned(filename string, pubKey ed25519.PublicKey, into interface{}) error {
+	return DefaultLoader.LoadSigned(filename, pubKey, into)
+}
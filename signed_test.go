@@ -0,0 +1,104 @@
+package loadfile
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// signedLoader serves a YAML file without an extension through a
+// FormatReader, and its signature
+type signedLoader struct {
+	content string
+	sig     []byte
+}
+
+func (sl signedLoader) GetReader(filename string) (io.Reader, error) {
+	if strings.HasSuffix(filename, ".sig") {
+		if sl.sig == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, filename)
+		}
+		return strings.NewReader(string(sl.sig)), nil
+	}
+	return formatReader{Reader: strings.NewReader(sl.content), ext: "yaml"}, nil
+}
+
+func TestLoadSigned(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "port: 8080\n"
+	sig := ed25519.Sign(privKey, []byte(content))
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		content string
+		sig     []byte
+		wantErr error
+	}{{
+		name:    "raw signature",
+		content: content,
+		sig:     sig,
+	}, {
+		name:    "base64 signature",
+		content: content,
+		sig:     []byte(base64.StdEncoding.EncodeToString(sig) + "\n"),
+	}, {
+		name:    "tampered content",
+		content: "port: 8081\n",
+		sig:     sig,
+		wantErr: ErrSignatureInvalid,
+	}, {
+		name:    "missing signature",
+		content: content,
+		wantErr: ErrSignatureInvalid,
+	}, {
+		name:    "over the read limit",
+		opts:    []Option{WithReadAllLimit(4)},
+		content: content,
+		sig:     sig,
+		wantErr: ErrTooLarge,
+	}, {
+		name:    "unsigned include",
+		opts:    []Option{WithIncludes()},
+		content: "port: !include port.yaml\n",
+		sig:     ed25519.Sign(privKey, []byte("port: !include port.yaml\n")),
+		wantErr: ErrUnsignedFetches,
+	}, {
+		name:    "template",
+		opts:    []Option{WithTemplate()},
+		content: content,
+		sig:     sig,
+		wantErr: ErrUnsignedFetches,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader(tc.opts...)
+			if err := l.Register(regexp.MustCompile(`^signed:\/\/`), signedLoader{content: tc.content, sig: tc.sig}); err != nil {
+				t.Fatal(err)
+			}
+			got := struct{ Port int }{}
+			err := l.LoadSigned("signed://config", pubKey, &got)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				if got.Port != 0 {
+					t.Errorf("decoded port %d despite the error", got.Port)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Port != 8080 {
+				t.Errorf("got port %d, want 8080 decoded as YAML", got.Port)
+			}
+		})
+	}
+}
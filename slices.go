@@ -0,0 +1,141 @@
+package loadfile
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+)
+
+// SliceMergeStrategy chooses what decoding a slice onto a non empty slice does
+type SliceMergeStrategy int
+
+const (
+	// SliceReplace replaces the existing elements, as the decoders do
+	SliceReplace SliceMergeStrategy = iota
+
+	// SliceAppend appends the file's elements to the existing ones
+	SliceAppend
+)
+
+// WithSliceMergeStrategy sets how slices in a file are decoded onto a target
+// which already has elements in them, e.g. defaults set in code, or an earlier
+// file given to LoadLayered. Only slices the file sets are affected, and only
+// for JSON and YAML.
+func WithSliceMergeStrategy(strategy SliceMergeStrategy) Option {
+	return func(l *Loader) {
+		l.sliceMerge = strategy
+	}
+}
+
+// appendingDecoder wraps decoder to append slices in the file to those
+// already in the target
+func appendingDecoder(format string, decoder DecoderFunc) DecoderFunc {
+	if format != docJSON && format != docYAML {
+		return decoder
+	}
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		doc, err := parseDocument(format, content)
+		if err != nil {
+			return err
+		}
+		target := reflect.ValueOf(into)
+		if target.Kind() != reflect.Ptr || target.IsNil() {
+			return decoder(bytes.NewReader(content), into)
+		}
+		target = target.Elem()
+		existing := existingSlices(target, format, doc, nil)
+		if err := decoder(bytes.NewReader(content), into); err != nil {
+			return err
+		}
+		for _, a := range existing {
+			decoded, ok := valueAtPath(target, a.path)
+			if !ok || decoded.Kind() != reflect.Slice {
+				continue
+			}
+			assignPath(target, a.path, reflect.AppendSlice(a.value, decoded))
+		}
+		return nil
+	}
+}
+
+// existingSlices returns a copy of each non empty slice in v which the
+// document sets
+func existingSlices(v reflect.Value, format string, doc interface{}, path []pathStep) []assignment {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return existingSlices(v.Elem(), format, doc, append(path, pathStep{kind: stepPtr}))
+	case reflect.Slice:
+		if _, ok := doc.([]interface{}); !ok || v.Len() == 0 {
+			return nil
+		}
+		snapshot := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(snapshot, v)
+		return []assignment{{path: append([]pathStep{}, path...), value: snapshot}}
+	case reflect.Struct:
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		found := []assignment{}
+		for key, child := range m {
+			index, ok := fieldForKey(v.Type(), format, key)
+			if !ok {
+				continue
+			}
+			step := pathStep{kind: stepField, field: index, key: key}
+			found = append(found, existingSlices(v.FieldByIndex(index), format, child, append(path, step))...)
+		}
+		return found
+	case reflect.Map:
+		m, ok := doc.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		found := []assignment{}
+		for key, child := range m {
+			elem := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			if !elem.IsValid() {
+				continue
+			}
+			step := pathStep{kind: stepKey, key: key}
+			found = append(found, existingSlices(elem, format, child, append(path, step))...)
+		}
+		return found
+	}
+	return nil
+}
+
+// valueAtPath returns the value at path within v, if everything on the way is
+// there
+func valueAtPath(v reflect.Value, path []pathStep) (reflect.Value, bool) {
+	for _, step := range path {
+		switch step.kind {
+		case stepPtr:
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		case stepField:
+			v = v.FieldByIndex(step.field)
+		case stepIndex:
+			if step.index >= v.Len() {
+				return reflect.Value{}, false
+			}
+			v = v.Index(step.index)
+		case stepKey:
+			v = v.MapIndex(reflect.ValueOf(step.key).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return reflect.Value{}, false
+			}
+		}
+	}
+	return v, true
+}
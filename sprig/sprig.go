@@ -0,0 +1,35 @@
+// Package sprig adds the Sprig template functions to loadfile's templates:
+//
+//	loader := loadfile.NewLoader(sprig.WithSprig())
+//
+// WithSprig implies loadfile.WithTemplate. As well as the Sprig functions,
+// toYaml is defined as Helm defines it, since Helm style templates expect it.
+//
+// This lives outside of the core package to keep the Sprig dependency, and
+// everything it brings, out of builds which don't need it.
+package sprig
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/daemonl/loadfile"
+	"gopkg.in/yaml.v2"
+)
+
+// WithSprig makes the Sprig functions available to templates
+func WithSprig() loadfile.Option {
+	funcs := sprig.TxtFuncMap()
+	funcs["toYaml"] = toYAML
+	return loadfile.WithTemplateFuncs(template.FuncMap(funcs))
+}
+
+// toYAML encodes v as YAML without a trailing newline, or "" if it can't be
+func toYAML(v interface{}) string {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
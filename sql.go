@@ -0,0 +1,162 @@
+package loadfile
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var reSQLFilename = regexp.MustCompile(`^sql:\/\/([^\/?]+)\/([^\/?]+)\/([^?]+)(?:\?(.*))?$`)
+
+// reSQLDSN matches the dsn of a sql:// filename, for redactURL
+var reSQLDSN = regexp.MustCompile(`^(sql:\/\/[^?]*\?(?:.*&)?dsn=)[^&]*`)
+
+// reSQLIdentifier matches the table and column names which are allowed, as
+// they can't be query parameters
+var reSQLIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLLoader reads a file stored as a text or blob column of a database table,
+// named like
+//
+//	sql://postgres/app_config/service.yaml?dsn=postgres%3A%2F%2Fhost%2Fdb
+//
+// which runs SELECT value FROM app_config WHERE name = $1 with "service.yaml",
+// through database/sql. The key's extension gives the format. The key and
+// value columns can be changed with the key= and value= query parameters, e.g.
+// ?key=name&value=content&dsn=....
+//
+// The driver, postgres above, must be registered by the caller, usually by
+// importing it, as this package doesn't depend on any. A connection pool is
+// opened for each driver and dsn on first use and reused after that. The dsn
+// is removed from filenames in errors, but to keep credentials out of
+// filenames entirely set DB, then dsn isn't needed.
+//
+// A missing row is ErrNotFound.
+type SQLLoader struct {
+	// DB, when set, is used for every query rather than opening the dsn
+	DB *sql.DB
+
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+// sqlQuery is a sql:// filename split into its parts
+type sqlQuery struct {
+	driver, dsn, table, keyColumn, valueColumn, key string
+}
+
+func parseSQLFilename(filename string) (sqlQuery, error) {
+	parts := reSQLFilename.FindStringSubmatch(filename)
+	if len(parts) != 5 {
+		return sqlQuery{}, errors.New("Impossible bad match passed to SQLLoader")
+	}
+	params, err := url.ParseQuery(parts[4])
+	if err != nil {
+		return sqlQuery{}, err
+	}
+	key, err := url.PathUnescape(parts[3])
+	if err != nil {
+		return sqlQuery{}, err
+	}
+	q := sqlQuery{
+		driver:      parts[1],
+		dsn:         params.Get("dsn"),
+		table:       parts[2],
+		keyColumn:   params.Get("key"),
+		valueColumn: params.Get("value"),
+		key:         key,
+	}
+	if q.keyColumn == "" {
+		q.keyColumn = "name"
+	}
+	if q.valueColumn == "" {
+		q.valueColumn = "value"
+	}
+	for _, name := range []string{q.table, q.keyColumn, q.valueColumn} {
+		if !reSQLIdentifier.MatchString(name) {
+			return sqlQuery{}, fmt.Errorf("Invalid SQL identifier %q", name)
+		}
+	}
+	return q, nil
+}
+
+func (sl *SQLLoader) getDB(q sqlQuery) (*sql.DB, error) {
+	if sl.DB != nil {
+		return sl.DB, nil
+	}
+	if q.dsn == "" {
+		return nil, errors.New("sql:// filename has no dsn parameter, and SQLLoader has no DB")
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	id := q.driver + "\x00" + q.dsn
+	if db, ok := sl.dbs[id]; ok {
+		return db, nil
+	}
+	db, err := sql.Open(q.driver, q.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if sl.dbs == nil {
+		sl.dbs = map[string]*sql.DB{}
+	}
+	sl.dbs[id] = db
+	return db, nil
+}
+
+// sqlPlaceholder returns the first bind parameter in the syntax of driver
+func sqlPlaceholder(driver string) string {
+	switch strings.ToLower(driver) {
+	case "postgres", "postgresql", "pgx", "cloudsqlpostgres":
+		return "$1"
+	case "sqlserver", "mssql":
+		return "@p1"
+	case "oracle", "godror", "oci8":
+		return ":1"
+	}
+	return "?"
+}
+
+func (sl *SQLLoader) GetReader(filename string) (io.Reader, error) {
+	return sl.GetReaderContext(context.Background(), filename)
+}
+
+// GetReaderContext is GetReader, with ctx covering the query
+func (sl *SQLLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	q, err := parseSQLFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sl.getDB(q)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s",
+		q.valueColumn, q.table, q.keyColumn, sqlPlaceholder(q.driver))
+	var value []byte
+	err = db.QueryRowContext(ctx, query, q.key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no row in %s with %s %q", ErrNotFound, q.table, q.keyColumn, q.key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(value), nil
+}
+
+// formatHint uses the extension of the key, as the query string follows it
+func (sl *SQLLoader) formatHint(filename string, isFormat func(ext string) bool) string {
+	parts := reSQLFilename.FindStringSubmatch(filename)
+	if len(parts) != 5 {
+		return ""
+	}
+	return strings.TrimPrefix(path.Ext(parts[3]), ".")
+}
@@ -0,0 +1,112 @@
+package loadfile
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the latency buckets of LoaderStats
+var latencyBounds = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// LoaderStats is a snapshot of the counters a Loader keeps across every Load
+// made through it, including those made by LoadLayered, LoadDir and the like
+// for each of their files
+type LoaderStats struct {
+	Loads  int64
+	Errors int64
+
+	// Bytes is the total size of everything decoded, after decompression
+	Bytes int64
+
+	// Latency counts loads by how long they took, fetching included, in
+	// buckets of increasing Max
+	Latency []LatencyBucket
+}
+
+// LatencyBucket counts the loads which took longer than the Max of the
+// bucket before it and at most its own Max, which is 0 for the last bucket,
+// holding everything slower than the others
+type LatencyBucket struct {
+	Max   time.Duration
+	Count int64
+}
+
+// loaderStats is shared by a Loader and the copies made of it for a single
+// call, such as withBudget's
+type loaderStats struct {
+	mu      sync.Mutex
+	loads   int64
+	errors  int64
+	bytes   int64
+	latency [len(latencyBounds) + 1]int64
+}
+
+func (s *loaderStats) recordLoad(took time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	bucket := len(latencyBounds)
+	for i, bound := range latencyBounds {
+		if took <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	if err != nil {
+		s.errors++
+	}
+	s.latency[bucket]++
+}
+
+func (s *loaderStats) recordBytes(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytes += int64(n)
+}
+
+// Stats returns the counters kept across every load by l. A zero Loader,
+// rather than one from NewLoader, keeps none.
+func (l *Loader) Stats() LoaderStats {
+	stats := LoaderStats{Latency: make([]LatencyBucket, len(latencyBounds)+1)}
+	for i, bound := range latencyBounds {
+		stats.Latency[i].Max = bound
+	}
+	if l.stats == nil {
+		return stats
+	}
+	l.stats.mu.Lock()
+	defer l.stats.mu.Unlock()
+	stats.Loads = l.stats.loads
+	stats.Errors = l.stats.errors
+	stats.Bytes = l.stats.bytes
+	for i, count := range l.stats.latency {
+		stats.Latency[i].Count = count
+	}
+	return stats
+}
+
+// ResetStats sets every counter returned by Stats back to zero
+func (l *Loader) ResetStats() {
+	if l.stats == nil {
+		return
+	}
+	l.stats.mu.Lock()
+	defer l.stats.mu.Unlock()
+	l.stats.loads, l.stats.errors, l.stats.bytes = 0, 0, 0
+	l.stats.latency = [len(latencyBounds) + 1]int64{}
+}
@@ -0,0 +1,61 @@
+package loadfile
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// WithTemplate renders JSON, YAML, XML and TOML files as text/template
+// templates before decoding them. The data is a map with Env, the
+// environment variables, e.g. {{ .Env.HOME }}, and referring to a variable
+// which isn't set is an error. Templates are rendered before
+// WithEnvExpansion, so a template can output ${VAR} placeholders, but a
+// placeholder can't output template actions.
+func WithTemplate() Option {
+	return func(l *Loader) {
+		l.template = true
+	}
+}
+
+// WithTemplateFuncs is WithTemplate, also making funcs available to
+// templates. It can be used more than once, later functions replacing earlier
+// ones of the same name. The sprig subpackage adds the Sprig functions.
+func WithTemplateFuncs(funcs template.FuncMap) Option {
+	return func(l *Loader) {
+		l.template = true
+		merged := template.FuncMap{}
+		for name, fn := range l.templateFuncs {
+			merged[name] = fn
+		}
+		for name, fn := range funcs {
+			merged[name] = fn
+		}
+		l.templateFuncs = merged
+	}
+}
+
+// renderTemplate executes content as a template named after the file
+func (l *Loader) renderTemplate(filename string, content []byte) ([]byte, error) {
+	tmpl, err := template.New(path.Base(filename)).
+		Funcs(l.templateFuncs).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{}
+	for _, pair := range os.Environ() {
+		if idx := strings.Index(pair, "="); idx > 0 {
+			env[pair[:idx]] = pair[idx+1:]
+		}
+	}
+	rendered := &bytes.Buffer{}
+	if err := tmpl.Execute(rendered, map[string]interface{}{"Env": env}); err != nil {
+		return nil, err
+	}
+	return rendered.Bytes(), nil
+}
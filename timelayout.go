@@ -0,0 +1,43 @@
+package loadfile
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WithTimeLayout decodes time.Time fields of JSON and YAML files from strings
+// in layout, as time.Parse takes it, e.g. "2006/01/02 15:04", falling back to
+// RFC 3339, which is what they are decoded from otherwise.
+func WithTimeLayout(layout string) Option {
+	return func(l *Loader) {
+		l.timeLayout = layout
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeLayoutHook is the typeHook for WithTimeLayout
+type timeLayoutHook struct {
+	layout string
+}
+
+func (timeLayoutHook) matches(t reflect.Type) bool {
+	return t == timeType
+}
+
+func (h timeLayoutHook) decode(format string, t reflect.Type, node interface{}) (reflect.Value, error) {
+	switch val := node.(type) {
+	case time.Time:
+		return reflect.ValueOf(val), nil
+	case string:
+		if parsed, err := time.Parse(h.layout, val); err == nil {
+			return reflect.ValueOf(parsed), nil
+		}
+		if parsed, err := time.Parse(time.RFC3339, val); err == nil {
+			return reflect.ValueOf(parsed), nil
+		}
+		return reflect.Value{}, fmt.Errorf("Cannot parse %q as a time in the layout %q or RFC 3339", val, h.layout)
+	}
+	return reflect.Value{}, fmt.Errorf("Cannot decode %T as a time", node)
+}
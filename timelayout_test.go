@@ -0,0 +1,45 @@
+package loadfile
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeLayout(t *testing.T) {
+	custom := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	fallback := time.Date(2024, 3, 5, 14, 30, 0, 0, time.FixedZone("", 10*60*60))
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    time.Time
+		wantErr string
+	}{
+		{name: "yaml layout", file: "app.yaml", content: "at: 2024/03/05 14:30\n", want: custom},
+		{name: "json layout", file: "app.json", content: `{"at": "2024/03/05 14:30"}`, want: custom},
+		{name: "yaml fallback", file: "app.yaml", content: "at: 2024-03-05T14:30:00+10:00\n", want: fallback},
+		{name: "json fallback", file: "app.json", content: `{"at": "2024-03-05T14:30:00+10:00"}`, want: fallback},
+		{name: "invalid", file: "app.json", content: `{"at": "yesterday"}`, wantErr: `"yesterday"`},
+		{name: "wrong layout", file: "app.yaml", content: "at: 05/03/2024 14:30\n", wantErr: "2006/01/02 15:04"},
+		{name: "number", file: "app.json", content: `{"at": 1709649000}`, wantErr: "as a time"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct{ At time.Time }{}
+			err := NewLoader(WithTimeLayout("2006/01/02 15:04")).Load(filename, &into)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want an error with %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !into.At.Equal(tc.want) {
+				t.Errorf("got %v, want %v", into.At, tc.want)
+			}
+		})
+	}
+}
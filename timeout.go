@@ -0,0 +1,114 @@
+package loadfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WithDefaultTimeout limits each fetch by a TypeLoader implementing
+// ContextLoader or InfoContextLoader, such as the S3 and HTTP loaders, to d,
+// including reading the content. Other loaders, including local files, are
+// unaffected.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(l *Loader) {
+		l.defaultTimeout = d
+	}
+}
+
+// fetch calls the TypeLoader, applying the default timeout and any budget
+func (l *Loader) fetch(rg TypeLoader, filename string) (io.Reader, error) {
+	r, _, err := l.fetchInfo(rg, filename)
+	return r, err
+}
+
+// fetchInfo is fetch, also returning Info when the TypeLoader implements
+// InfoLoader
+func (l *Loader) fetchInfo(rg TypeLoader, filename string) (io.Reader, Info, error) {
+	if l.budget == nil {
+		return l.fetchWithin(rg, filename, time.Time{})
+	}
+	if err := l.budget.check(); err != nil {
+		return nil, Info{}, err
+	}
+	r, info, err := l.fetchWithin(rg, filename, l.budget.deadline)
+	if err != nil {
+		if l.budget.check() != nil {
+			return nil, Info{}, fmt.Errorf("%w: %w", ErrBudgetExceeded, err)
+		}
+		return nil, Info{}, err
+	}
+	return &budgetReader{r: r, budget: l.budget}, info, nil
+}
+
+// fetchWithin calls the TypeLoader with a context ending at the default
+// timeout or deadline, whichever is first, when it can. The context is within
+// the Loader's own, for LoadAsync, when it has one, and carries the read
+// limit.
+func (l *Loader) fetchWithin(rg TypeLoader, filename string, deadline time.Time) (io.Reader, Info, error) {
+	parent := context.Background()
+	if l.ctx != nil {
+		if err := l.ctx.Err(); err != nil {
+			return nil, Info{}, err
+		}
+		parent = l.ctx
+	}
+	if l.defaultTimeout > 0 {
+		if end := time.Now().Add(l.defaultTimeout); deadline.IsZero() || end.Before(deadline) {
+			deadline = end
+		}
+	}
+	contextLoader, isContext := rg.(ContextLoader)
+	infoContextLoader, isInfoContext := rg.(InfoContextLoader)
+	if !(isContext || isInfoContext) || (deadline.IsZero() && l.ctx == nil && l.readLimit <= 0) {
+		if infoLoader, ok := rg.(InfoLoader); ok {
+			return infoLoader.GetReaderInfo(filename)
+		}
+		r, err := rg.GetReader(filename)
+		return r, Info{}, err
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if deadline.IsZero() {
+		ctx, cancel = context.WithCancel(parent)
+	} else {
+		ctx, cancel = context.WithDeadline(parent, deadline)
+	}
+	ctx = withReadLimit(ctx, l.readLimit)
+	var r io.Reader
+	var info Info
+	var err error
+	if isInfoContext {
+		r, info, err = infoContextLoader.GetReaderInfoContext(ctx, filename)
+	} else {
+		r, err = contextLoader.GetReaderContext(ctx, filename)
+	}
+	if err != nil {
+		cancel()
+		return nil, Info{}, err
+	}
+	// The context has to outlive GetReaderContext while the body is read
+	return &cancelReadCloser{Reader: r, cancel: cancel}, info, nil
+}
+
+// cancelReadCloser cancels its context when closed
+type cancelReadCloser struct {
+	io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	if closer, ok := c.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (c *cancelReadCloser) FormatExtension() string {
+	if fr, ok := c.Reader.(FormatReader); ok {
+		return fr.FormatExtension()
+	}
+	return ""
+}
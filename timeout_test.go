@@ -0,0 +1,51 @@
+package loadfile
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hintingContextLoader serves YAML through a FormatReader, as a backend with
+// content types does
+type hintingContextLoader struct{}
+
+func (hintingContextLoader) GetReader(filename string) (io.Reader, error) {
+	return hintingContextLoader{}.GetReaderContext(context.Background(), filename)
+}
+
+func (hintingContextLoader) GetReaderContext(ctx context.Context, filename string) (io.Reader, error) {
+	return formatReader{Reader: strings.NewReader("port: 8080\n"), ext: "yaml"}, nil
+}
+
+func TestTimeoutKeepsFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []Option
+	}{{
+		name: "no timeout",
+	}, {
+		name:    "default timeout",
+		options: []Option{WithDefaultTimeout(time.Minute)},
+	}, {
+		name:    "total timeout",
+		options: []Option{WithTotalTimeout(time.Minute)},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLoader(tc.options...)
+			if err := l.Register(regexp.MustCompile(`^hint:\/\/`), hintingContextLoader{}); err != nil {
+				t.Fatal(err)
+			}
+			got := struct{ Port int }{}
+			if err := l.Load("hint://config", &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Port != 8080 {
+				t.Errorf("got port %d, want 8080", got.Port)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package loadfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+func decodeTOML(r io.Reader, into interface{}) error {
+	return toml.NewDecoder(r).Decode(into)
+}
+
+// tomlUnknownKeys returns the dotted path of each key in a TOML file which no
+// field of t decodes, in the order they appear, from the decoder's own
+// strict mode
+func tomlUnknownKeys(content []byte, t reflect.Type) ([]string, error) {
+	dec := toml.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(reflect.New(t).Interface())
+	var missing *toml.StrictMissingError
+	if errors.As(err, &missing) {
+		keys := make([]string, len(missing.Errors))
+		for i, keyErr := range missing.Errors {
+			keys[i] = strings.Join(keyErr.Key(), ".")
+		}
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
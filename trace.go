@@ -0,0 +1,180 @@
+package loadfile
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Trace is the tree of steps taken by a load, from LoadWithTrace
+type Trace struct {
+	Steps []*TraceStep
+}
+
+// TraceStep is one step of a load, with the steps it took in turn
+type TraceStep struct {
+	// Op is what was done: load, layer, fetch, decompress, transform,
+	// template, expand, includes, include, merge, refs, environment, decode
+	// or secrets
+	Op string
+
+	// Name is the file the step was for, redacted as in errors
+	Name string
+
+	// Detail says more about the step, e.g. the TypeLoader which served a
+	// fetch, the format decoded, or that an include was already fetched
+	Detail string
+
+	Start    time.Time
+	Duration time.Duration
+
+	// Err is the error the step failed with, if it did
+	Err error
+
+	Steps []*TraceStep
+}
+
+// String returns the trace as an indented tree, one step per line
+func (t *Trace) String() string {
+	b := &strings.Builder{}
+	for _, step := range t.Steps {
+		step.write(b, 0)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (s *TraceStep) write(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString(s.Op)
+	if s.Name != "" {
+		b.WriteString(" " + s.Name)
+	}
+	if s.Detail != "" {
+		b.WriteString(" " + s.Detail)
+	}
+	fmt.Fprintf(b, " (%s)", s.Duration)
+	if s.Err != nil {
+		b.WriteString(": " + s.Err.Error())
+	}
+	b.WriteString("\n")
+	for _, step := range s.Steps {
+		step.write(b, depth+1)
+	}
+}
+
+// LoadWithTrace is Load, also returning a Trace of the steps it took, such as
+// each file fetched and the TypeLoader which served it, includes and refs
+// resolved, templates rendered and merges applied, nested in the order they
+// were taken. The trace is returned with any error, showing the step which
+// failed. Steps are only recorded by LoadWithTrace and TraceLoads, other
+// loads skip them.
+func (l *Loader) LoadWithTrace(filename string, into interface{}) (*Trace, error) {
+	return l.TraceLoads(func(l *Loader) error {
+		return l.Load(filename, into)
+	})
+}
+
+// LoadWithTrace loads a file, returning the Trace of the steps taken, using
+// the default loader
+func LoadWithTrace(filename string, into interface{}) (*Trace, error) {
+	return DefaultLoader.LoadWithTrace(filename, into)
+}
+
+// LoadLayeredWithTrace is LoadLayered, also returning a Trace with a layer
+// step for each file merged into into
+func (l *Loader) LoadLayeredWithTrace(into interface{}, filenames ...string) (*Trace, error) {
+	return l.TraceLoads(func(l *Loader) error {
+		return l.LoadLayered(into, filenames...)
+	})
+}
+
+// TraceLoads calls fn with a copy of the Loader which records the steps of
+// everything loaded through it, returning them as a Trace with fn's error. It
+// traces what LoadWithTrace can't, such as LoadLayeredWithSources or several
+// loads together. Loads run at once, by LoadAsync, each get their own steps.
+func (l *Loader) TraceLoads(fn func(l *Loader) error) (*Trace, error) {
+	loader := *l
+	loader.tracer = &tracer{record: &traceRecord{}}
+	err := fn(&loader)
+	return loader.tracer.record.trace(), err
+}
+
+// traceRecord holds the steps recorded by a tracer and those forked from it
+type traceRecord struct {
+	mu    sync.Mutex
+	steps []*TraceStep
+}
+
+func (r *traceRecord) trace() *Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Trace{Steps: r.steps}
+}
+
+// tracer records the steps of a load. A nil tracer records nothing. Each
+// goroutine loading needs its own, from fork, as the open steps new ones are
+// nested in are those of one goroutine.
+type tracer struct {
+	record *traceRecord
+	open   []*TraceStep
+}
+
+// fork returns a tracer for another goroutine, nesting its steps in the step
+// open now
+func (tr *tracer) fork() *tracer {
+	if tr == nil {
+		return nil
+	}
+	tr.record.mu.Lock()
+	defer tr.record.mu.Unlock()
+	forked := &tracer{record: tr.record}
+	if len(tr.open) > 0 {
+		forked.open = []*TraceStep{tr.open[len(tr.open)-1]}
+	}
+	return forked
+}
+
+// begin starts a step within the one open, returning it to be ended, with
+// detail joined as its Detail
+func (tr *tracer) begin(op, name string, detail ...string) *TraceStep {
+	if tr == nil {
+		return nil
+	}
+	tr.record.mu.Lock()
+	defer tr.record.mu.Unlock()
+	step := &TraceStep{Op: op, Name: redactURL(name), Detail: strings.Join(detail, ""), Start: time.Now()}
+	if len(tr.open) == 0 {
+		tr.record.steps = append(tr.record.steps, step)
+	} else {
+		parent := tr.open[len(tr.open)-1]
+		parent.Steps = append(parent.Steps, step)
+	}
+	tr.open = append(tr.open, step)
+	return step
+}
+
+// beginFetch starts a fetch step, naming the TypeLoader by its type
+func (tr *tracer) beginFetch(filename string, rg TypeLoader) *TraceStep {
+	if tr == nil {
+		return nil
+	}
+	return tr.begin("fetch", filename, "by ", fmt.Sprintf("%T", rg))
+}
+
+// end ends step, and any steps within it left open
+func (tr *tracer) end(step *TraceStep, err error) {
+	if tr == nil || step == nil {
+		return
+	}
+	tr.record.mu.Lock()
+	defer tr.record.mu.Unlock()
+	step.Duration = time.Since(step.Start)
+	step.Err = err
+	for i := len(tr.open) - 1; i >= 0; i-- {
+		if tr.open[i] == step {
+			tr.open = tr.open[:i]
+			break
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package loadfile
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadLayeredWithTrace(t *testing.T) {
+	base := writeTestFile(t, "base.yaml", "host: localhost\nport: 80\n")
+	override := writeTestFile(t, "override.yaml", "port: 8080\n")
+	got := struct {
+		Host string
+		Port int
+	}{}
+	trace, err := NewLoader().LoadLayeredWithTrace(&got, base, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Host != "localhost" || got.Port != 8080 {
+		t.Errorf("got %+v", got)
+	}
+	if len(trace.Steps) != 2 {
+		t.Fatalf("got %d steps, want 2:\n%s", len(trace.Steps), trace)
+	}
+	for i, want := range []struct{ name, detail string }{
+		{base, ""},
+		{override, "merged over 1 earlier"},
+	} {
+		step := trace.Steps[i]
+		if step.Op != "layer" || step.Name != want.name || step.Detail != want.detail {
+			t.Errorf("step %d: got %s %s %q", i, step.Op, step.Name, step.Detail)
+		}
+		if len(step.Steps) == 0 || step.Steps[0].Op != "load" {
+			t.Errorf("step %d: want a load within the layer:\n%s", i, trace)
+		}
+	}
+}
+
+func TestTraceLoadsConcurrently(t *testing.T) {
+	filenames := []string{}
+	for _, name := range []string{"a.json", "b.json", "c.json", "d.json"} {
+		filenames = append(filenames, writeTestFile(t, name, `{"name": "`+name+`"}`))
+	}
+	trace, err := NewLoader().TraceLoads(func(l *Loader) error {
+		handles := []*LoadHandle{}
+		for _, filename := range filenames {
+			handles = append(handles, l.LoadAsync(filename, &map[string]interface{}{}))
+		}
+		for _, h := range handles {
+			if err := h.Wait(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.Steps) != len(filenames) {
+		t.Fatalf("got %d steps, want %d:\n%s", len(trace.Steps), len(filenames), trace)
+	}
+	for _, step := range trace.Steps {
+		if step.Op != "load" {
+			t.Errorf("got %s step at the top, want load:\n%s", step.Op, trace)
+		}
+		for _, child := range step.Steps {
+			if child.Name != "" && child.Name != step.Name {
+				t.Errorf("%s %s nested in the load of %s:\n%s", child.Op, filepath.Base(child.Name), filepath.Base(step.Name), trace)
+			}
+		}
+		if len(step.Steps) == 0 {
+			t.Errorf("load of %s has no steps", step.Name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package loadfile
+
+// WithTransform adds a function which rewrites the content of every file
+// before it is decoded, e.g. to strip a legacy header line. Transforms run in
+// the order they were added, on the decompressed, UTF-8 content, before any
+// other preprocessing such as templates, includes or WithMaxDepth. As a
+// transform needs the whole content, the file is read into memory first, even
+// for formats which would otherwise be decoded as they are read. Iterate
+// doesn't apply transforms.
+func WithTransform(fn func([]byte) ([]byte, error)) Option {
+	return func(l *Loader) {
+		l.transforms = append(l.transforms, fn)
+	}
+}
+
+// transform applies each of the Loader's transforms to content in turn
+func (l *Loader) transform(content []byte) ([]byte, error) {
+	for _, fn := range l.transforms {
+		var err error
+		content, err = fn(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
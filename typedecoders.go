@@ -0,0 +1,84 @@
+package loadfile
+
+import (
+	"reflect"
+	"sync"
+)
+
+var typeDecodersLock sync.RWMutex
+var typeDecoders = map[reflect.Type]func([]byte, interface{}) error{}
+
+// RegisterTypeDecoder sets fn to decode every value of type t, whatever the
+// format, e.g. a time.Time written in a nonstandard layout. fn is called with
+// a pointer to a new t, which is then set on the target.
+//
+// A type decoder takes precedence over the format's decoder, including any
+// UnmarshalJSON or UnmarshalYAML methods on t. When the whole target is a t, fn
+// receives the whole file. Values of type t within the target receive that
+// value as it is written in the file, e.g. "2006/01/02" with its quotes from
+// JSON, or 1.10 from YAML, encoded on its own with any aliases expanded, which
+// yaml.Unmarshal reads for JSON and YAML alike. Values within the target are
+// only found for JSON and YAML files.
+//
+// Like RegisterFormat it is intended to be called from an init function.
+func RegisterTypeDecoder(t reflect.Type, fn func([]byte, interface{}) error) {
+	typeDecodersLock.Lock()
+	defer typeDecodersLock.Unlock()
+	typeDecoders[t] = fn
+}
+
+// hasTypeDecoders returns true if there are type decoders, as there were when
+// the Loader was frozen if it is
+func (l *Loader) hasTypeDecoders() bool {
+	if r := l.frozenRegistries(); r != nil {
+		return len(r.typeDecoders) > 0
+	}
+	typeDecodersLock.RLock()
+	defer typeDecodersLock.RUnlock()
+	return len(typeDecoders) > 0
+}
+
+// typeDecoder returns the type decoder for t, as it was when the Loader was
+// frozen if it is
+func (l *Loader) typeDecoder(t reflect.Type) func([]byte, interface{}) error {
+	if r := l.frozenRegistries(); r != nil {
+		return r.typeDecoders[t]
+	}
+	return getTypeDecoder(t)
+}
+
+func getTypeDecoder(t reflect.Type) func([]byte, interface{}) error {
+	typeDecodersLock.RLock()
+	defer typeDecodersLock.RUnlock()
+	return typeDecoders[t]
+}
+
+// registeredTypes is the typeHook for types given to RegisterTypeDecoder, as
+// loader has them
+type registeredTypes struct {
+	loader *Loader
+}
+
+func (rt registeredTypes) matches(t reflect.Type) bool {
+	return rt.loader.typeDecoder(t) != nil
+}
+
+// decodeWhole decodes content with the type decoder for the whole target,
+// returning false if there isn't one
+func (rt registeredTypes) decodeWhole(content []byte, into interface{}) (bool, error) {
+	fn := rt.loader.typeDecoder(reflect.TypeOf(into).Elem())
+	if fn == nil {
+		return false, nil
+	}
+	return true, fn(content, into)
+}
+
+// decodeRaw calls the type decoder with the value as it is written in the
+// file
+func (rt registeredTypes) decodeRaw(format string, t reflect.Type, content []byte) (reflect.Value, error) {
+	value := reflect.New(t)
+	if err := rt.loader.typeDecoder(t)(content, value.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return value.Elem(), nil
+}
@@ -0,0 +1,56 @@
+package loadfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testRawValue records the content its type decoder is given
+type testRawValue string
+
+func init() {
+	RegisterTypeDecoder(reflect.TypeOf(testRawValue("")), func(content []byte, into interface{}) error {
+		*into.(*testRawValue) = testRawValue(content)
+		return nil
+	})
+}
+
+func TestTypeDecoderContent(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		file      string
+		content   string
+		want      testRawValue
+		wantOther string
+		wantBig   uint64
+	}{
+		{name: "yaml trailing zero", file: "app.yaml", content: "v: 1.10\nother: 1.10\n", want: "1.10\n", wantOther: "1.10"},
+		{name: "yaml leading zero", file: "app.yaml", content: "v: 01234\n", want: "01234\n"},
+		{name: "yaml 1.1 bool", file: "app.yaml", content: "v: yes\n", want: "yes\n"},
+		{name: "yaml quoted", file: "app.yaml", content: "v: '1.10'\n", want: "'1.10'\n"},
+		{name: "yaml alias", file: "app.yaml", content: "other: &a 1.10\nv: *a\n", want: "1.10\n", wantOther: "1.10"},
+		{name: "yaml mapping", file: "app.yaml", content: "v:\n  a: 1.10 # kept\n", want: "a: 1.10 # kept\n"},
+		{name: "json number", file: "app.json", content: `{"v": 1.10, "big": 18446744073709551615}`, want: "1.10", wantBig: 18446744073709551615},
+		{name: "json big number", file: "app.json", content: `{"v": 18446744073709551616}`, want: "18446744073709551616"},
+		{name: "json string", file: "app.json", content: `{"v": "a<b \u00e9", "other": "1.10"}`, want: `"a<b \u00e9"`, wantOther: "1.10"},
+		{name: "json object", file: "app.json", content: `{"v": {"a": 1.0,  "b": [1e3]}}`, want: `{"a": 1.0,  "b": [1e3]}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			got := struct {
+				V     testRawValue
+				Other string
+				Big   uint64
+			}{}
+			if err := NewLoader().Load(filename, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.V != tc.want {
+				t.Errorf("decoder got %q, want %q", got.V, tc.want)
+			}
+			if got.Other != tc.wantOther || got.Big != tc.wantBig {
+				t.Errorf("siblings are %q and %d, want %q and %d", got.Other, got.Big, tc.wantOther, tc.wantBig)
+			}
+		})
+	}
+}
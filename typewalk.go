@@ -0,0 +1,622 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// typeHook decodes the values found where the target has a type the hook
+// matches, in place of the format's decoder. It is also a genericHook or a
+// rawHook.
+type typeHook interface {
+	matches(t reflect.Type) bool
+}
+
+// genericHook is a typeHook which decodes generic values, as parseDocument
+// returns them
+type genericHook interface {
+	decode(format string, t reflect.Type, node interface{}) (reflect.Value, error)
+}
+
+// rawHook is a typeHook which decodes a value from its own encoding, as it
+// was written in the file, rather than from generic values: the value's JSON
+// as it is in the file, or its YAML node encoded alone
+type rawHook interface {
+	decodeRaw(format string, t reflect.Type, content []byte) (reflect.Value, error)
+}
+
+// typeHooks returns the hooks which apply to loads by l
+func (l *Loader) typeHooks() []typeHook {
+	hooks := []typeHook{}
+	if l.hasTypeDecoders() {
+		hooks = append(hooks, registeredTypes{loader: l})
+	}
+	if l.hasUnions() {
+		hooks = append(hooks, unionHook{loader: l})
+	}
+	if l.durationStrings {
+		hooks = append(hooks, durationHook{})
+	}
+	if l.timeLayout != "" {
+		hooks = append(hooks, timeLayoutHook{layout: l.timeLayout})
+	}
+	if l.stringCoercion {
+		hooks = append(hooks, stringCoercionHook{})
+	}
+	return hooks
+}
+
+// typedDecoder wraps decoder so that values matched by hooks are decoded by
+// the hooks, and the strings of nested fields by nested. The file is walked
+// alongside the target type, matched values are decoded by their hook and
+// replaced with null, then the rest is decoded as usual and the hook values
+// are set on the target. YAML is walked as yaml.v3 nodes and JSON with its
+// numbers as written, so everything else decodes from the file as it was, and
+// a file with nothing matched is decoded unchanged. Only JSON and YAML can be
+// walked, other formats only have the whole target checked.
+func typedDecoder(format string, decoder DecoderFunc, hooks []typeHook, nested nestedFunc) DecoderFunc {
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		for _, h := range hooks {
+			if rt, ok := h.(registeredTypes); ok {
+				if ok, err := rt.decodeWhole(content, into); ok {
+					return err
+				}
+			}
+		}
+
+		walker := &typeWalker{format: format, hooks: hooks, nested: nested}
+		t := reflect.TypeOf(into).Elem()
+		switch format {
+		case docJSON:
+			if !json.Valid(content) {
+				// Leave syntax errors to the decoder to report
+				return decoder(bytes.NewReader(content), into)
+			}
+			walked, changed, err := walker.walkJSON(t, bytes.TrimSpace(content), nil)
+			if err != nil {
+				return err
+			}
+			if changed {
+				content = walked
+			}
+		case docYAML:
+			doc := &yamlv3.Node{}
+			if err := yamlv3.Unmarshal(content, doc); err != nil || len(doc.Content) != 1 {
+				// Leave syntax errors to the decoder to report
+				return decoder(bytes.NewReader(content), into)
+			}
+			walked, err := walker.walkNode(t, doc.Content[0], nil)
+			if err != nil {
+				return err
+			}
+			if walked != nil {
+				doc.Content[0] = walked
+				walker.expandStaleAliases(doc)
+				if content, err = yamlv3.Marshal(doc); err != nil {
+					return err
+				}
+			}
+		}
+		if err := decoder(bytes.NewReader(content), into); err != nil {
+			return err
+		}
+		target := reflect.ValueOf(into).Elem()
+		for _, a := range walker.assignments {
+			assignPath(target, a.path, a.value)
+		}
+		return nil
+	}
+}
+
+// needsHooks returns true if into is a pointer to a type which has, anywhere
+// within it, a type matched by one of the hooks
+func needsHooks(into interface{}, hooks []typeHook) bool {
+	if len(hooks) == 0 {
+		return false
+	}
+	t := reflect.TypeOf(into)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return false
+	}
+	return containsHookedType(t.Elem(), hooks, map[reflect.Type]bool{})
+}
+
+func containsHookedType(t reflect.Type, hooks []typeHook, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	for _, h := range hooks {
+		if h.matches(t) {
+			return true
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsHookedType(t.Elem(), hooks, seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if containsHookedType(t.Field(i).Type, hooks, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type stepKind int
+
+const (
+	stepPtr stepKind = iota
+	stepField
+	stepKey
+	stepIndex
+)
+
+// pathStep is one step from a value to a value within it
+type pathStep struct {
+	kind  stepKind
+	field []int
+	key   string
+	index int
+}
+
+type assignment struct {
+	path  []pathStep
+	value reflect.Value
+}
+
+// nestedFunc decodes the string value of a nested field as the format of ext
+type nestedFunc func(ext, content string, into interface{}) error
+
+// typeWalker walks a document alongside the type it will be decoded into,
+// collecting the values decoded by hooks and nested
+type typeWalker struct {
+	format      string
+	hooks       []typeHook
+	nested      nestedFunc
+	assignments []assignment
+
+	// rewritten is each anchored YAML node replaced by a copy with values
+	// decoded, which its aliases must no longer refer to
+	rewritten map[*yamlv3.Node]bool
+}
+
+// hookFor returns the first hook matching t, or nil
+func (w *typeWalker) hookFor(t reflect.Type) typeHook {
+	for _, h := range w.hooks {
+		if h.matches(t) {
+			return h
+		}
+	}
+	return nil
+}
+
+// decodeHooked decodes the generic value node with h, to be set at path
+func (w *typeWalker) decodeHooked(h typeHook, t reflect.Type, node interface{}, path []pathStep) error {
+	value, err := h.(genericHook).decode(w.format, t, node)
+	return w.assignHooked(value, err, path)
+}
+
+// assignHooked records value, decoded by a hook, to be set at path
+func (w *typeWalker) assignHooked(value reflect.Value, err error, path []pathStep) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", pathString(path), err)
+	}
+	w.assignments = append(w.assignments, assignment{
+		path:  append([]pathStep{}, path...),
+		value: value,
+	})
+	return nil
+}
+
+var jsonNull = json.RawMessage("null")
+
+// walkJSON walks a JSON value, returning it with the values decoded replaced by
+// null, and whether there were any. Values which aren't changed are kept as
+// they were written, and those decoded by a rawHook are given to it as they
+// are in the file.
+func (w *typeWalker) walkJSON(t reflect.Type, raw json.RawMessage, path []pathStep) (json.RawMessage, bool, error) {
+	if bytes.Equal(raw, jsonNull) {
+		return raw, false, nil
+	}
+	if h := w.hookFor(t); h != nil {
+		if rh, ok := h.(rawHook); ok {
+			value, err := rh.decodeRaw(w.format, t, raw)
+			return jsonNull, true, w.assignHooked(value, err, path)
+		}
+		doc, err := decodeJSONNumbers(raw)
+		if err != nil {
+			return nil, false, err
+		}
+		return jsonNull, true, w.decodeHooked(h, t, normalizeDocument(doc), path)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return w.walkJSON(t.Elem(), raw, append(path, pathStep{kind: stepPtr}))
+	case reflect.Struct, reflect.Map:
+		if raw[0] != '{' || (t.Kind() == reflect.Map && t.Key().Kind() != reflect.String) {
+			return raw, false, nil
+		}
+		m := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, false, err
+		}
+		changed := false
+		for key, child := range m {
+			var childType reflect.Type
+			var step pathStep
+			if t.Kind() == reflect.Map {
+				childType, step = t.Elem(), pathStep{kind: stepKey, key: key}
+			} else {
+				index, ok := fieldForKey(t, w.format, key)
+				if !ok {
+					continue
+				}
+				step = pathStep{kind: stepField, field: index, key: key}
+				field := t.FieldByIndex(index)
+				childType = field.Type
+				if ext, ok := nestedFormat(field); ok && child[0] == '"' {
+					var s string
+					if err := json.Unmarshal(child, &s); err != nil {
+						return nil, false, err
+					}
+					if err := w.walkNested(ext, field.Type, s, append(path, step)); err != nil {
+						return nil, false, err
+					}
+					m[key], changed = jsonNull, true
+					continue
+				}
+			}
+			walked, childChanged, err := w.walkJSON(childType, child, append(path, step))
+			if err != nil {
+				return nil, false, err
+			}
+			if childChanged {
+				m[key], changed = walked, true
+			}
+		}
+		if !changed {
+			return raw, false, nil
+		}
+		encoded, err := encodeRawJSON(m)
+		return encoded, true, err
+	case reflect.Slice, reflect.Array:
+		if raw[0] != '[' {
+			return raw, false, nil
+		}
+		s := []json.RawMessage{}
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, false, err
+		}
+		changed := false
+		for i, child := range s {
+			walked, childChanged, err := w.walkJSON(t.Elem(), child, append(path, pathStep{kind: stepIndex, index: i}))
+			if err != nil {
+				return nil, false, err
+			}
+			if childChanged {
+				s[i], changed = walked, true
+			}
+		}
+		if !changed {
+			return raw, false, nil
+		}
+		encoded, err := encodeRawJSON(s)
+		return encoded, true, err
+	}
+	return raw, false, nil
+}
+
+// encodeRawJSON encodes v, which holds json.RawMessages, without escaping
+// the HTML characters of strings within them
+func encodeRawJSON(v interface{}) (json.RawMessage, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// walkNode walks a YAML node, returning what to replace it with when values
+// within it were decoded, with those values replaced by null, or nil when
+// nothing was. Nodes are copied rather than changed, as an anchored node can
+// be aliased where nothing is decoded.
+func (w *typeWalker) walkNode(t reflect.Type, node *yamlv3.Node, path []pathStep) (*yamlv3.Node, error) {
+	target := node
+	if node.Kind == yamlv3.AliasNode {
+		target = node.Alias
+	}
+	if target == nil || isNullNode(target) {
+		return nil, nil
+	}
+	if h := w.hookFor(t); h != nil {
+		if rh, ok := h.(rawHook); ok {
+			content, err := nodeYAML(target)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pathString(path), err)
+			}
+			value, err := rh.decodeRaw(w.format, t, content)
+			if err := w.assignHooked(value, err, path); err != nil {
+				return nil, err
+			}
+			return w.replaced(node, nullNode()), nil
+		}
+		doc, err := nodeDocument(target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pathString(path), err)
+		}
+		if err := w.decodeHooked(h, t, doc, path); err != nil {
+			return nil, err
+		}
+		return w.replaced(node, nullNode()), nil
+	}
+
+	var content []*yamlv3.Node
+	set := func(i int, child *yamlv3.Node) {
+		if content == nil {
+			content = append([]*yamlv3.Node{}, target.Content...)
+		}
+		content[i] = child
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return w.walkNode(t.Elem(), node, append(path, pathStep{kind: stepPtr}))
+	case reflect.Struct, reflect.Map:
+		if target.Kind != yamlv3.MappingNode || (t.Kind() == reflect.Map && t.Key().Kind() != reflect.String) {
+			return nil, nil
+		}
+		// Merged keys first, so the mapping's own keys win as they do
+		// when decoding
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			if !isMergeKey(target.Content[i]) {
+				continue
+			}
+			walked, err := w.walkMerged(t, target.Content[i+1], path)
+			if err != nil {
+				return nil, err
+			}
+			if walked != nil {
+				set(i+1, walked)
+			}
+		}
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			key, child := target.Content[i], target.Content[i+1]
+			if key.Kind != yamlv3.ScalarNode || isMergeKey(key) {
+				continue
+			}
+			var childType reflect.Type
+			var step pathStep
+			if t.Kind() == reflect.Map {
+				childType, step = t.Elem(), pathStep{kind: stepKey, key: key.Value}
+			} else {
+				index, ok := fieldForKey(t, docYAML, key.Value)
+				if !ok {
+					continue
+				}
+				step = pathStep{kind: stepField, field: index, key: key.Value}
+				field := t.FieldByIndex(index)
+				childType = field.Type
+				if ext, ok := nestedFormat(field); ok && child.Kind == yamlv3.ScalarNode && child.ShortTag() == "!!str" {
+					if err := w.walkNested(ext, field.Type, child.Value, append(path, step)); err != nil {
+						return nil, err
+					}
+					set(i+1, nullNode())
+					continue
+				}
+			}
+			walked, err := w.walkNode(childType, child, append(path, step))
+			if err != nil {
+				return nil, err
+			}
+			if walked != nil {
+				set(i+1, walked)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if target.Kind != yamlv3.SequenceNode {
+			return nil, nil
+		}
+		for i, child := range target.Content {
+			walked, err := w.walkNode(t.Elem(), child, append(path, pathStep{kind: stepIndex, index: i}))
+			if err != nil {
+				return nil, err
+			}
+			if walked != nil {
+				set(i, walked)
+			}
+		}
+	}
+	if content == nil {
+		return nil, nil
+	}
+	copied := *target
+	copied.Content = content
+	return w.replaced(node, &copied), nil
+}
+
+// walkMerged walks the value of a << key as a part of the mapping merging
+// it, returning what to replace it with as walkNode does
+func (w *typeWalker) walkMerged(t reflect.Type, value *yamlv3.Node, path []pathStep) (*yamlv3.Node, error) {
+	if value.Kind != yamlv3.SequenceNode {
+		return w.walkNode(t, value, path)
+	}
+	var content []*yamlv3.Node
+	for i, child := range value.Content {
+		walked, err := w.walkNode(t, child, path)
+		if err != nil {
+			return nil, err
+		}
+		if walked != nil {
+			if content == nil {
+				content = append([]*yamlv3.Node{}, value.Content...)
+			}
+			content[i] = walked
+		}
+	}
+	if content == nil {
+		return nil, nil
+	}
+	copied := *value
+	copied.Content = content
+	return &copied, nil
+}
+
+// replaced returns replacement for node, without the anchor of the node it
+// copies. An anchored node is recorded, so its aliases can be expanded.
+func (w *typeWalker) replaced(node, replacement *yamlv3.Node) *yamlv3.Node {
+	replacement.Anchor = ""
+	if node.Anchor != "" {
+		if w.rewritten == nil {
+			w.rewritten = map[*yamlv3.Node]bool{}
+		}
+		w.rewritten[node] = true
+	}
+	return replacement
+}
+
+// expandStaleAliases replaces the aliases left in node which refer to an
+// anchored node since rewritten with a copy of the node as it was
+func (w *typeWalker) expandStaleAliases(node *yamlv3.Node) {
+	if len(w.rewritten) == 0 {
+		return
+	}
+	for i, child := range node.Content {
+		if child.Kind == yamlv3.AliasNode && w.rewritten[child.Alias] {
+			expanded := copyNode(child.Alias)
+			expanded.Anchor = ""
+			node.Content[i] = expanded
+			continue
+		}
+		w.expandStaleAliases(child)
+	}
+}
+
+// walkNested decodes content, the string value of a field tagged
+// loadfile:"nested,ext", into a new t, to be set at path
+func (w *typeWalker) walkNested(ext string, t reflect.Type, content string, path []pathStep) error {
+	if ext == "" {
+		return fmt.Errorf("%s: loadfile:\"nested\" needs a format, e.g. loadfile:\"nested,yaml\"", pathString(path))
+	}
+	value := reflect.New(t)
+	if err := w.nested(ext, content, value.Interface()); err != nil {
+		return fmt.Errorf("%s: %w", pathString(path), err)
+	}
+	w.assignments = append(w.assignments, assignment{
+		path:  append([]pathStep{}, path...),
+		value: value.Elem(),
+	})
+	return nil
+}
+
+// assignPath sets the value at path within v, allocating pointers and maps on
+// the way
+func assignPath(v reflect.Value, path []pathStep, value reflect.Value) {
+	if len(path) == 0 {
+		v.Set(value)
+		return
+	}
+	step := path[0]
+	switch step.kind {
+	case stepPtr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		assignPath(v.Elem(), path[1:], value)
+	case stepField:
+		assignPath(v.FieldByIndex(step.field), path[1:], value)
+	case stepIndex:
+		if step.index < v.Len() {
+			assignPath(v.Index(step.index), path[1:], value)
+		}
+	case stepKey:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(step.key).Convert(v.Type().Key())
+		// Map values aren't addressable, so update a copy and put it back
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		assignPath(elem, path[1:], value)
+		v.SetMapIndex(key, elem)
+	}
+}
+
+// pathString formats a path as the dotted document keys it follows
+func pathString(path []pathStep) string {
+	parts := []string{}
+	for _, step := range path {
+		switch step.kind {
+		case stepField, stepKey:
+			parts = append(parts, step.key)
+		case stepIndex:
+			parts = append(parts, fmt.Sprint(step.index))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// fieldForKey returns the index of the struct field which the format's decoder
+// would decode key into: by json tag or case insensitive name for JSON, by
+// yaml tag or lower cased name for YAML. Embedded structs are followed as the
+// decoders do, JSON for untagged embedded structs and YAML for ",inline", and
+// for YAML untagged embedded structs too, as flattenEmbedded arranges.
+func fieldForKey(t reflect.Type, format, key string) ([]int, bool) {
+	var folded []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(format)
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		embedded := f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct
+		inline := embedded
+		if format == docYAML {
+			// yaml can't decode into an embedded struct of an unexported type
+			embedded = embedded && f.PkgPath == ""
+			inline = embedded || strings.Contains(tag, ",inline")
+		}
+		if inline && f.Type.Kind() == reflect.Struct {
+			if index, ok := fieldForKey(f.Type, format, key); ok {
+				return append([]int{i}, index...), true
+			}
+			// yaml still decodes an untagged embedded struct by its name
+			if !(format == docYAML && embedded) {
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+			if format == docYAML {
+				name = strings.ToLower(name)
+			}
+		}
+		if name == key {
+			return []int{i}, true
+		}
+		if format == docJSON && folded == nil && strings.EqualFold(name, key) {
+			folded = []int{i}
+		}
+	}
+	return folded, folded != nil
+}
@@ -0,0 +1,95 @@
+package loadfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedDecoderKeepsSiblings(t *testing.T) {
+	type service struct {
+		Timeout time.Duration     `json:"timeout" yaml:"timeout"`
+		Name    string            `json:"name" yaml:"name"`
+		Labels  map[string]string `json:"labels" yaml:"labels"`
+	}
+	type config struct {
+		Timeout  time.Duration          `json:"timeout" yaml:"timeout"`
+		Version  string                 `json:"version" yaml:"version"`
+		Flag     string                 `json:"flag" yaml:"flag"`
+		Code     string                 `json:"code" yaml:"code"`
+		Big      uint64                 `json:"big" yaml:"big"`
+		Defaults service                `json:"defaults" yaml:"defaults"`
+		Service  service                `json:"service" yaml:"service"`
+		Raw      map[string]interface{} `json:"raw" yaml:"raw"`
+	}
+
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    config
+	}{{
+		name:    "yaml scalars",
+		file:    "config.yaml",
+		content: "timeout: 30s\nversion: 1.10\nflag: yes\ncode: 01234\nbig: 18446744073709551615\n",
+		want:    config{Timeout: 30 * time.Second, Version: "1.10", Flag: "yes", Code: "01234", Big: 18446744073709551615},
+	}, {
+		name:    "json numbers",
+		file:    "config.json",
+		content: `{"timeout": "1m", "version": "1.10", "big": 18446744073709551615}`,
+		want:    config{Timeout: time.Minute, Version: "1.10", Big: 18446744073709551615},
+	}, {
+		name: "anchor aliased where nothing is decoded",
+		file: "config.yaml",
+		content: "defaults: &d\n  timeout: 5s\n  name: base\n" +
+			"raw: *d\n",
+		want: config{
+			Defaults: service{Timeout: 5 * time.Second, Name: "base"},
+			Raw:      map[string]interface{}{"timeout": "5s", "name": "base"},
+		},
+	}, {
+		name: "merged alias",
+		file: "config.yaml",
+		content: "defaults: &d\n  timeout: 5s\n  labels: {tier: yes}\n" +
+			"service:\n  <<: *d\n  name: web\n",
+		want: config{
+			Defaults: service{Timeout: 5 * time.Second, Labels: map[string]string{"tier": "yes"}},
+			Service:  service{Timeout: 5 * time.Second, Name: "web", Labels: map[string]string{"tier": "yes"}},
+		},
+	}, {
+		name: "own key wins over merged",
+		file: "config.yaml",
+		content: "defaults: &d\n  timeout: 5s\n" +
+			"service:\n  timeout: 10s\n  <<: *d\n",
+		want: config{
+			Defaults: service{Timeout: 5 * time.Second},
+			Service:  service{Timeout: 10 * time.Second},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := config{}
+			if err := NewLoader(WithDurationStrings()).Load(writeTestFile(t, tc.file, tc.content), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTypedDecoderErrorPath(t *testing.T) {
+	type config struct {
+		Services []struct {
+			Timeout time.Duration `yaml:"timeout"`
+		} `yaml:"services"`
+	}
+	err := NewLoader(WithDurationStrings()).Load(writeTestFile(t, "config.yaml", "services:\n- timeout: 1s\n- timeout: soon\n"), &config{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := "services.1.timeout: "; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q doesn't name %q", err, want)
+	}
+}
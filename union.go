@@ -0,0 +1,164 @@
+package loadfile
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type union struct {
+	field   string
+	mapping map[string]reflect.Type
+}
+
+var unionsLock sync.RWMutex
+var unions []union
+
+// RegisterUnion decodes values of an interface type, e.g. the elements of a
+// []Plugin, from JSON and YAML objects whose field key names the concrete
+// type, e.g. {"type": "http", "url": "..."} with mapping["http"] being
+// reflect.TypeOf(HTTPPlugin{}). The union applies to every interface type,
+// other than interface{}, which each type in mapping, or a pointer to it,
+// implements, the first registered winning. A mapping type can also be a
+// pointer type, to decode a pointer. The whole object, field key included, is
+// decoded into the concrete type, and a value missing from mapping is an error
+// naming it.
+//
+// Like RegisterFormat it is intended to be called from an init function.
+func RegisterUnion(field string, mapping map[string]reflect.Type) {
+	unionsLock.Lock()
+	defer unionsLock.Unlock()
+	unions = append(unions, union{field: field, mapping: mapping})
+}
+
+// registeredUnions returns the unions, as they were when the Loader was
+// frozen if it is
+func (l *Loader) registeredUnions() []union {
+	if r := l.frozenRegistries(); r != nil {
+		return r.unions
+	}
+	unionsLock.RLock()
+	defer unionsLock.RUnlock()
+	return unions
+}
+
+func (l *Loader) hasUnions() bool {
+	return len(l.registeredUnions()) > 0
+}
+
+// unionFor returns the first union whose every type implements t
+func (l *Loader) unionFor(t reflect.Type) (union, bool) {
+	if t.Kind() != reflect.Interface || t.NumMethod() == 0 {
+		return union{}, false
+	}
+	for _, u := range l.registeredUnions() {
+		if u.implements(t) {
+			return u, true
+		}
+	}
+	return union{}, false
+}
+
+func (u union) implements(t reflect.Type) bool {
+	for _, concrete := range u.mapping {
+		if !concrete.Implements(t) && !reflect.PtrTo(concrete).Implements(t) {
+			return false
+		}
+	}
+	return len(u.mapping) > 0
+}
+
+// unionHook is the typeHook for types given to RegisterUnion, decoding the
+// concrete types as loader decodes a file
+type unionHook struct {
+	loader *Loader
+}
+
+func (uh unionHook) matches(t reflect.Type) bool {
+	_, ok := uh.loader.unionFor(t)
+	return ok
+}
+
+// decodeRaw decodes a member from its JSON as written, or its YAML node
+// encoded alone, so that its values are decoded as written rather than as a
+// generic document holds them
+func (uh unionHook) decodeRaw(format string, t reflect.Type, content []byte) (reflect.Value, error) {
+	var doc interface{}
+	var err error
+	if format == docJSON {
+		doc, err = decodeJSONNumbers(content)
+	} else {
+		doc, err = parseDocument(format, content)
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	concrete, err := uh.concreteType(t, doc)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return uh.decodeConcrete(format, t, concrete, content)
+}
+
+// concreteType returns the type the discriminator of the generic object node
+// names for the union of t
+func (uh unionHook) concreteType(t reflect.Type, node interface{}) (reflect.Type, error) {
+	u, _ := uh.loader.unionFor(t)
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Cannot decode %T as %s, which needs an object", node, t)
+	}
+	discriminator, ok := object[u.field].(string)
+	if !ok {
+		return nil, fmt.Errorf("Missing %s, which %s needs to pick a type", u.field, t)
+	}
+	concrete, ok := u.mapping[discriminator]
+	if !ok {
+		return nil, fmt.Errorf("Unknown %s %q, expected one of %s", u.field, discriminator, strings.Join(u.names(), ", "))
+	}
+	return concrete, nil
+}
+
+func (uh unionHook) decodeConcrete(format string, t, concrete reflect.Type, content []byte) (reflect.Value, error) {
+	ptr := concrete.Kind() == reflect.Ptr
+	if ptr {
+		concrete = concrete.Elem()
+	}
+	value := reflect.New(concrete)
+	if err := uh.loader.decodeMember(format, content, value.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	if ptr || !concrete.Implements(t) {
+		return value, nil
+	}
+	return value.Elem(), nil
+}
+
+// decodeMember decodes content, a union member of a JSON or YAML document,
+// into into as the document is decoded, with yaml.v3 WithYAMLv3 and with the
+// type hooks and nested fields, so members can hold unions in turn
+func (l *Loader) decodeMember(format string, content []byte, into interface{}) error {
+	decoder := DecoderFunc(decodeJSON)
+	if format == docYAML {
+		decoder = decodeYAML
+		if l.yamlV3 {
+			decoder = l.decodeYAMLv3
+		}
+	}
+	if hooks := l.typeHooks(); needsTypedDecoder(into, hooks) {
+		decoder = typedDecoder(format, decoder, hooks, l.decodeNested)
+	}
+	return decoder(bytes.NewReader(content), into)
+}
+
+func (u union) names() []string {
+	names := make([]string, 0, len(u.mapping))
+	for name := range u.mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
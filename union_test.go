@@ -0,0 +1,95 @@
+package loadfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testStep interface{ stepName() string }
+
+type testShellStep struct {
+	Type    string
+	Name    string
+	Version string
+	Extra   interface{}
+	Timeout time.Duration
+}
+
+func (s testShellStep) stepName() string { return s.Name }
+
+type testGroupStep struct {
+	Type  string
+	Name  string
+	Steps []testStep
+}
+
+func (s *testGroupStep) stepName() string { return s.Name }
+
+func init() {
+	RegisterUnion("type", map[string]reflect.Type{
+		"shell": reflect.TypeOf(testShellStep{}),
+		"group": reflect.TypeOf(&testGroupStep{}),
+	})
+}
+
+func TestUnion(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		file    string
+		content string
+		want    []testStep
+		wantErr string
+	}{{
+		name:    "yaml",
+		file:    "steps.yaml",
+		content: "steps:\n- type: shell\n  name: a\n  version: 1.10\n",
+		want:    []testStep{testShellStep{Type: "shell", Name: "a", Version: "1.10"}},
+	}, {
+		name:    "nested yaml",
+		file:    "steps.yaml",
+		content: "steps:\n- type: group\n  name: g\n  steps:\n  - type: shell\n    name: b\n    version: 01234\n",
+		want: []testStep{&testGroupStep{Type: "group", Name: "g", Steps: []testStep{
+			testShellStep{Type: "shell", Name: "b", Version: "01234"},
+		}}},
+	}, {
+		name:    "yaml v3 untyped values",
+		opts:    []Option{WithYAMLv3()},
+		file:    "steps.yaml",
+		content: "steps:\n- type: shell\n  name: a\n  extra: yes\n",
+		want:    []testStep{testShellStep{Type: "shell", Name: "a", Extra: "yes"}},
+	}, {
+		name:    "nested json with hooks",
+		opts:    []Option{WithDurationStrings()},
+		file:    "steps.json",
+		content: `{"steps": [{"type": "group", "name": "g", "steps": [{"type": "shell", "name": "b", "timeout": "5s"}]}]}`,
+		want: []testStep{&testGroupStep{Type: "group", Name: "g", Steps: []testStep{
+			testShellStep{Type: "shell", Name: "b", Timeout: 5 * time.Second},
+		}}},
+	}, {
+		name:    "unknown type",
+		file:    "steps.yaml",
+		content: "steps:\n- type: group\n  steps:\n  - type: python\n",
+		wantErr: `Unknown type "python"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct{ Steps []testStep }{}
+			err := NewLoader(tc.opts...).Load(filename, &into)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(into.Steps, tc.want) {
+				t.Errorf("decoded %#v, want %#v", into.Steps, tc.want)
+			}
+		})
+	}
+}
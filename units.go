@@ -0,0 +1,284 @@
+package loadfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithDurationStrings decodes time.Duration fields from Go duration strings
+// such as "30s", "1h30m" or "-5m" in JSON as well as YAML. Numbers are still
+// taken as nanoseconds.
+func WithDurationStrings() Option {
+	return func(l *Loader) {
+		l.durationStrings = true
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// durationHook is the typeHook for WithDurationStrings
+type durationHook struct{}
+
+func (durationHook) matches(t reflect.Type) bool {
+	return t == durationType
+}
+
+func (durationHook) decode(format string, t reflect.Type, node interface{}) (reflect.Value, error) {
+	var d time.Duration
+	switch val := node.(type) {
+	case string:
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		d = parsed
+	case int:
+		d = time.Duration(val)
+	case int64:
+		d = time.Duration(val)
+	case float64:
+		d = time.Duration(val)
+	default:
+		return reflect.Value{}, fmt.Errorf("Cannot decode %T as a duration", node)
+	}
+	return reflect.ValueOf(d).Convert(t), nil
+}
+
+// Bytes is a size in bytes which decodes from a number, or a string with a
+// unit such as "256MiB", "1.5GiB" or "10 MB". KiB, MiB, GiB and TiB are powers
+// of 1024, KB, MB, GB and TB powers of 1000, B is bytes. Units are case
+// insensitive, and fractions are rounded to the nearest byte.
+type Bytes int64
+
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// ParseBytes parses a size as Bytes decodes it
+func ParseBytes(s string) (Bytes, error) {
+	s = strings.TrimSpace(s)
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+')
+	})
+	number, unit := s, ""
+	if split >= 0 {
+		number, unit = s[:split], strings.TrimSpace(s[split:])
+	}
+	multiplier, ok := byteUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("Invalid size %q: unknown unit %q", s, unit)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid size %q", s)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("Invalid size %q: negative", s)
+	}
+	size := math.Round(value * multiplier)
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("Invalid size %q: too large", s)
+	}
+	return Bytes(size), nil
+}
+
+// UnmarshalText decodes a size string, used by XML and TOML style decoders
+func (b *Bytes) UnmarshalText(text []byte) error {
+	parsed, err := ParseBytes(string(text))
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// UnmarshalJSON decodes a number of bytes or a size string
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Not a string, so a number
+		s = string(data)
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// UnmarshalYAML decodes a number of bytes or a size string. It is the yaml.v2
+// form, which yaml.v3 also accepts.
+func (b *Bytes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// Quantity is a number with an optional Kubernetes resource quantity suffix,
+// e.g. "500m", "2Gi", "1.5G" or "1e3", decoding from a string or a number.
+// Ki, Mi, Gi, Ti, Pi and Ei are powers of 1024, k, M, G, T, P and E powers of
+// 1000, and n, u and m are billionths, millionths and thousandths. Suffixes
+// are case sensitive, as m is milli and M mega. As in Kubernetes the value is
+// kept to milli precision, rounding anything finer up, so "2Gi" normalizes to
+// 2147483648 and "100m" to 0.1.
+type Quantity struct {
+	// milli is the value in thousandths, nil for zero
+	milli *big.Int
+}
+
+var reQuantity = regexp.MustCompile(`^([+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+))(?:[eE]([+-]?[0-9]+)|(Ki|Mi|Gi|Ti|Pi|Ei|[numkMGTPE])?)$`)
+
+var quantitySuffixes = map[string]*big.Rat{
+	"":   big.NewRat(1, 1),
+	"n":  big.NewRat(1, 1e9),
+	"u":  big.NewRat(1, 1e6),
+	"m":  big.NewRat(1, 1e3),
+	"k":  big.NewRat(1e3, 1),
+	"M":  big.NewRat(1e6, 1),
+	"G":  big.NewRat(1e9, 1),
+	"T":  big.NewRat(1e12, 1),
+	"P":  big.NewRat(1e15, 1),
+	"E":  big.NewRat(1e18, 1),
+	"Ki": big.NewRat(1<<10, 1),
+	"Mi": big.NewRat(1<<20, 1),
+	"Gi": big.NewRat(1<<30, 1),
+	"Ti": big.NewRat(1<<40, 1),
+	"Pi": big.NewRat(1<<50, 1),
+	"Ei": big.NewRat(1<<60, 1),
+}
+
+// ParseQuantity parses a quantity as Quantity decodes it
+func ParseQuantity(s string) (Quantity, error) {
+	match := reQuantity.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Quantity{}, fmt.Errorf("Invalid quantity %q", s)
+	}
+	value, ok := new(big.Rat).SetString(match[1])
+	if !ok {
+		return Quantity{}, fmt.Errorf("Invalid quantity %q", s)
+	}
+	if match[2] != "" {
+		exponent, err := strconv.Atoi(match[2])
+		if err != nil || exponent > 18 || exponent < -18 {
+			return Quantity{}, fmt.Errorf("Invalid quantity %q: exponent out of range", s)
+		}
+		power := func(n int) *big.Rat {
+			return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+		}
+		if exponent < 0 {
+			value.Quo(value, power(-exponent))
+		} else {
+			value.Mul(value, power(exponent))
+		}
+	} else {
+		value.Mul(value, quantitySuffixes[match[3]])
+	}
+	value.Mul(value, big.NewRat(1000, 1))
+
+	// Round up to a whole milli-unit, away from zero as Kubernetes does
+	milli := new(big.Int).Quo(value.Num(), value.Denom())
+	if new(big.Rat).SetInt(milli).Cmp(value) != 0 {
+		milli.Add(milli, big.NewInt(int64(value.Sign())))
+	}
+	return Quantity{milli: milli}, nil
+}
+
+func (q Quantity) milliValue() *big.Int {
+	if q.milli == nil {
+		return new(big.Int)
+	}
+	return q.milli
+}
+
+// clampInt64 returns n, or the nearest int64 when it is out of range
+func clampInt64(n *big.Int) int64 {
+	switch {
+	case n.IsInt64():
+		return n.Int64()
+	case n.Sign() > 0:
+		return math.MaxInt64
+	}
+	return math.MinInt64
+}
+
+// Value returns the quantity in whole units, rounded up as Kubernetes does,
+// e.g. 1 for "100m", and clamped to the range of an int64
+func (q Quantity) Value() int64 {
+	// Div rounds down, so negate to round up
+	value := new(big.Int).Neg(q.milliValue())
+	value.Div(value, big.NewInt(1000))
+	return clampInt64(value.Neg(value))
+}
+
+// MilliValue returns the quantity in thousandths of a unit, e.g. 100 for
+// "100m" and 2000 for "2". It is clamped to the range of an int64, which in
+// thousandths ends at about 9P.
+func (q Quantity) MilliValue() int64 {
+	return clampInt64(q.milliValue())
+}
+
+// Float64 returns the quantity as a float64, e.g. 0.1 for "100m"
+func (q Quantity) Float64() float64 {
+	value, _ := new(big.Rat).SetFrac(q.milliValue(), big.NewInt(1000)).Float64()
+	return value
+}
+
+// String returns the quantity in units, or in milli-units with an m suffix
+// when it isn't whole, e.g. "2147483648" for "2Gi" and "1500m" for "1.5"
+func (q Quantity) String() string {
+	units, milli := new(big.Int).QuoRem(q.milliValue(), big.NewInt(1000), new(big.Int))
+	if milli.Sign() != 0 {
+		return q.milliValue().String() + "m"
+	}
+	return units.String()
+}
+
+// UnmarshalText decodes a quantity string, used by XML and TOML style decoders
+func (q *Quantity) UnmarshalText(text []byte) error {
+	parsed, err := ParseQuantity(string(text))
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// UnmarshalJSON decodes a number or a quantity string
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Not a string, so a number
+		s = string(data)
+	}
+	return q.UnmarshalText([]byte(s))
+}
+
+// UnmarshalYAML decodes a number or a quantity string. It is the yaml.v2
+// form, which yaml.v3 also accepts.
+func (q *Quantity) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return q.UnmarshalText([]byte(s))
+}
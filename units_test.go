@@ -0,0 +1,202 @@
+package loadfile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationStrings(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "yaml", file: "app.yaml", content: "timeout: 30s\n", want: 30 * time.Second},
+		{name: "json", file: "app.json", content: `{"timeout": "1h30m"}`, want: 90 * time.Minute},
+		{name: "zero", file: "app.yaml", content: "timeout: 0s\n", want: 0},
+		{name: "negative", file: "app.yaml", content: "timeout: -5m\n", want: -5 * time.Minute},
+		{name: "fraction", file: "app.json", content: `{"timeout": "1.5s"}`, want: 1500 * time.Millisecond},
+		{name: "yaml nanoseconds", file: "app.yaml", content: "timeout: 1000\n", want: 1000},
+		{name: "json nanoseconds", file: "app.json", content: `{"timeout": 1000}`, want: 1000},
+		{name: "no unit", file: "app.yaml", content: "timeout: '30'\n", wantErr: true},
+		{name: "invalid", file: "app.json", content: `{"timeout": "soon"}`, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct{ Timeout time.Duration }{}
+			err := NewLoader(WithDurationStrings()).Load(filename, &into)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", into.Timeout)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.Timeout != tc.want {
+				t.Errorf("got %v, want %v", into.Timeout, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, tc := range []struct {
+		size    string
+		want    Bytes
+		wantErr bool
+	}{
+		{size: "0", want: 0},
+		{size: "0B", want: 0},
+		{size: "512", want: 512},
+		{size: "1KiB", want: 1024},
+		{size: "256MiB", want: 256 << 20},
+		{size: "1.5GiB", want: 3 << 29},
+		{size: "10 MB", want: 10e6},
+		{size: "1kb", want: 1000},
+		{size: "1.0005KB", want: 1001},
+		{size: "1TiB", want: 1 << 40},
+		{size: "-1KiB", wantErr: true},
+		{size: "1XB", wantErr: true},
+		{size: "MiB", wantErr: true},
+		{size: "1e10TiB", wantErr: true},
+		{size: "10000000TiB", wantErr: true},
+	} {
+		t.Run(tc.size, func(t *testing.T) {
+			got, err := ParseBytes(tc.size)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %d, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadBytes(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    Bytes
+	}{
+		{name: "yaml string", file: "app.yaml", content: "size: 256MiB\n", want: 256 << 20},
+		{name: "yaml number", file: "app.yaml", content: "size: 1024\n", want: 1024},
+		{name: "json string", file: "app.json", content: `{"size": "1.5GiB"}`, want: 3 << 29},
+		{name: "json number", file: "app.json", content: `{"size": 1024}`, want: 1024},
+		{name: "json null", file: "app.json", content: `{"size": null}`, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct{ Size Bytes }{}
+			if err := NewLoader().Load(filename, &into); err != nil {
+				t.Fatal(err)
+			}
+			if into.Size != tc.want {
+				t.Errorf("got %d, want %d", into.Size, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		quantity  string
+		wantMilli int64
+		wantValue int64
+		wantStr   string
+		wantErr   bool
+	}{
+		{quantity: "0", wantMilli: 0, wantValue: 0, wantStr: "0"},
+		{quantity: "500m", wantMilli: 500, wantValue: 1, wantStr: "500m"},
+		{quantity: "100m", wantMilli: 100, wantValue: 1, wantStr: "100m"},
+		{quantity: "2", wantMilli: 2000, wantValue: 2, wantStr: "2"},
+		{quantity: "1.5", wantMilli: 1500, wantValue: 2, wantStr: "1500m"},
+		{quantity: "2Gi", wantMilli: 2 << 30 * 1000, wantValue: 2 << 30, wantStr: "2147483648"},
+		{quantity: "1.5Gi", wantMilli: 3 << 29 * 1000, wantValue: 3 << 29, wantStr: "1610612736"},
+		{quantity: "128Mi", wantMilli: 128 << 20 * 1000, wantValue: 128 << 20, wantStr: "134217728"},
+		{quantity: "1.5G", wantMilli: 1.5e12, wantValue: 1.5e9, wantStr: "1500000000"},
+		{quantity: "2k", wantMilli: 2e6, wantValue: 2000, wantStr: "2000"},
+		{quantity: "1e3", wantMilli: 1e6, wantValue: 1000, wantStr: "1000"},
+		{quantity: "1.5e-3", wantMilli: 2, wantValue: 1, wantStr: "2m"},
+		{quantity: "0.1m", wantMilli: 1, wantValue: 1, wantStr: "1m"},
+		{quantity: "500u", wantMilli: 1, wantValue: 1, wantStr: "1m"},
+		{quantity: "-100m", wantMilli: -100, wantValue: 0, wantStr: "-100m"},
+		{quantity: "-0.1m", wantMilli: -1, wantValue: 0, wantStr: "-1m"},
+		{quantity: "-2Gi", wantMilli: -2 << 30 * 1000, wantValue: -2 << 30, wantStr: "-2147483648"},
+		{quantity: ".5", wantMilli: 500, wantValue: 1, wantStr: "500m"},
+		{quantity: "2GB", wantErr: true},
+		{quantity: "2gi", wantErr: true},
+		{quantity: "2 Gi", wantErr: true},
+		{quantity: "m", wantErr: true},
+		{quantity: "1e19", wantErr: true},
+	} {
+		t.Run(tc.quantity, func(t *testing.T) {
+			got, err := ParseQuantity(tc.quantity)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.MilliValue() != tc.wantMilli {
+				t.Errorf("MilliValue is %d, want %d", got.MilliValue(), tc.wantMilli)
+			}
+			if got.Value() != tc.wantValue {
+				t.Errorf("Value is %d, want %d", got.Value(), tc.wantValue)
+			}
+			if got.String() != tc.wantStr {
+				t.Errorf("String is %q, want %q", got.String(), tc.wantStr)
+			}
+		})
+	}
+}
+
+func TestLoadQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "yaml milli", file: "app.yaml", content: "cpu: 500m\n", want: "500m"},
+		{name: "yaml binary", file: "app.yaml", content: "cpu: 2Gi\n", want: "2147483648"},
+		{name: "yaml number", file: "app.yaml", content: "cpu: 1.5\n", want: "1500m"},
+		{name: "json string", file: "app.json", content: `{"cpu": "1.5G"}`, want: "1500000000"},
+		{name: "json number", file: "app.json", content: `{"cpu": 0.25}`, want: "250m"},
+		{name: "json null", file: "app.json", content: `{"cpu": null}`, want: "0"},
+		{name: "invalid", file: "app.yaml", content: "cpu: lots\n", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, tc.file, tc.content)
+			into := struct{ CPU Quantity }{}
+			err := NewLoader().Load(filename, &into)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", into.CPU)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if into.CPU.String() != tc.want {
+				t.Errorf("got %s, want %s", into.CPU, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	genericMapType = reflect.TypeOf(map[string]interface{}{})
+	rawMapType     = reflect.TypeOf(map[string]json.RawMessage{})
+)
+
+var unknownFields sync.Map // reflect.Type -> int
+
+// unknownField returns the index of the field of struct t tagged
+// loadfile:",unknown", or -1
+func unknownField(t reflect.Type) int {
+	if index, ok := unknownFields.Load(t); ok {
+		return index.(int)
+	}
+	index := -1
+	for i := 0; i < t.NumField(); i++ {
+		options := strings.Split(t.Field(i).Tag.Get("loadfile"), ",")
+		for _, option := range options[1:] {
+			if option == "unknown" {
+				index = i
+			}
+		}
+	}
+	unknownFields.Store(t, index)
+	return index
+}
+
+// captureTarget returns the struct into points to, if it has a field for
+// unknown keys
+func captureTarget(into interface{}) (reflect.Value, int, bool) {
+	v := reflect.ValueOf(into)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, 0, false
+	}
+	index := unknownField(v.Elem().Type())
+	return v.Elem(), index, index >= 0
+}
+
+// capturingDecoder wraps decoder to set the loadfile:",unknown" field of the
+// target struct to the top level keys no other field decodes, replacing any
+// value it had, nil when there are none
+func capturingDecoder(format string, decoder DecoderFunc) DecoderFunc {
+	if format != docJSON && format != docYAML {
+		return decoder
+	}
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		err = decoder(bytes.NewReader(content), into)
+		var partial *MultiError
+		if err != nil && !errors.As(err, &partial) {
+			return err
+		}
+		target, index, _ := captureTarget(into)
+		if captureErr := captureUnknown(format, content, target, index); captureErr != nil {
+			return captureErr
+		}
+		return err
+	}
+}
+
+func captureUnknown(format string, content []byte, target reflect.Value, index int) error {
+	t := target.Type()
+	field := t.Field(index)
+	if field.PkgPath != "" || (field.Type != genericMapType && field.Type != rawMapType) {
+		return fmt.Errorf("Field %s tagged loadfile:\",unknown\" must be an exported map[string]interface{} or map[string]json.RawMessage", field.Name)
+	}
+	doc, err := parseDocument(format, content)
+	if err != nil {
+		return err
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if field.Type == rawMapType && format == docJSON {
+		if err := json.Unmarshal(content, &raw); err != nil {
+			return err
+		}
+	}
+
+	captured := reflect.Zero(field.Type)
+	for _, key := range sortedKeys(m) {
+		// The capture field itself can be matched by name, but holds nothing
+		// the file meant for it
+		if fieldIndex, ok := fieldForKey(t, format, key); ok && !(len(fieldIndex) == 1 && fieldIndex[0] == index) {
+			continue
+		}
+		if captured.IsNil() {
+			captured = reflect.MakeMap(field.Type)
+		}
+		var value reflect.Value
+		switch {
+		case field.Type == genericMapType:
+			child := m[key]
+			value = reflect.ValueOf(&child).Elem()
+		case raw != nil:
+			value = reflect.ValueOf(raw[key])
+		default:
+			encoded, err := json.Marshal(m[key])
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			value = reflect.ValueOf(json.RawMessage(encoded))
+		}
+		captured.SetMapIndex(reflect.ValueOf(key), value)
+	}
+	target.Field(index).Set(captured)
+	return nil
+}
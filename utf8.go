@@ -0,0 +1,38 @@
+package loadfile
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is returned, with WithUTF8Validation, for a file which isn't
+// valid UTF-8
+var ErrInvalidUTF8 = errors.New("Invalid UTF-8")
+
+// WithUTF8Validation checks that JSON, YAML, XML and TOML files are valid
+// UTF-8 before decoding them, returning ErrInvalidUTF8 with the offset of the
+// first invalid byte otherwise. Decoders can accept invalid bytes into string
+// values, so this catches a corrupted or truncated transfer before it becomes
+// garbage in the config. Files are checked after any WithCharset or byte order
+// mark transcoding, so the offset is into the transcoded content for those.
+func WithUTF8Validation() Option {
+	return func(l *Loader) {
+		l.utf8Validation = true
+	}
+}
+
+// validateUTF8 returns ErrInvalidUTF8 at the first invalid byte of content
+func validateUTF8(content []byte) error {
+	if utf8.Valid(content) {
+		return nil
+	}
+	for offset := 0; offset < len(content); {
+		r, size := utf8.DecodeRune(content[offset:])
+		if r == utf8.RuneError && size == 1 {
+			return fmt.Errorf("%w at byte %d", ErrInvalidUTF8, offset)
+		}
+		offset += size
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrUnknownKey is returned by a Loader created WithStrict when a file has a
+// key which no struct field decodes
+var ErrUnknownKey = errors.New("Unknown key")
+
+// WithStrict makes keys in JSON, YAML and TOML files which don't match any
+// field of the target struct an error, ErrUnknownKey, rather than silently
+// ignored. Maps, interface{} values, types with their own Unmarshal methods
+// and structs capturing unknown keys (see Load) accept any key.
+func WithStrict() Option {
+	return func(l *Loader) {
+		l.strict = true
+	}
+}
+
+// Warning describes something which didn't stop a load, but which may not be
+// what was intended
+type Warning struct {
+	Filename string
+
+	// Key is the dotted path of the key the warning is about, if any
+	Key string
+
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Key != "" {
+		return fmt.Sprintf("%s: %s: %s", redactURL(w.Filename), w.Key, w.Message)
+	}
+	return fmt.Sprintf("%s: %s", redactURL(w.Filename), w.Message)
+}
+
+// LoadWithWarnings is Load, also returning warnings for:
+//
+//   - keys which no field of the target decodes, which are ignored (an
+//     error WithStrict)
+//   - a stale cached copy being used because the source couldn't be fetched
+//   - an empty file, which leaves into untouched (an error WithErrorOnEmpty)
+func (l *Loader) LoadWithWarnings(filename string, into interface{}) ([]Warning, error) {
+	reader, info, err := l.GetReaderInfo(filename)
+	if err != nil {
+		return nil, err
+	}
+	if readCloser, ok := reader.(io.Closer); ok {
+		defer readCloser.Close()
+	}
+
+	warnings := []Warning{}
+	if info.Stale {
+		warnings = append(warnings, Warning{
+			Filename: filename,
+			Message:  "Using a stale cached copy, the source could not be fetched",
+		})
+	}
+
+	ext, prepared, err := l.prepare(filename, reader)
+	if err == ErrEmpty && !l.errorOnEmpty {
+		return append(warnings, Warning{Filename: filename, Message: "File is empty"}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer prepared.Close()
+	content, err := ioutil.ReadAll(prepared)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.decodeAs(filename, ext, bytes.NewReader(content), into); err != nil {
+		return nil, err
+	}
+
+	keys, err := l.findUnknownKeys(l.docFormat(ext), content, into)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", redactURL(filename), err)
+	}
+	for _, key := range keys {
+		warnings = append(warnings, Warning{
+			Filename: filename,
+			Key:      key,
+			Message:  "Unknown key ignored",
+		})
+	}
+	return warnings, nil
+}
+
+// LoadWithWarnings loads a file, also returning any warnings, using the default
+// loader
+func LoadWithWarnings(filename string, into interface{}) ([]Warning, error) {
+	return DefaultLoader.LoadWithWarnings(filename, into)
+}
+
+// strictDecoder wraps decoder to return ErrUnknownKey for keys which no field
+// decodes
+func (l *Loader) strictDecoder(format string, decoder DecoderFunc) DecoderFunc {
+	return func(r io.Reader, into interface{}) error {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		keys, err := l.findUnknownKeys(format, content, into)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownKey, strings.Join(keys, ", "))
+		}
+		return decoder(bytes.NewReader(content), into)
+	}
+}
+
+// findUnknownKeys returns the unknown keys of content as l decodes it, with
+// keys renamed WithKeyMap and folded WithCaseInsensitiveYAML first, so that a
+// mapped or folded key isn't unknown
+func (l *Loader) findUnknownKeys(format string, content []byte, into interface{}) ([]string, error) {
+	if l.keyMap != nil {
+		if t := keyMappedStruct(format, reflect.TypeOf(into)); t != nil {
+			mapped, err := mapKeys(format, content, l.keyMap, t)
+			if err != nil {
+				return nil, err
+			}
+			content = mapped
+		}
+	}
+	if l.caseInsensitiveYAML && format == docYAML && !isMapTarget(into) {
+		folded, err := foldKeys(content, reflect.TypeOf(into))
+		if err != nil {
+			return nil, err
+		}
+		content = folded
+	}
+	return findUnknownKeys(format, content, into)
+}
+
+// findUnknownKeys returns the dotted path of each key in a JSON, YAML or TOML
+// file which no field of into would decode, in order
+func findUnknownKeys(format string, content []byte, into interface{}) ([]string, error) {
+	if format != docJSON && format != docYAML && format != docTOML {
+		return nil, nil
+	}
+	t := reflect.TypeOf(into)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, nil
+	}
+	if format == docTOML {
+		return tomlUnknownKeys(content, t.Elem())
+	}
+	doc, err := parseDocument(format, content)
+	if err != nil {
+		return nil, err
+	}
+	return unknownKeys(t.Elem(), format, doc, ""), nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decodesItself returns true if t has an Unmarshal method the format's decoder
+// uses, or a type decoder, so what it accepts can't be known
+func decodesItself(t reflect.Type, format string) bool {
+	ptr := reflect.PtrTo(t)
+	if ptr.Implements(textUnmarshalerType) || getTypeDecoder(t) != nil {
+		return true
+	}
+	if format == docYAML {
+		return ptr.Implements(yamlUnmarshalerType)
+	}
+	return ptr.Implements(jsonUnmarshalerType)
+}
+
+func unknownKeys(t reflect.Type, format string, doc interface{}, prefix string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if decodesItself(t, format) {
+		return nil
+	}
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	unknown := []string{}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := doc.(map[string]interface{})
+		if !ok || (format == docYAML && hasInlineMap(t)) || (prefix == "" && unknownField(t) >= 0) {
+			return nil
+		}
+		for _, key := range sortedKeys(m) {
+			index, ok := fieldForKey(t, format, key)
+			if !ok {
+				unknown = append(unknown, join(key))
+				continue
+			}
+			unknown = append(unknown, unknownKeys(t.FieldByIndex(index).Type, format, m[key], join(key))...)
+		}
+	case reflect.Map:
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(m) {
+			unknown = append(unknown, unknownKeys(t.Elem(), format, m[key], join(key))...)
+		}
+	case reflect.Slice, reflect.Array:
+		s, ok := doc.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, child := range s {
+			unknown = append(unknown, unknownKeys(t.Elem(), format, child, join(fmt.Sprint(i)))...)
+		}
+	}
+	return unknown
+}
+
+// hasInlineMap returns true if a struct has a yaml ",inline" map, which takes
+// every key no other field does
+func hasInlineMap(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Map && strings.Contains(f.Tag.Get("yaml"), ",inline") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,156 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const xincludeNamespace = "http://www.w3.org/2001/XInclude"
+
+// resolveXInclude replaces each <xi:include href="..."/> element with the root
+// element of the referenced document, or its text when parse="text". The
+// document is spliced as bytes so everything else is kept exactly as written.
+// xi:fallback is not supported, a missing file is an error.
+func (inc *includer) resolveXInclude(filename string, content []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	// scopes holds the namespace prefixes declared by each open element
+	scopes := []map[string]string{}
+	copied := int64(0)
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := map[string]string{}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					scope[attr.Name.Local] = attr.Value
+				} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					scope[""] = attr.Value
+				}
+			}
+			scopes = append(scopes, scope)
+			if t.Name.Local != "include" || lookupPrefix(scopes, t.Name.Space) != xincludeNamespace {
+				continue
+			}
+
+			if err := skipElement(dec); err != nil {
+				return nil, err
+			}
+			scopes = scopes[:len(scopes)-1]
+			included, err := inc.fetchXInclude(filename, t)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(content[copied:start])
+			out.Write(included)
+			copied = dec.InputOffset()
+
+		case xml.EndElement:
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+	out.Write(content[copied:])
+	return out.Bytes(), nil
+}
+
+// fetchXInclude returns what an include element is replaced with
+func (inc *includer) fetchXInclude(filename string, el xml.StartElement) ([]byte, error) {
+	href, parse := "", "xml"
+	for _, attr := range el.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "href":
+			href = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "parse":
+			parse = attr.Value
+		}
+	}
+	if href == "" {
+		return nil, errors.New("xi:include without href")
+	}
+
+	switch parse {
+	case "text":
+		content, err := inc.fetchText(filename, href)
+		if err != nil {
+			return nil, err
+		}
+		escaped := &bytes.Buffer{}
+		if err := xml.EscapeText(escaped, content); err != nil {
+			return nil, err
+		}
+		return escaped.Bytes(), nil
+	case "xml":
+		ext, content, err := inc.fetch(filename, href)
+		if err != nil {
+			return nil, err
+		}
+		if inc.loader.docFormat(ext) != docXML {
+			return nil, fmt.Errorf("include %s: not XML, use parse=\"text\"", redactURL(href))
+		}
+		root, err := xmlRootElement(content)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", redactURL(href), err)
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("xi:include parse=%q is not supported", parse)
+}
+
+// lookupPrefix returns the namespace bound to prefix by the innermost scope
+func lookupPrefix(scopes []map[string]string, prefix string) string {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if ns, ok := scopes[i][prefix]; ok {
+			return ns
+		}
+	}
+	return ""
+}
+
+// skipElement reads up to the end of the element whose start was just read
+func skipElement(dec *xml.Decoder) error {
+	for depth := 1; depth > 0; {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+// xmlRootElement returns the bytes of the root element of an XML document,
+// without its prolog
+func xmlRootElement(content []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			if err := skipElement(dec); err != nil {
+				return nil, err
+			}
+			return content[start:dec.InputOffset()], nil
+		}
+	}
+}
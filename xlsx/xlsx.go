@@ -0,0 +1,212 @@
+// Package xlsx adds Excel workbooks to loadfile. It is imported for its side
+// effects:
+//
+//	import _ "github.com/daemonl/loadfile/xlsx"
+//
+// Files ending in .xlsx are decoded into a slice of structs, one per row of a
+// worksheet, with the first row naming the columns. data.xlsx#Prices reads the
+// Prices sheet, data.xlsx the first sheet. Columns map to fields by an xlsx
+// tag, `xlsx:"Unit Price"`, or by field name, case insensitively; `xlsx:"-"`
+// skips a field. Blank rows are skipped.
+//
+// Numeric, boolean and date cells convert to int, uint, float, bool and
+// time.Time fields. String fields and encoding.TextUnmarshalers get the cell
+// as displayed, with its number format applied.
+//
+// This lives outside of the core package to keep the excelize dependency out
+// of builds which don't need it.
+package xlsx
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daemonl/loadfile"
+	"github.com/xuri/excelize/v2"
+)
+
+func init() {
+	loadfile.RegisterFragmentFormat("xlsx", decodeXLSX)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func decodeXLSX(r io.Reader, sheet string, into interface{}) error {
+	target := reflect.ValueOf(into)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("xlsx target must be a pointer to a slice, not %T", into)
+	}
+	slice := target.Elem()
+	rowType := slice.Type().Elem()
+	structType := rowType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("xlsx target must be a slice of structs, not %T", into)
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	} else if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+		return fmt.Errorf("No sheet named %q", sheet)
+	}
+	date1904 := false
+	if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		date1904 = *props.Date1904
+	}
+
+	displayed, err := f.GetRows(sheet)
+	if err != nil {
+		return err
+	}
+	raw, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+	if len(displayed) == 0 {
+		return nil
+	}
+
+	columns := columnFields(structType, displayed[0])
+	for i := 1; i < len(displayed); i++ {
+		if isBlank(displayed[i]) {
+			continue
+		}
+		row := reflect.New(structType).Elem()
+		for col, field := range columns {
+			if field == nil || col >= len(displayed[i]) || displayed[i][col] == "" {
+				continue
+			}
+			rawValue := displayed[i][col]
+			if i < len(raw) && col < len(raw[i]) {
+				rawValue = raw[i][col]
+			}
+			cell, _ := excelize.CoordinatesToCellName(col+1, i+1)
+			if err := setCell(row.FieldByIndex(field), displayed[i][col], rawValue, date1904); err != nil {
+				return fmt.Errorf("%s!%s: %w", sheet, cell, err)
+			}
+		}
+		if rowType.Kind() == reflect.Ptr {
+			row = row.Addr()
+		}
+		slice.Set(reflect.Append(slice, row))
+	}
+	return nil
+}
+
+// columnFields returns the index of the field for each header, nil for
+// columns without one
+func columnFields(t reflect.Type, header []string) [][]int {
+	byName := map[string][]int{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("xlsx"); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		byName[strings.ToLower(name)] = field.Index
+	}
+	columns := make([][]int, len(header))
+	for i, name := range header {
+		columns[i] = byName[strings.ToLower(strings.TrimSpace(name))]
+	}
+	return columns
+}
+
+func isBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// setCell converts a cell to the field's type, using the displayed value for
+// text and the raw value for numbers, booleans and dates
+func setCell(field reflect.Value, displayed, raw string, date1904 bool) error {
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	if field.Type() == timeType {
+		serial, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			parsed, err := time.Parse(time.RFC3339, displayed)
+			if err != nil {
+				parsed, err = time.Parse("2006-01-02", displayed)
+			}
+			if err != nil {
+				return fmt.Errorf("%q is not a date", displayed)
+			}
+			field.Set(reflect.ValueOf(parsed))
+			return nil
+		}
+		t, err := excelize.ExcelDateToTime(serial, date1904)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(displayed))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(displayed)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || n != float64(int64(n)) {
+			return fmt.Errorf("%q is not an integer", displayed)
+		}
+		if field.OverflowInt(int64(n)) {
+			return fmt.Errorf("%q overflows %s", displayed, field.Type())
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || n < 0 || n != float64(uint64(n)) {
+			return fmt.Errorf("%q is not an unsigned integer", displayed)
+		}
+		if field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%q overflows %s", displayed, field.Type())
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", displayed)
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("Can't decode a cell into %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package loadfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrWrongXMLRoot is returned by a Loader created WithXMLRoot when an XML
+// document's root element has a different name
+var ErrWrongXMLRoot = errors.New("Unexpected XML root element")
+
+// WithXMLRoot checks that XML documents have a root element with the given
+// local name, returning ErrWrongXMLRoot otherwise. This catches loading the
+// wrong file, which decoding without a schema would otherwise accept.
+func WithXMLRoot(name string) Option {
+	return func(l *Loader) {
+		l.xmlRoot = name
+	}
+}
+
+// checkXMLRoot reads up to the first start element and compares its name,
+// returning a reader which replays the whole document
+func checkXMLRoot(reader io.Reader, want string) (io.Reader, error) {
+	consumed := &bytes.Buffer{}
+	dec := xml.NewDecoder(io.TeeReader(reader, consumed))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != want {
+			return nil, fmt.Errorf("%w: expected <%s>, got <%s>", ErrWrongXMLRoot, want, start.Name.Local)
+		}
+		return io.MultiReader(consumed, reader), nil
+	}
+}
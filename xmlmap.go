@@ -0,0 +1,392 @@
+package loadfile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// xmlKey is where a keyed map field takes the keys of its elements from
+type xmlKey struct {
+	name string
+	attr bool
+}
+
+func (k xmlKey) String() string {
+	if k.attr {
+		return k.name + " attribute"
+	}
+	return "<" + k.name + "> child"
+}
+
+// xmlKeyTag returns the key option of a field's loadfile tag
+func xmlKeyTag(field reflect.StructField) (xmlKey, bool) {
+	options := strings.Split(field.Tag.Get("loadfile"), ",")
+	for _, option := range options[1:] {
+		if !strings.HasPrefix(option, "key=") {
+			continue
+		}
+		name := strings.TrimPrefix(option, "key=")
+		if strings.HasPrefix(name, "@") {
+			return xmlKey{name: name[1:], attr: true}, true
+		}
+		return xmlKey{name: name}, true
+	}
+	return xmlKey{}, false
+}
+
+var (
+	xmlUnmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+	xmlElementsType    = reflect.TypeOf([]xmlElement{})
+)
+
+// xmlShadowStruct is a struct type with the same fields as a struct holding
+// keyed maps, somewhere within it, but with each keyed map replaced by the
+// elements it is decoded from
+type xmlShadowStruct struct {
+	typ    reflect.Type
+	fields []xmlShadowField
+}
+
+type xmlShadowField struct {
+	index int // in the original struct
+	key   *xmlKey
+}
+
+type xmlShadowResult struct {
+	typ reflect.Type
+	err error
+}
+
+var (
+	xmlShadowTypes   sync.Map // reflect.Type -> xmlShadowResult
+	xmlShadowStructs sync.Map // reflect.Type -> *xmlShadowStruct
+)
+
+// xmlShadowType returns the type t is decoded as to fill its keyed maps, or
+// nil when it has none
+func xmlShadowType(t reflect.Type) (reflect.Type, error) {
+	if result, ok := xmlShadowTypes.Load(t); ok {
+		return result.(xmlShadowResult).typ, result.(xmlShadowResult).err
+	}
+	shadow, err := buildXMLShadow(t, map[reflect.Type]bool{})
+	xmlShadowTypes.Store(t, xmlShadowResult{typ: shadow, err: err})
+	return shadow, err
+}
+
+// buildXMLShadow builds the shadow of t. building holds the structs being
+// built, set to true when one is reached again from within itself.
+func buildXMLShadow(t reflect.Type, building map[reflect.Type]bool) (reflect.Type, error) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := buildXMLShadow(t.Elem(), building)
+		if elem == nil || err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(elem), nil
+	case reflect.Slice:
+		elem, err := buildXMLShadow(t.Elem(), building)
+		if elem == nil || err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case reflect.Array:
+		elem, err := buildXMLShadow(t.Elem(), building)
+		if elem == nil || err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(t.Len(), elem), nil
+	case reflect.Struct:
+	default:
+		return nil, nil
+	}
+	if reflect.PtrTo(t).Implements(xmlUnmarshalerType) {
+		return nil, nil
+	}
+	if _, ok := building[t]; ok {
+		building[t] = true
+		return nil, nil
+	}
+	building[t] = false
+	defer delete(building, t)
+
+	info := &xmlShadowStruct{}
+	fields := []reflect.StructField{}
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// encoding/xml ignores them, and StructOf can't make them
+			continue
+		}
+		shadowField := xmlShadowField{index: i}
+		if key, ok := xmlKeyTag(field); ok {
+			if field.Type.Kind() != reflect.Map || field.Type.Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("Field %s tagged loadfile:\",key=\" must be a map with string keys", field.Name)
+			}
+			shadowField.key = &key
+			field.Type = xmlElementsType
+			changed = true
+		} else {
+			elem, err := buildXMLShadow(field.Type, building)
+			if err != nil {
+				return nil, err
+			}
+			if elem != nil {
+				field.Type = elem
+				changed = true
+			}
+		}
+		field.Index = nil
+		fields = append(fields, field)
+		info.fields = append(info.fields, shadowField)
+	}
+	if !changed {
+		return nil, nil
+	}
+	if building[t] {
+		return nil, fmt.Errorf("Can't decode keyed maps in %s, which contains itself", t)
+	}
+	shadow, err := structOf(fields)
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode keyed maps in %s: %v", t, err)
+	}
+	info.typ = shadow
+	xmlShadowStructs.Store(t, info)
+	return shadow, nil
+}
+
+// structOf is reflect.StructOf, which panics on what it doesn't support, such
+// as embedded types with methods
+func structOf(fields []reflect.StructField) (t reflect.Type, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return reflect.StructOf(fields), nil
+}
+
+// decodeKeyedXML decodes into as dec.Decode does, also decoding keyed maps
+func decodeKeyedXML(dec *xml.Decoder, into interface{}) error {
+	v := reflect.ValueOf(into)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return dec.Decode(into)
+	}
+	shadowType, err := xmlShadowType(v.Elem().Type())
+	if err != nil {
+		return err
+	}
+	if shadowType == nil {
+		return dec.Decode(into)
+	}
+	shadow := reflect.New(shadowType)
+	copyToShadow(shadow.Elem(), v.Elem())
+	if err := dec.Decode(shadow.Interface()); err != nil {
+		return err
+	}
+	return copyFromShadow(v.Elem(), shadow.Elem())
+}
+
+// copyToShadow copies src into its shadow dst, which starts out zero, leaving
+// out keyed maps
+func copyToShadow(dst, src reflect.Value) {
+	if dst.Type() == src.Type() {
+		dst.Set(src)
+		return
+	}
+	switch src.Kind() {
+	case reflect.Ptr:
+		if !src.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+			copyToShadow(dst.Elem(), src.Elem())
+		}
+	case reflect.Slice:
+		if !src.IsNil() {
+			dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+			for i := 0; i < src.Len(); i++ {
+				copyToShadow(dst.Index(i), src.Index(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyToShadow(dst.Index(i), src.Index(i))
+		}
+	case reflect.Struct:
+		info, _ := xmlShadowStructs.Load(src.Type())
+		for i, field := range info.(*xmlShadowStruct).fields {
+			if field.key == nil {
+				copyToShadow(dst.Field(i), src.Field(field.index))
+			}
+		}
+	}
+}
+
+// copyFromShadow copies the decoded shadow src back into dst, decoding the
+// elements of keyed maps into them
+func copyFromShadow(dst, src reflect.Value) error {
+	if dst.Type() == src.Type() {
+		dst.Set(src)
+		return nil
+	}
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return copyFromShadow(dst.Elem(), src.Elem())
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		// Elements which were there before decoding keep what the shadow
+		// doesn't hold
+		slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		reflect.Copy(slice, dst)
+		for i := 0; i < src.Len(); i++ {
+			if err := copyFromShadow(slice.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			if err := copyFromShadow(dst.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		info, _ := xmlShadowStructs.Load(dst.Type())
+		for i, field := range info.(*xmlShadowStruct).fields {
+			var err error
+			if field.key != nil {
+				err = mergeXMLElements(dst.Field(field.index), src.Field(i).Interface().([]xmlElement), *field.key)
+			} else {
+				err = copyFromShadow(dst.Field(field.index), src.Field(i))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeXMLElements decodes each element into the entry for its key in m
+func mergeXMLElements(m reflect.Value, elements []xmlElement, key xmlKey) error {
+	if len(elements) == 0 {
+		return nil
+	}
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	seen := map[string]bool{}
+	for _, element := range elements {
+		name := element.start().Name.Local
+		k, ok := element.key(key)
+		if !ok {
+			return fmt.Errorf("<%s> has no %s to key it by", name, key)
+		}
+		if seen[k] {
+			return fmt.Errorf("Duplicate <%s> with %s %q", name, key, k)
+		}
+		seen[k] = true
+
+		mapKey := reflect.ValueOf(k).Convert(m.Type().Key())
+		value := reflect.New(m.Type().Elem())
+		if existing := m.MapIndex(mapKey); existing.IsValid() {
+			value.Elem().Set(existing)
+		}
+		dec := xml.NewTokenDecoder(&tokenReader{tokens: element.tokens})
+		if err := decodeKeyedXML(dec, value.Interface()); err != nil {
+			return fmt.Errorf("<%s> %q: %w", name, k, err)
+		}
+		m.SetMapIndex(mapKey, value.Elem())
+	}
+	return nil
+}
+
+// xmlElement records an element of a keyed map, to be decoded once its key
+// is known
+type xmlElement struct {
+	tokens []xml.Token
+}
+
+func (e *xmlElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	e.tokens = []xml.Token{start.Copy()}
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		e.tokens = append(e.tokens, xml.CopyToken(token))
+	}
+	return nil
+}
+
+func (e *xmlElement) start() xml.StartElement {
+	return e.tokens[0].(xml.StartElement)
+}
+
+// key returns the value of the attribute, or the text of the first child
+// element, named by key
+func (e *xmlElement) key(key xmlKey) (string, bool) {
+	if key.attr {
+		for _, attr := range e.start().Attr {
+			if attr.Name.Local == key.name {
+				return attr.Value, true
+			}
+		}
+		return "", false
+	}
+	depth := 0
+	var text strings.Builder
+	inKey := false
+	for _, token := range e.tokens[1:] {
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 && token.Name.Local == key.name {
+				inKey = true
+			}
+		case xml.EndElement:
+			if depth == 1 && inKey {
+				return strings.TrimSpace(text.String()), true
+			}
+			depth--
+		case xml.CharData:
+			if inKey {
+				text.Write(token)
+			}
+		}
+	}
+	return "", false
+}
+
+// tokenReader replays recorded tokens
+type tokenReader struct {
+	tokens []xml.Token
+}
+
+func (tr *tokenReader) Token() (xml.Token, error) {
+	if len(tr.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := tr.tokens[0]
+	tr.tokens = tr.tokens[1:]
+	return token, nil
+}
@@ -0,0 +1,150 @@
+package loadfile
+
+import (
+	"errors"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// The wrappers which rewrite YAML before decoding work on yaml.v3 nodes
+// rather than generic values, so that what they don't change is written back
+// as it was, with its tags and quoting. A yaml.v2 round trip through
+// interface{} turns 1.10 into 1.1, yes into true and 01234 into 668.
+
+// parseYAMLNode parses content as a single YAML document, returning its root
+// node, or nil for a document with no content
+func parseYAMLNode(content []byte) (*yamlv3.Node, error) {
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(content, doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) != 1 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// nullNode returns a node for a YAML null
+func nullNode() *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// isNullNode returns true for an explicit or implicit YAML null
+func isNullNode(node *yamlv3.Node) bool {
+	return node.Kind == yamlv3.ScalarNode && node.ShortTag() == "!!null"
+}
+
+// isMergeKey returns true for the << key of a mapping merging others into it
+func isMergeKey(node *yamlv3.Node) bool {
+	return node.Kind == yamlv3.ScalarNode && node.Value == "<<" && node.ShortTag() == "!!merge"
+}
+
+// mergedNodes returns the mappings merged by the value of a << key, a
+// mapping, an alias of one or a sequence of either
+func mergedNodes(value *yamlv3.Node) []*yamlv3.Node {
+	merged := []*yamlv3.Node{value}
+	if value.Kind == yamlv3.SequenceNode {
+		merged = value.Content
+	}
+	mappings := []*yamlv3.Node{}
+	for _, node := range merged {
+		if node.Kind == yamlv3.AliasNode {
+			node = node.Alias
+		}
+		if node != nil && node.Kind == yamlv3.MappingNode {
+			mappings = append(mappings, node)
+		}
+	}
+	return mappings
+}
+
+// hasAlias returns true if node is, or contains, an alias
+func hasAlias(node *yamlv3.Node) bool {
+	if node.Kind == yamlv3.AliasNode {
+		return true
+	}
+	for _, child := range node.Content {
+		if hasAlias(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeDocument decodes node into generic values, as parseDocument would have
+// decoded it as part of a whole file
+func nodeDocument(node *yamlv3.Node) (interface{}, error) {
+	if hasAlias(node) {
+		// An alias can't be written without its anchor, so let yaml.v3
+		// expand it, with its limits on how far
+		var doc interface{}
+		if err := node.Decode(&doc); err != nil {
+			return nil, err
+		}
+		return normalizeDocument(doc), nil
+	}
+	content, err := yamlv3.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(docYAML, content)
+}
+
+// nodeYAML encodes node on its own, as it was written but with any aliases
+// within it expanded and without anchors
+func nodeYAML(node *yamlv3.Node) ([]byte, error) {
+	expanded, err := expandedCopy(node)
+	if err != nil {
+		return nil, err
+	}
+	return yamlv3.Marshal(expanded)
+}
+
+// copyNode returns a copy of node which shares nothing with it but the
+// targets of aliases
+func copyNode(node *yamlv3.Node) *yamlv3.Node {
+	copied := *node
+	if node.Content != nil {
+		copied.Content = make([]*yamlv3.Node, len(node.Content))
+		for i, child := range node.Content {
+			copied.Content[i] = copyNode(child)
+		}
+	}
+	return &copied
+}
+
+// maxExpandedNodes limits the nodes expandedCopy creates, against aliases of
+// aliases growing a small document exponentially
+const maxExpandedNodes = 1 << 20
+
+// expandedCopy returns a copy of node with each alias replaced by a copy of
+// what it refers to, and without anchors, so it can be written anywhere
+func expandedCopy(node *yamlv3.Node) (*yamlv3.Node, error) {
+	count := 0
+	var expand func(node *yamlv3.Node) (*yamlv3.Node, error)
+	expand = func(node *yamlv3.Node) (*yamlv3.Node, error) {
+		for node.Kind == yamlv3.AliasNode {
+			if node.Alias == nil {
+				return nullNode(), nil
+			}
+			node = node.Alias
+		}
+		if count++; count > maxExpandedNodes {
+			return nil, errors.New("Too many nodes expanding aliases")
+		}
+		copied := *node
+		copied.Anchor = ""
+		if node.Content != nil {
+			copied.Content = make([]*yamlv3.Node, len(node.Content))
+			for i, child := range node.Content {
+				expanded, err := expand(child)
+				if err != nil {
+					return nil, err
+				}
+				copied.Content[i] = expanded
+			}
+		}
+		return &copied, nil
+	}
+	return expand(node)
+}
@@ -0,0 +1,84 @@
+package loadfile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestYAMLv3(t *testing.T) {
+	type config struct {
+		Version string
+		Code    string
+		Switch  string
+		Answer  string
+		Enabled bool
+		Extra   interface{}
+	}
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    config
+		wantV2  *config
+	}{{
+		name:    "version",
+		content: "version: 1.10\n",
+		want:    config{Version: "1.10"},
+	}, {
+		name:    "leading zero",
+		content: "code: 01234\n",
+		want:    config{Code: "01234"},
+	}, {
+		name:    "on and off",
+		content: "switch: on\nanswer: off\n",
+		want:    config{Switch: "on", Answer: "off"},
+	}, {
+		name:    "yes and no",
+		content: "switch: yes\nanswer: no\n",
+		want:    config{Switch: "yes", Answer: "no"},
+	}, {
+		name:    "typed bool",
+		content: "enabled: true\n",
+		want:    config{Enabled: true},
+	}, {
+		name:    "untyped yes",
+		content: "extra: yes\n",
+		want:    config{Extra: "yes"},
+		wantV2:  &config{Extra: true},
+	}, {
+		name:    "comments only",
+		content: "# nothing here\n",
+		want:    config{},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeTestFile(t, "config.yaml", tc.content)
+			got := config{}
+			if err := NewLoader(WithYAMLv3()).Load(filename, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("v3 decoded %+v, want %+v", got, tc.want)
+			}
+
+			wantV2 := tc.want
+			if tc.wantV2 != nil {
+				wantV2 = *tc.wantV2
+			}
+			got = config{}
+			if err := NewLoader().Load(filename, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, wantV2) {
+				t.Errorf("v2 decoded %+v, want %+v", got, wantV2)
+			}
+		})
+	}
+}
+
+func TestYAMLv3CommentsOnlyErrorOnEmpty(t *testing.T) {
+	filename := writeTestFile(t, "config.yaml", "# nothing here\n")
+	into := map[string]interface{}{}
+	if err := NewLoader(WithYAMLv3(), WithErrorOnEmpty()).Load(filename, &into); !errors.Is(err, ErrEmpty) {
+		t.Errorf("got %v, want ErrEmpty", err)
+	}
+}